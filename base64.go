@@ -4,6 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
 )
 
 var b64 = base64.StdEncoding.Strict()
@@ -31,9 +35,12 @@ func (enc encMode) Encode(data []byte) string {
 
 type b64ctx struct {
 	mode encMode
+
+	// err is the first error that occurred while converting.
+	err error
 }
 
-func (ctx b64ctx) Convert(data any) any {
+func (ctx *b64ctx) Convert(data any) any {
 	switch data := data.(type) {
 	case []byte:
 		return ctx.mode.Encode(data)
@@ -45,6 +52,17 @@ func (ctx b64ctx) Convert(data any) any {
 		}
 		return ret
 
+	case map[any]any:
+		ret := make(map[string]any, len(data))
+		for k, v := range data {
+			key := ctx.convertKey(k)
+			if _, ok := ret[key]; ok && ctx.err == nil {
+				ctx.err = newSemanticError("cbor: duplicate map key " + strconv.Quote(key) + " after converting to JSON")
+			}
+			ret[key] = ctx.Convert(v)
+		}
+		return ret
+
 	case []any:
 		ret := make([]any, len(data))
 		for i, v := range data {
@@ -52,30 +70,123 @@ func (ctx b64ctx) Convert(data any) any {
 		}
 		return ret
 
+	case float64:
+		// NaN and infinities can't be represented in JSON.
+		// See RFC 8949 Section 6.1.
+		if math.IsNaN(data) || math.IsInf(data, 0) {
+			return nil
+		}
+		return data
+
+	case Simple, undefined:
+		// the simple values other than false, true and null are converted into null.
+		// See RFC 8949 Section 6.1.
+		return nil
+
+	case EncodedData:
+		return ctx.mode.Encode(data)
+
+	case url.URL:
+		return data.String()
+
+	case *url.URL:
+		return data.String()
+
+	case Tag:
+		return ctx.convertTag(data.Number, data.Content)
+
+	case RawTag:
+		var v any
+		if err := (Options{UseAnyKey: true, PreserveTags: true}).Unmarshal(data.Content, &v); err != nil {
+			// RawMessage.MarshalJSON reports the error.
+			return data.Content
+		}
+		return ctx.convertTag(data.Number, v)
+
 	case ExpectedBase64URL:
-		orig := ctx.mode
-		ctx.mode = encModeBase64URL
-		ret := ctx.Convert(data.Content)
-		ctx.mode = orig
-		return ret
+		return ctx.convertIn(encModeBase64URL, data.Content)
 
 	case ExpectedBase64:
-		orig := ctx.mode
-		ctx.mode = encModeBase64
-		ret := ctx.Convert(data.Content)
-		ctx.mode = orig
-		return ret
+		return ctx.convertIn(encModeBase64, data.Content)
 
 	case ExpectedBase16:
-		orig := ctx.mode
-		ctx.mode = encModeBase16
-		ret := ctx.Convert(data.Content)
-		ctx.mode = orig
+		return ctx.convertIn(encModeBase16, data.Content)
+	}
+
+	// arrays are decoded from byte strings and arrays in map keys by UseAnyKey.
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Array {
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return ctx.mode.Encode(b)
+		}
+		ret := make([]any, v.Len())
+		for i := range ret {
+			ret[i] = ctx.Convert(v.Index(i).Interface())
+		}
 		return ret
 	}
 	return data
 }
 
+// convertIn converts data with the encoding mode of byte strings.
+func (ctx *b64ctx) convertIn(mode encMode, data any) any {
+	orig := ctx.mode
+	ctx.mode = mode
+	ret := ctx.Convert(data)
+	ctx.mode = orig
+	return ret
+}
+
+// convertTag converts the tag of the tag number n and the content following RFC 8949 Section 6.1.
+// Bignums are converted into the strings of their byte strings, prefixed with "~" if negative,
+// byte strings in the contents of tag number 21, 22 and 23 are encoded in the expected encodings,
+// and the tag numbers of the other tags are ignored.
+func (ctx *b64ctx) convertTag(n TagNumber, content any) any {
+	switch n {
+	case tagNumberPositiveBignum, tagNumberNegativeBignum:
+		s, ok := ctx.Convert(content).(string)
+		if !ok {
+			break
+		}
+		if n == tagNumberNegativeBignum {
+			s = "~" + s
+		}
+		return s
+	case tagNumberExpectedBase64URL:
+		return ctx.convertIn(encModeBase64URL, content)
+	case tagNumberExpectedBase64:
+		return ctx.convertIn(encModeBase64, content)
+	case tagNumberExpectedBase16:
+		return ctx.convertIn(encModeBase16, content)
+	}
+	return ctx.Convert(content)
+}
+
+// convertKey converts the map key k into a JSON object key.
+// Text strings are used as is, the keys converted into strings,
+// e.g. byte strings encoded in the current encoding, are used as the converted strings,
+// and the other keys are converted into the JSON texts of their values, e.g. 1 into "1".
+// See RFC 8949 Section 6.1.
+func (ctx *b64ctx) convertKey(k any) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+
+	v := ctx.Convert(k)
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		if ctx.err == nil {
+			ctx.err = err
+		}
+		return ""
+	}
+	return string(data)
+}
+
 var _ json.Marshaler = ExpectedBase64URL{}
 var _ json.Marshaler = ExpectedBase64{}
 var _ json.Marshaler = ExpectedBase16{}
@@ -91,6 +202,9 @@ type ExpectedBase64URL struct {
 func (e ExpectedBase64URL) MarshalJSON() ([]byte, error) {
 	ctx := &b64ctx{mode: encModeBase64URL}
 	data := ctx.Convert(e.Content)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
 	return json.Marshal(data)
 }
 
@@ -105,6 +219,9 @@ type ExpectedBase64 struct {
 func (e ExpectedBase64) MarshalJSON() ([]byte, error) {
 	ctx := &b64ctx{mode: encModeBase64}
 	data := ctx.Convert(e.Content)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
 	return json.Marshal(data)
 }
 
@@ -119,6 +236,9 @@ type ExpectedBase16 struct {
 func (e ExpectedBase16) MarshalJSON() ([]byte, error) {
 	ctx := &b64ctx{mode: encModeBase16}
 	data := ctx.Convert(e.Content)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
 	return json.Marshal(data)
 }
 
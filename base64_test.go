@@ -2,6 +2,7 @@ package cbor
 
 import (
 	"encoding/json"
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -77,6 +78,18 @@ func TestExpectedBase(t *testing.T) {
 			},
 			`{"base16":"01020304","child":{"base16":"01020304","base64":"AQIDBA==","base64url":"AQIDBA"}}`,
 		},
+
+		{
+			"decoded tags",
+			ExpectedBase16{
+				Content: []any{
+					EncodedData{0x01},
+					&url.URL{Scheme: "http", Host: "example.com", Path: "/a", RawQuery: "b"},
+					Simple(16),
+				},
+			},
+			`["01","http://example.com/a?b",null]`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,15 +1,21 @@
 package cbor
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"math/bits"
+	"net"
 	"net/url"
 	"reflect"
 	"slices"
 	"strconv"
 	"time"
+
+	"github.com/shogo82148/float16"
 )
 
 // maximum epoch time we accept (10000-01-01T00:00:00Z) excluded
@@ -18,17 +24,36 @@ const maxEpoch = 253402300800
 // minimum epoch time we accept (0000-01-01T00:00:00Z) excluded
 const minEpoch = -62135596800
 
+const secondsPerDay = 24 * 60 * 60
+
 var anySliceType = reflect.TypeOf([]any(nil))
 var anyType = reflect.TypeOf((*any)(nil)).Elem()
 var bigFloatType = reflect.TypeOf(big.Float{})
 var bigIntType = reflect.TypeOf(big.Int{})
+var bigRatType = reflect.TypeOf(big.Rat{})
 var byteType = reflect.TypeOf(byte(0))
+var bytesBufferType = reflect.TypeOf(bytes.Buffer{})
+var durationType = reflect.TypeOf(time.Duration(0))
+var float16Type = reflect.TypeOf(Float16(0))
+var fullDateType = reflect.TypeOf(FullDate(""))
+var emptyStructType = reflect.TypeOf(struct{}{})
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var epochDaysType = reflect.TypeOf(EpochDays(0))
+var int64Type = reflect.TypeOf(int64(0))
+var intType = reflect.TypeOf(int(0))
 var integerType = reflect.TypeOf(Integer{})
+var ipType = reflect.TypeOf(net.IP(nil))
+var ipNetType = reflect.TypeOf(net.IPNet{})
+var multiDimArrayType = reflect.TypeOf(MultiDimArray{})
+var marshalerType = reflect.TypeOf((*CBORMarshaler)(nil)).Elem()
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
 var rawTagType = reflect.TypeOf(RawTag{})
 var simpleType = reflect.TypeOf(Simple(0))
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
 var tagType = reflect.TypeOf(Tag{})
 var timeType = reflect.TypeOf(time.Time{})
 var undefinedType = reflect.TypeOf(Undefined)
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
 var urlType = reflect.TypeOf(url.URL{})
 
 var base64StringType = reflect.TypeOf(Base64String(""))
@@ -42,11 +67,19 @@ var minusOne = big.NewInt(-1)
 
 type undefined *struct{}
 
+// Undefined is the CBOR undefined value.
+// Undefined is decoded into any as Undefined.
+//
+// The type of Undefined is not exported.
+// To have a struct field that may be undefined, use a field of type any and compare it with Undefined,
+// or use a field of type Simple that holds 23 for undefined.
 var Undefined undefined = nil
 
 // RawMessage is a raw encoded CBOR value. It implements Marshaler and
 // Unmarshaler and can be used to delay CBOR decoding or precompute a CBOR
 // encoding.
+// Decoding into a RawMessage keeps the data item byte-for-byte,
+// including indefinite-length encoding and the "break" stop codes.
 // nil RawMessage encodes as the CBOR undefined value.
 type RawMessage []byte
 
@@ -64,7 +97,162 @@ func (m *RawMessage) UnmarshalCBOR(data []byte) error {
 	return nil
 }
 
+// Equal reports whether m and other are semantically equal CBOR data items.
+// The order of map keys, the width of integers, lengths and floating-point numbers,
+// and indefinite-length encoding are insignificant.
+// It returns an error if m or other is not well-formed, or has duplicate map keys.
+func (m RawMessage) Equal(other RawMessage) (bool, error) {
+	a, err := m.canonicalize()
+	if err != nil {
+		return false, err
+	}
+	b, err := other.canonicalize()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
+
+// MarshalJSON converts m into JSON following RFC 8949 Section 6.1.
+// Byte strings are encoded as base64url-encoded strings,
+// and the expected conversions of tag number 21, 22 and 23 are honored.
+// Bignums (tag number 2 and 3) are converted into the base64url-encoded strings of their byte strings,
+// prefixed with "~" if negative.
+// The tag numbers of the other tags are ignored and their contents are converted,
+// e.g. 1(1363896240) into 1363896240 and 32("http://example.com") into "http://example.com".
+// NaN, infinities and the simple values other than false, true and null are converted into null.
+// Map keys that are not text strings are converted into strings:
+// byte strings in the same way as the byte string values,
+// and the other keys into their JSON texts, e.g. the integer 1 into "1".
+// It is an error if two keys are converted into the same string.
+func (m RawMessage) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	var v any
+	if err := (Options{UseAnyKey: true, PreserveTags: true}).Unmarshal(m, &v); err != nil {
+		return nil, err
+	}
+	ctx := &b64ctx{mode: encModeBase64URL}
+	data := ctx.Convert(v)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	return json.Marshal(data)
+}
+
+func (m RawMessage) canonicalize() ([]byte, error) {
+	data, err := m.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(data)
+}
+
+// RawMapEntry is an entry of RawMap.
+type RawMapEntry struct {
+	Key   RawMessage
+	Value RawMessage
+}
+
+// RawMap is a CBOR map of raw encoded keys and values.
+// It can be used to pass through maps without decoding their keys and values.
+// The keys and values are emitted verbatim, and the keys are sorted in the bytewise lexicographic order.
+type RawMap []RawMapEntry
+
+// MarshalCBOR returns the CBOR encoding of m.
+func (m RawMap) MarshalCBOR() ([]byte, error) {
+	type entry struct {
+		key, value []byte
+	}
+	entries := make([]entry, 0, len(m))
+	for _, ent := range m {
+		key, err := ent.Key.MarshalCBOR()
+		if err != nil {
+			return nil, err
+		}
+		value, err := ent.Value.MarshalCBOR()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key, value})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	var e encodeState
+	e.writeUint(majorTypeMap, uint64(len(entries)))
+	for i, ent := range entries {
+		if i > 0 && bytes.Equal(entries[i-1].key, ent.key) {
+			return nil, newSemanticError("cbor: duplicate map key")
+		}
+		e.buf.Write(ent.key)
+		e.buf.Write(ent.value)
+	}
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalCBOR sets *m to the entries of the CBOR map in data.
+// The keys and values are copied verbatim, and the order of the entries is preserved.
+func (m *RawMap) UnmarshalCBOR(data []byte) error {
+	d := newDecodeState(data)
+	typ, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	if majorType(typ>>5) != majorTypeMap {
+		return newSemanticError("cbor: unexpected type, want map")
+	}
+
+	indefinite := typ&0x1f == 31
+	var n uint64
+	if !indefinite {
+		n, err = d.readArgument(typ & 0x1f)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries := RawMap{}
+	for i := uint64(0); ; i++ {
+		if indefinite {
+			typ, err := d.peekByte()
+			if err != nil {
+				return err
+			}
+			if typ == 0xff {
+				break
+			}
+		} else if i >= n {
+			break
+		}
+
+		keyStart := d.off
+		if err := d.checkWellFormedChild(); err != nil {
+			return err
+		}
+		valueStart := d.off
+		if err := d.checkWellFormedChild(); err != nil {
+			return err
+		}
+		entries = append(entries, RawMapEntry{
+			Key:   slices.Clone(RawMessage(data[keyStart:valueStart])),
+			Value: slices.Clone(RawMessage(data[valueStart:d.off])),
+		})
+	}
+	*m = entries
+	return nil
+}
+
 // Integer is a CBOR integer type.
+// Any CBOR integer can be decoded into Integer regardless of Options.UseInteger,
+// so it is useful for struct fields that may hold integers out of the range of int64.
+// Bignums (tag number 2 and 3) are also decoded into Integer if they are in its range.
+//
+// Integer is comparable, so it is the recommended key type of maps that have integer keys out of the range of int64.
+// The keys of map[*big.Int]T are compared by their addresses, not their values.
 type Integer struct {
 	// Sign is true if the integer is negative.
 	Sign bool
@@ -192,10 +380,131 @@ func (i *Integer) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// Number is a raw encoded CBOR integer or floating-point number.
+// It captures whether the source was an integer or a float,
+// and lets the caller decide the Go type with the accessors.
+// It implements Marshaler and Unmarshaler, and is encoded verbatim.
+// The zero Number is the integer 0.
+type Number []byte
+
+// MarshalCBOR returns n as the CBOR encoding of n.
+func (n Number) MarshalCBOR() ([]byte, error) {
+	return n.raw(), nil
+}
+
+// UnmarshalCBOR sets *n to a copy of data.
+// An error is returned if data is neither an integer nor a floating-point number.
+func (n *Number) UnmarshalCBOR(data []byte) error {
+	if len(data) == 0 {
+		return ErrUnexpectedEnd
+	}
+	switch typ := data[0]; {
+	case majorType(typ>>5) == majorTypePositiveInt, majorType(typ>>5) == majorTypeNegativeInt:
+	case typ == 0xf9, typ == 0xfa, typ == 0xfb:
+	default:
+		return &UnmarshalTypeError{Value: "non-number", Type: reflect.TypeOf(Number(nil))}
+	}
+	*n = slices.Clone(Number(data))
+	return nil
+}
+
+func (n Number) raw() []byte {
+	if len(n) == 0 {
+		return []byte{0x00}
+	}
+	return n
+}
+
+// IsFloat reports whether n is a floating-point number.
+func (n Number) IsFloat() bool {
+	return majorType(n.raw()[0]>>5) == majorTypeOther
+}
+
+// Int64 returns the number as an int64.
+// An error is returned if n is a floating-point number or overflows int64.
+func (n Number) Int64() (int64, error) {
+	i, err := n.integer()
+	if err != nil {
+		return 0, err
+	}
+	return i.Int64()
+}
+
+// BigInt returns the number as a *big.Int.
+// An error is returned if n is a floating-point number.
+func (n Number) BigInt() (*big.Int, error) {
+	i, err := n.integer()
+	if err != nil {
+		return nil, err
+	}
+	return i.BigInt(), nil
+}
+
+// Float64 returns the number as a float64.
+// Integers are rounded to the nearest float64 if they are not representable exactly.
+func (n Number) Float64() (float64, error) {
+	if n.IsFloat() {
+		var f float64
+		if err := (Options{}).Unmarshal(n.raw(), &f); err != nil {
+			return 0, err
+		}
+		return f, nil
+	}
+
+	i, err := n.integer()
+	if err != nil {
+		return 0, err
+	}
+	f, _ := new(big.Float).SetInt(i.BigInt()).Float64()
+	return f, nil
+}
+
+func (n Number) integer() (Integer, error) {
+	if n.IsFloat() {
+		return Integer{}, errors.New("cbor: number is not an integer")
+	}
+	var i Integer
+	if err := (Options{}).Unmarshal(n.raw(), &i); err != nil {
+		return Integer{}, err
+	}
+	return i, nil
+}
+
 // EncodedData is a CBOR encoded data.
 // CBOR tags that has tag number 24 is converted to this type.
 // See RFC 8949 Section 3.4.5.1.
 type EncodedData []byte
 
+// FullDate is a full-date string in the form YYYY-MM-DD, e.g. "2013-03-21".
+// It is encoded as a CBOR tag that has tag number 1004,
+// and the encoder validates that it is a valid full-date.
+// See RFC 8943.
+type FullDate string
+
+// EpochDays is a number of days since 1970-01-01.
+// It is encoded as a CBOR tag that has tag number 100,
+// and the encoder validates that it is in the range of years 0001 to 9999.
+// See RFC 8943.
+type EpochDays int64
+
+// MultiDimArray is a multi-dimensional array.
+// Elements is the flat slice or array of the elements,
+// and its length must be the product of Dimensions.
+// It is encoded as a CBOR tag that has tag number 40 if the elements are in row-major order,
+// or tag number 1040 if ColumnMajor is true.
+// See RFC 8746 Section 3.1.
+type MultiDimArray struct {
+	Dimensions  []int
+	Elements    any
+	ColumnMajor bool
+}
+
 // Simple is a CBOR simple type.
+// All simple values including false (20), true (21), null (22) and undefined (23)
+// are decoded into Simple as they are.
 type Simple byte
+
+// Float16 is a half-precision floating-point number.
+// It is encoded as a CBOR half-precision float (0xf9) with its bits exactly preserved,
+// and a CBOR float is decoded into it only if the value is representable without loss.
+type Float16 = float16.Float16
@@ -1,9 +1,13 @@
 package cbor
 
 import (
+	"bytes"
+	"encoding/json"
 	"math"
 	"math/big"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // xorshift64 is a pseudo random number generator.
@@ -204,3 +208,398 @@ func TestRawMessage(t *testing.T) {
 		}
 	})
 }
+
+func TestRawMessage_Indefinite(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"indefinite-length array", []byte{0x9f, 0x01, 0x9f, 0x02, 0xff, 0xff}},
+		{"empty indefinite-length array", []byte{0x9f, 0xff}},
+		{"indefinite-length map", []byte{0xbf, 0x61, 0x61, 0x01, 0x61, 0x62, 0x9f, 0xff, 0xff}},
+		{"indefinite-length byte string", []byte{0x5f, 0x41, 0x01, 0x40, 0x42, 0x02, 0x03, 0xff}},
+		{"indefinite-length text string", []byte{0x7f, 0x61, 0x61, 0x62, 0x62, 0x63, 0xff}},
+		{"tagged indefinite-length array", []byte{0xc1, 0x9f, 0x01, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type S struct {
+				A RawMessage
+				B *RawMessage
+				C int
+			}
+			// {"A": raw, "B": raw, "C": 1}
+			input := []byte{0xa3, 0x61, 0x41}
+			input = append(input, tt.raw...)
+			input = append(input, 0x61, 0x42)
+			input = append(input, tt.raw...)
+			input = append(input, 0x61, 0x43, 0x01)
+
+			var got S
+			if err := Unmarshal(input, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(RawMessage(tt.raw), got.A); diff != "" {
+				t.Errorf("A mismatch (-want +got):\n%s", diff)
+			}
+			if got.B == nil {
+				t.Fatal("B is nil")
+			}
+			if diff := cmp.Diff(RawMessage(tt.raw), *got.B); diff != "" {
+				t.Errorf("B mismatch (-want +got):\n%s", diff)
+			}
+			if got.C != 1 {
+				t.Errorf("C = %d, want 1", got.C)
+			}
+
+			// the messages don't share the input.
+			for i := range input {
+				input[i] = 0
+			}
+			if diff := cmp.Diff(RawMessage(tt.raw), got.A); diff != "" {
+				t.Errorf("A mismatch after modifying the input (-want +got):\n%s", diff)
+			}
+
+			// the messages are encoded as is.
+			data, err := Marshal(got.B)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.raw, data); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRawMessage_Equal(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b RawMessage
+		want bool
+	}{
+		{"same", RawMessage{0x01}, RawMessage{0x01}, true},
+		{"integer width", RawMessage{0x0a}, RawMessage{0x18, 0x0a}, true},
+		{"negative integer width", RawMessage{0x38, 0xff}, RawMessage{0x3b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff}, true},
+		{"different integers", RawMessage{0x01}, RawMessage{0x02}, false},
+		{"float width", RawMessage{0xf9, 0x3c, 0x00}, RawMessage{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, true},
+		{"integer and float", RawMessage{0x01}, RawMessage{0xf9, 0x3c, 0x00}, false},
+		{
+			"map key order",
+			RawMessage{0xa2, 0x01, 0x02, 0x61, 0x61, 0x03}, // {1: 2, "a": 3}
+			RawMessage{0xa2, 0x61, 0x61, 0x03, 0x01, 0x02}, // {"a": 3, 1: 2}
+			true,
+		},
+		{
+			"indefinite-length map",
+			RawMessage{0xbf, 0x61, 0x61, 0x03, 0x01, 0x02, 0xff}, // {_ "a": 3, 1: 2}
+			RawMessage{0xa2, 0x01, 0x02, 0x61, 0x61, 0x03},       // {1: 2, "a": 3}
+			true,
+		},
+		{
+			"indefinite-length array",
+			RawMessage{0x9f, 0x01, 0x82, 0x02, 0x03, 0xff}, // [_ 1, [2, 3]]
+			RawMessage{0x82, 0x01, 0x9f, 0x02, 0x03, 0xff}, // [1, [_ 2, 3]]
+			true,
+		},
+		{
+			"indefinite-length string",
+			RawMessage{0x7f, 0x62, 0x73, 0x74, 0x63, 0x72, 0x65, 0x61, 0xff}, // (_ "st", "rea")
+			RawMessage{0x65, 0x73, 0x74, 0x72, 0x65, 0x61},                   // "strea"
+			true,
+		},
+		{"bytes and string", RawMessage{0x41, 0x61}, RawMessage{0x61, 0x61}, false},
+		{"tag number width", RawMessage{0xd8, 0x01, 0x00}, RawMessage{0xc1, 0x00}, true},
+		{"different tags", RawMessage{0xc1, 0x00}, RawMessage{0xc2, 0x00}, false},
+		{"nil is null", nil, RawMessage{0xf6}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Equal(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("not well-formed", func(t *testing.T) {
+		_, err := RawMessage{0x18}.Equal(RawMessage{0x01})
+		if err == nil {
+			t.Error("Equal() should return error")
+		}
+	})
+}
+
+func TestRawMessage_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		m    RawMessage
+		want string
+	}{
+		{"integer", RawMessage{0x01}, `1`},
+		{"bytes", RawMessage{0x43, 0x01, 0x02, 0xff}, `"AQL_"`},
+		{"base64", RawMessage{0xd6, 0x43, 0x01, 0x02, 0xff}, `"AQL/"`},
+		{"base16", RawMessage{0xd7, 0x43, 0x01, 0x02, 0xff}, `"0102ff"`},
+		{
+			"base16 in map",
+			RawMessage{0xd7, 0xa1, 0x61, 0x61, 0x82, 0x41, 0xab, 0xd5, 0x41, 0xfb}, // 23({"a": [h'ab', 21(h'fb')]})
+			`{"a":["ab","-w"]}`,
+		},
+		{"unknown tag", RawMessage{0xd9, 0xff, 0xff, 0x41, 0xfb}, `"-w"`},
+		{"unknown tag in base16", RawMessage{0xd7, 0xd9, 0xff, 0xff, 0x41, 0xfb}, `"fb"`},
+		{"NaN", RawMessage{0xf9, 0x7e, 0x00}, `null`},
+		{"undefined", RawMessage{0xf7}, `null`},
+		{"simple value", RawMessage{0xf0}, `null`},
+
+		// the contents of tags
+		{"epoch-based date/time", RawMessage{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, `1363896240`}, // 1(1363896240)
+		{"date/time string in array", RawMessage{0x81, 0xc0, 0x61, 0x61}, `["a"]`},              // [0("a")]
+		{"epoch days", RawMessage{0xd8, 0x64, 0x05}, `5`},                                       // 100(5)
+		{"full-date", RawMessage{0xd9, 0x03, 0xec, 0x64, 0x32, 0x30, 0x32, 0x30}, `"2020"`},     // 1004("2020")
+		{"URI", RawMessage{0xd8, 0x20, 0x63, 0x61, 0x3f, 0x62}, `"a?b"`},                        // 32("a?b")
+		{"encoded data", RawMessage{0xd8, 0x18, 0x41, 0x01}, `"AQ"`},                            // 24(h'01')
+		{"decimal fraction", RawMessage{0xc4, 0x82, 0x21, 0x19, 0x6a, 0xb3}, `[-2,27315]`},      // 4([-2, 27315])
+		{"bignum", RawMessage{0xc2, 0x42, 0x01, 0x00}, `"AQA"`},                                 // 2(h'0100')
+		{"negative bignum", RawMessage{0xc3, 0x42, 0x01, 0x00}, `"~AQA"`},                       // 3(h'0100')
+		{"bignum in base16", RawMessage{0xd7, 0xc2, 0x42, 0x01, 0x00}, `"0100"`},                // 23(2(h'0100'))
+		{"tagged key", RawMessage{0xa1, 0xc1, 0x01, 0x02}, `{"1":2}`},                           // {1(1): 2}
+		{"bignum key", RawMessage{0xa1, 0xc2, 0x41, 0x01, 0x02}, `{"AQ":2}`},                    // {2(h'01'): 2}
+		{"nil", nil, `null`},
+
+		// non-text map keys
+		{"integer key", RawMessage{0xa1, 0x01, 0x61, 0x61}, `{"1":"a"}`},
+		{"negative integer key", RawMessage{0xa2, 0x20, 0x01, 0x61, 0x61, 0x02}, `{"-1":1,"a":2}`},
+		{"bytes key", RawMessage{0xa1, 0x41, 0xfb, 0x01}, `{"-w":1}`},
+		{"bytes key in base16", RawMessage{0xd7, 0xa1, 0x41, 0xfb, 0x01}, `{"fb":1}`},
+		{"bool key", RawMessage{0xa1, 0xf5, 0x01}, `{"true":1}`},
+		{"array key", RawMessage{0xa1, 0x82, 0x01, 0x02, 0x01}, `{"[1,2]":1}`},
+		{"nested integer key", RawMessage{0x81, 0xa1, 0x01, 0xa1, 0x02, 0x03}, `[{"1":{"2":3}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("key collision", func(t *testing.T) {
+		// {1: 1, "1": 2}
+		_, err := RawMessage{0xa2, 0x01, 0x01, 0x61, 0x31, 0x02}.MarshalJSON()
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("MarshalJSON() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		v := struct {
+			A RawMessage
+		}{
+			A: RawMessage{0xd7, 0x41, 0xab},
+		}
+		got, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"A":"ab"}`; string(got) != want {
+			t.Errorf("json.Marshal() = %s, want %s", got, want)
+		}
+	})
+	t.Run("struct with expected conversions", func(t *testing.T) {
+		data, err := Marshal(&FooI{A: []byte{0xfb}, B: []byte{0xfb}, C: []byte{0xfb}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := RawMessage(data).MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"A":"+w==","B":"-w","C":"fb"}`; string(got) != want {
+			t.Errorf("MarshalJSON() = %s, want %s", got, want)
+		}
+	})
+}
+
+func TestRawMap(t *testing.T) {
+	t.Run("marshal", func(t *testing.T) {
+		m := RawMap{
+			{Key: RawMessage{0x61, 0x61}, Value: RawMessage{0x18, 0x0a}}, // "a": 10 in non-shortest form
+			{Key: RawMessage{0x01}, Value: RawMessage{0x02}},             // 1: 2
+			{Key: RawMessage{0x20}, Value: nil},                          // -1: null
+		}
+		got, err := Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa3, 0x01, 0x02, 0x20, 0xf6, 0x61, 0x61, 0x18, 0x0a}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("marshal nested", func(t *testing.T) {
+		v := []RawMap{{{Key: RawMessage{0x01}, Value: RawMessage{0x02}}}}
+		got, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0x81, 0xa1, 0x01, 0x02}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("duplicate keys", func(t *testing.T) {
+		m := RawMap{
+			{Key: RawMessage{0x01}, Value: RawMessage{0x02}},
+			{Key: RawMessage{0x01}, Value: RawMessage{0x03}},
+		}
+		_, err := Marshal(m)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Marshal() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		input := []byte{0xa2, 0x61, 0x61, 0x18, 0x0a, 0x01, 0x82, 0x02, 0x03}
+		var got RawMap
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := RawMap{
+			{Key: RawMessage{0x61, 0x61}, Value: RawMessage{0x18, 0x0a}},
+			{Key: RawMessage{0x01}, Value: RawMessage{0x82, 0x02, 0x03}},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unmarshal indefinite-length map", func(t *testing.T) {
+		input := []byte{0xbf, 0x61, 0x61, 0x01, 0xff}
+		var got RawMap
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := RawMap{
+			{Key: RawMessage{0x61, 0x61}, Value: RawMessage{0x01}},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unmarshal non-map", func(t *testing.T) {
+		var got RawMap
+		err := Unmarshal([]byte{0x80}, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+	})
+}
+
+func TestNumber(t *testing.T) {
+	t.Run("integer", func(t *testing.T) {
+		var n Number
+		if err := Unmarshal([]byte{0x18, 0x64}, &n); err != nil {
+			t.Fatal(err)
+		}
+		if n.IsFloat() {
+			t.Error("IsFloat() = true, want false")
+		}
+		if i, err := n.Int64(); err != nil || i != 100 {
+			t.Errorf("Int64() = %v, %v, want 100, nil", i, err)
+		}
+		if i, err := n.BigInt(); err != nil || i.Cmp(big.NewInt(100)) != 0 {
+			t.Errorf("BigInt() = %v, %v, want 100, nil", i, err)
+		}
+		if f, err := n.Float64(); err != nil || f != 100 {
+			t.Errorf("Float64() = %v, %v, want 100, nil", f, err)
+		}
+
+		// the encoding is preserved.
+		data, err := Marshal(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, []byte{0x18, 0x64}) {
+			t.Errorf("Marshal() = %x, want %x", data, []byte{0x18, 0x64})
+		}
+	})
+
+	t.Run("large negative integer", func(t *testing.T) {
+		var n Number
+		if err := Unmarshal([]byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, &n); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := n.Int64(); err == nil {
+			t.Error("Int64() should return error")
+		}
+		want, _ := new(big.Int).SetString("-18446744073709551616", 10)
+		if i, err := n.BigInt(); err != nil || i.Cmp(want) != 0 {
+			t.Errorf("BigInt() = %v, %v, want %v, nil", i, err, want)
+		}
+	})
+
+	t.Run("float", func(t *testing.T) {
+		var n Number
+		if err := Unmarshal([]byte{0xf9, 0x3e, 0x00}, &n); err != nil {
+			t.Fatal(err)
+		}
+		if !n.IsFloat() {
+			t.Error("IsFloat() = false, want true")
+		}
+		if _, err := n.Int64(); err == nil {
+			t.Error("Int64() should return error")
+		}
+		if _, err := n.BigInt(); err == nil {
+			t.Error("BigInt() should return error")
+		}
+		if f, err := n.Float64(); err != nil || f != 1.5 {
+			t.Errorf("Float64() = %v, %v, want 1.5, nil", f, err)
+		}
+	})
+
+	t.Run("in a struct", func(t *testing.T) {
+		var v struct {
+			A Number
+			B Number
+		}
+		input := []byte{0xa2, 0x61, 0x41, 0x01, 0x61, 0x42, 0xf9, 0x3c, 0x00} // {"A": 1, "B": 1.0}
+		if err := Unmarshal(input, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.A.IsFloat() || !v.B.IsFloat() {
+			t.Errorf("unexpected kinds: A = %x, B = %x", []byte(v.A), []byte(v.B))
+		}
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, input) {
+			t.Errorf("Marshal() = %x, want %x", data, input)
+		}
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var n Number
+		if i, err := n.Int64(); err != nil || i != 0 {
+			t.Errorf("Int64() = %v, %v, want 0, nil", i, err)
+		}
+	})
+
+	t.Run("not a number", func(t *testing.T) {
+		var n Number
+		err := Unmarshal([]byte{0x61, 0x61}, &n)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package cbor
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/binary"
 	"errors"
 	"io"
@@ -11,6 +12,8 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 	"unicode/utf8"
 
 	"github.com/shogo82148/float16"
@@ -57,26 +60,225 @@ func (e *InvalidUnmarshalError) Error() string {
 	return "cbor: Unmarshal(nil " + e.Type.String() + ")"
 }
 
+// Options specifies the options for encoding and decoding CBOR.
+// The zero value is the default options used by Marshal and Unmarshal.
+//
+// Options is not comparable, because it has map and function fields
+// such as TagTypes, FieldResolver and DecodeHook.
+// Compare the fields individually instead of comparing Options values with ==.
 type Options struct {
 	// UseInteger will decode CBOR integers as Integer instead of Go int64.
+	// It affects only the values decoded into interface types such as any.
+	// Typed destinations are decoded as usual, e.g. an int64 field still can't receive integers out of its range.
+	// Use Integer as the destination type to receive any CBOR integer regardless of its width.
 	UseInteger bool
 
 	// UseAnyKey will decode CBOR map keys as Go map[any]any instead of map[string]any.
+	// Without it, maps decoded into interface types such as any must have only text string keys,
+	// and the other keys, e.g. byte strings and integers, are a SemanticError.
+	// Byte-string keys are decoded as byte arrays such as [4]byte,
+	// because slices can't be map keys.
+	//
+	// NaN can't be used as a map key, because it is not equal to itself.
+	// Two keys are duplicated if they are equal after decoding,
+	// e.g. 1.0 encoded in half-precision and single-precision are duplicated.
+	// The integer 1 and the float 1.0 are different keys, because they are different in the CBOR data model.
+	// 0.0 and -0.0 are duplicated, because they are the same key in Go maps.
 	UseAnyKey bool
+
+	// PreserveTags will decode CBOR tags into Go any as Tag instead of interpreting them.
+	// The content of the tag is decoded recursively under the same options.
+	PreserveTags bool
+
+	// TagTypes maps tag numbers to the concrete types that the tags are decoded into,
+	// when the destination is an interface type with methods.
+	// The content of the tag is decoded into a new value of the concrete type,
+	// if the type implements the interface.
+	// It is useful for plugin architectures.
+	TagTypes map[TagNumber]reflect.Type
+
+	// RequireShortestInts will reject integers, lengths and tag numbers that are not encoded in the shortest form.
+	// It is useful to validate canonical CBOR, e.g. the payload of signatures.
+	// The contents of RawMessage and RawTag are not checked.
+	RequireShortestInts bool
+
+	// StripBOM will remove a leading byte order mark (U+FEFF) from decoded text strings.
+	StripBOM bool
+
+	// NormalizeText will normalize decoded text strings if it is not nil,
+	// e.g. norm.NFC from golang.org/x/text/unicode/norm.
+	// The byte order mark is removed before normalization if StripBOM is set.
+	NormalizeText TextNormalizer
+
+	// AllowIntegralFloatToInt will decode CBOR floats that have no fractional part into Go integer types,
+	// if the values fit in the types.
+	AllowIntegralFloatToInt bool
+
+	// AllowIntToFloat will decode CBOR integers into Go float types.
+	// The values are rounded to the nearest floats,
+	// so integers whose absolute values are larger than 2^53 may lose precision.
+	AllowIntToFloat bool
+
+	// AllowDatetimeToEpoch will decode date/time strings (tag number 0) into Go integer and float types
+	// as the seconds since the Unix epoch.
+	// The fractional seconds are truncated for integer types.
+	AllowDatetimeToEpoch bool
+
+	// AllowNullToZero will decode CBOR null into Go types that can't be nil, such as int and struct types,
+	// as their zero values. Without it, null for such types is an UnmarshalTypeError.
+	AllowNullToZero bool
+
+	// AllowBoolCoercion will decode CBOR booleans into Go integer types as 0 or 1,
+	// and into Go string types as "false" or "true".
+	AllowBoolCoercion bool
+
+	// InternKeys will share the strings of the same text string map keys in a decoded value,
+	// e.g. the decoded maps of map[string]any that have the same keys share their key strings.
+	// It reduces allocations and memory for large documents that repeat the same keys,
+	// in exchange for a table of the keys kept while decoding.
+	InternKeys bool
+
+	// FieldResolver resolves the fields of structs for the decoded map keys if it is not nil.
+	// It is consulted before the keys of the fields specified by the struct field tags.
+	FieldResolver FieldResolver
+
+	// AllowLeapSeconds will accept the leap second "60" in date/time strings (tag number 0),
+	// which time.Parse rejects.
+	// It is accepted only at 23:59 in UTC, where leap seconds are inserted.
+	// The leap second is clamped to the last nanosecond of the previous second, i.e. ":59.999999999",
+	// because time.Time can't represent it.
+	AllowLeapSeconds bool
+
+	// ReplaceInvalidUTF8 will replace invalid UTF-8 sequences in decoded text strings with U+FFFD,
+	// instead of returning an error.
+	// Each invalid sequence is replaced with a single U+FFFD, the same as strings.ToValidUTF8.
+	ReplaceInvalidUTF8 bool
+
+	// DecodeHook is called with each value decoded into an interface type, if it is not nil.
+	// See DecodeHook for details.
+	DecodeHook DecodeHook
+
+	// RequireExactArrayLength will reject the arrays decoded into Go arrays that have a different length
+	// with an UnmarshalTypeError.
+	// Without it, the extra elements are discarded and the missing elements are set to zero values,
+	// the same as encoding/json.
+	// The arrays in map keys are always required to have the exact length,
+	// because the keys of different lengths would collide.
+	RequireExactArrayLength bool
+
+	// FloatMode specifies how to encode floating-point numbers.
+	FloatMode FloatMode
+
+	// MapKeySort specifies how to sort the keys of maps when encoding.
+	// The default is MapKeySortCanonical.
+	MapKeySort MapKeySort
+
+	// TimeMode specifies how to encode time.Time values.
+	// The default is TimeModeEpoch.
+	TimeMode TimeMode
+
+	// TimeLocation specifies the location of time.Time values encoded in TimeModeRFC3339.
+	// If it is nil, UTC is used.
+	TimeLocation *time.Location
+
+	// TimePrecision specifies the precision of the fractional seconds of time.Time values encoded in TimeModeRFC3339.
+	// The values are truncated to a multiple of TimePrecision.
+	// If it is zero or negative, the values are encoded in nanosecond precision.
+	TimePrecision time.Duration
+
+	// StructMode specifies how to encode structs that are not tagged with "toarray".
+	// The decoder also accepts arrays for such structs if it is StructModeArray.
+	// The default is StructModeMap.
+	StructMode StructMode
+
+	// ComplexMode specifies how to encode complex numbers.
+	// The decoder also accepts two-element arrays for complex numbers if it is ComplexModeArray.
+	// The default is ComplexModeError.
+	ComplexMode ComplexMode
+
+	// DurationMode specifies how to encode and decode time.Duration values.
+	// The default is DurationModeNanoseconds.
+	DurationMode DurationMode
+
+	// EnumAsString will encode integer types implementing fmt.Stringer as their String() text.
+	// It is useful for enums defined with iota.
+	// The types that have their own encoding, such as time.Duration, are not affected.
+	// To decode them, the types must implement encoding.TextUnmarshaler.
+	EnumAsString bool
+
+	// HomogeneousArrayTag will encode Go slices and arrays as homogeneous arrays (tag number 41),
+	// except for the slices and arrays of byte strings and of the types whose elements may be encoded in different types,
+	// such as interface types, pointers, big.Int, big.Float, Tag, RawTag and CBORMarshaler.
+	HomogeneousArrayTag bool
+
+	// StringerAsText will encode the values implementing error or fmt.Stringer as their Error() or String() text,
+	// e.g. errors in logging pipelines, which are otherwise encoded as empty maps.
+	// error takes precedence over fmt.Stringer.
+	// The types that have their own encoding, such as time.Time, *big.Int and CBORMarshaler, are not affected.
+	StringerAsText bool
+
+	// ValidateMarshalerOutput will check that the outputs of MarshalCBOR are well-formed,
+	// and return a MarshalerError if not, e.g. to debug custom marshalers.
+	// Without it, the outputs are written verbatim for performance,
+	// so a broken marshaler corrupts the encoding silently.
+	ValidateMarshalerOutput bool
 }
 
 func (o Options) set(d *decodeState) {
 	d.useInteger = o.UseInteger
 	d.useAnyKey = o.UseAnyKey
+	d.preserveTags = o.PreserveTags
+	d.tagTypes = o.TagTypes
+	d.requireShortestInts = o.RequireShortestInts
+	d.stripBOM = o.StripBOM
+	d.normalizeText = o.NormalizeText
+	d.structMode = o.StructMode
+	d.allowIntegralFloatToInt = o.AllowIntegralFloatToInt
+	d.allowIntToFloat = o.AllowIntToFloat
+	d.complexMode = o.ComplexMode
+	d.allowDatetimeToEpoch = o.AllowDatetimeToEpoch
+	d.allowNullToZero = o.AllowNullToZero
+	d.allowBoolCoercion = o.AllowBoolCoercion
+	d.internKeys = o.InternKeys
+	d.fieldResolver = o.FieldResolver
+	d.allowLeapSeconds = o.AllowLeapSeconds
+	d.replaceInvalidUTF8 = o.ReplaceInvalidUTF8
+	d.hook = o.DecodeHook
+	d.requireExactArrayLength = o.RequireExactArrayLength
+	d.durationMode = o.DurationMode
+}
+
+// TextNormalizer normalizes text strings.
+// norm.Form in golang.org/x/text/unicode/norm implements this interface.
+type TextNormalizer interface {
+	String(s string) string
 }
 
+// FieldResolver maps the map key decoded into the struct type t to the Go name of the field.
+// The key is decoded in the same way as the keys of the fields:
+// text strings are string, and integers are int64, uint64 if they overflow int64,
+// or Integer if they are less than math.MinInt64.
+// It returns false to fall back to the keys of the fields.
+// If it returns the name of no field, the key is handled as an unknown key.
+type FieldResolver func(t reflect.Type, key any) (fieldName string, ok bool)
+
+// DecodeHook inspects or replaces the value v decoded into an interface type,
+// such as the elements of []any and the values of map[string]any.
+// The nested values are passed before the array or map that contains them,
+// and the value returned by the hook is stored instead of v.
+// If it returns an error, the decoding stops and the error is returned as is.
+// The hook is not called for map keys.
+type DecodeHook func(v any) (any, error)
+
+// Unmarshal parses the CBOR-encoded data with the options and stores the result in the value pointed to by v.
+// It is useful to decode a value with UseInteger or UseAnyKey without creating a Decoder.
 func (o Options) Unmarshal(data []byte, v any) error {
 	d := newDecodeState(data)
 	o.set(d)
 
 	// Check for well-formedness.
 	// Avoids filling out half a data structure
-	// before discovering a JSON syntax error.
+	// before discovering a CBOR syntax error.
 	if err := d.checkWellFormed(); err != nil {
 		return err
 	}
@@ -92,24 +294,47 @@ func (o Options) Unmarshal(data []byte, v any) error {
 }
 
 // Unmarshal parses the CBOR-encoded data and stores the result in the value pointed to by v.
+//
+// Unmarshal allocates the nil pointers that it follows, including *any and **any.
+// To unmarshal into an interface value that holds a non-nil pointer,
+// Unmarshal decodes into the value pointed to by the pointer,
+// so the concrete type of the pointer constrains the data that can be decoded and
+// maps and structs are merged into the existing value.
+// Otherwise, Unmarshal replaces the value held by the interface,
+// regardless of its concrete type.
+// CBOR null and undefined set the interface value to nil.
+//
+// Unmarshal decodes with the options set by SetDefaultDecodeOptions if any.
 func Unmarshal(data []byte, v any) error {
-	d := newDecodeState(data)
+	return DefaultDecodeOptions().Unmarshal(data, v)
+}
 
-	// Check for well-formedness.
-	// Avoids filling out half a data structure
-	// before discovering a JSON syntax error.
-	if err := d.checkWellFormed(); err != nil {
-		return err
-	}
+var defaultDecodeOptions atomic.Pointer[Options]
 
-	d.init(data)
-	if err := d.decode(v); err != nil {
-		return err
-	}
-	if d.savedError != nil {
-		return d.savedError
+// SetDefaultDecodeOptions sets the options that Unmarshal and FramedDecoder decode with,
+// e.g. to decode integers as Integer with UseInteger in the whole program.
+// It doesn't affect Options.Unmarshal and Decoder.
+// Call Decoder.UseDefaultOptions for a Decoder to opt in.
+// The package itself doesn't decode with the default options either,
+// e.g. the contents of tags, UnmarshalSafe, Lazy and RawMessage.MarshalJSON are not affected.
+//
+// The default options are shared by the whole program, including the other packages that use Unmarshal,
+// so SetDefaultDecodeOptions should be called once in the initialization of the program,
+// e.g. in an init function of the main package.
+// It is safe to call it concurrently with Unmarshal,
+// but the concurrent calls of Unmarshal may decode with either the old or the new options.
+// The maps and the functions in opts, such as TagTypes, must not be modified after the call.
+func SetDefaultDecodeOptions(opts Options) {
+	defaultDecodeOptions.Store(&opts)
+}
+
+// DefaultDecodeOptions returns the options set by SetDefaultDecodeOptions,
+// or the zero Options if they are not set.
+func DefaultDecodeOptions() Options {
+	if opts := defaultDecodeOptions.Load(); opts != nil {
+		return *opts
 	}
-	return nil
+	return Options{}
 }
 
 func newDecodeState(data []byte) *decodeState {
@@ -120,8 +345,27 @@ func newDecodeState(data []byte) *decodeState {
 
 func (d *decodeState) options() Options {
 	return Options{
-		UseInteger: d.useInteger,
-		UseAnyKey:  d.useAnyKey,
+		UseInteger:              d.useInteger,
+		UseAnyKey:               d.useAnyKey,
+		PreserveTags:            d.preserveTags,
+		TagTypes:                d.tagTypes,
+		RequireShortestInts:     d.requireShortestInts,
+		StripBOM:                d.stripBOM,
+		NormalizeText:           d.normalizeText,
+		StructMode:              d.structMode,
+		AllowIntegralFloatToInt: d.allowIntegralFloatToInt,
+		AllowIntToFloat:         d.allowIntToFloat,
+		ComplexMode:             d.complexMode,
+		AllowDatetimeToEpoch:    d.allowDatetimeToEpoch,
+		AllowNullToZero:         d.allowNullToZero,
+		AllowBoolCoercion:       d.allowBoolCoercion,
+		InternKeys:              d.internKeys,
+		FieldResolver:           d.fieldResolver,
+		AllowLeapSeconds:        d.allowLeapSeconds,
+		ReplaceInvalidUTF8:      d.replaceInvalidUTF8,
+		DecodeHook:              d.hook,
+		RequireExactArrayLength: d.requireExactArrayLength,
+		DurationMode:            d.durationMode,
 	}
 }
 
@@ -137,9 +381,32 @@ type decodeState struct {
 	savedError   error
 	decodingKeys bool // whether we're decoding a map key (as opposed to a map value)
 	errorContext *errorContext
-
-	useAnyKey  bool
-	useInteger bool
+	depth        int // nesting depth of checkWellFormedChild
+
+	useAnyKey               bool
+	useInteger              bool
+	preserveTags            bool
+	tagTypes                map[TagNumber]reflect.Type
+	requireShortestInts     bool
+	stripBOM                bool
+	normalizeText           TextNormalizer
+	structMode              StructMode
+	allowIntegralFloatToInt bool
+	allowIntToFloat         bool
+	complexMode             ComplexMode
+	allowDatetimeToEpoch    bool
+	allowNullToZero         bool
+	allowBoolCoercion       bool
+	internKeys              bool
+	internTable             map[string]string // the table of the interned keys
+	fieldResolver           FieldResolver
+	allowLeapSeconds        bool
+	replaceInvalidUTF8      bool
+	hook                    DecodeHook
+	requireExactArrayLength bool
+	durationMode            DurationMode
+
+	rawFields map[string]RawMessage // the raw encodings of the fields, see Decoder.CaptureRawFields
 }
 
 func (d *decodeState) init(data []byte) {
@@ -152,6 +419,14 @@ func (d *decodeState) init(data []byte) {
 		d.errorContext.FieldStack = d.errorContext.FieldStack[:0]
 	}
 	d.decodingKeys = false
+	d.depth = 0
+	if d.internTable != nil {
+		// Reuse the allocated space for the next value.
+		clear(d.internTable)
+	}
+	if d.rawFields != nil {
+		clear(d.rawFields)
+	}
 }
 
 func (s *decodeState) readByte() (byte, error) {
@@ -301,6 +576,39 @@ func (d *decodeState) decode(v any) error {
 }
 
 func (d *decodeState) decodeReflectValue(v reflect.Value) error {
+	if d.hook == nil || d.decodingKeys {
+		return d.decodeItem(v)
+	}
+
+	// the hook is called for the values decoded into interfaces.
+	iv := v
+	if iv.Kind() == reflect.Pointer && !iv.IsNil() {
+		iv = iv.Elem()
+	}
+	if iv.Kind() != reflect.Interface || !iv.CanSet() {
+		return d.decodeItem(v)
+	}
+	start := d.off
+	if err := d.decodeItem(v); err != nil {
+		return err
+	}
+	w, err := d.hook(iv.Interface())
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		iv.SetZero()
+		return nil
+	}
+	rw := reflect.ValueOf(w)
+	if !rw.Type().AssignableTo(iv.Type()) {
+		return &UnmarshalTypeError{Value: rw.Type().String(), Type: iv.Type(), Offset: int64(start)}
+	}
+	iv.Set(rw)
+	return nil
+}
+
+func (d *decodeState) decodeItem(v reflect.Value) error {
 	start := d.off // mark position in data so we can rewind in case of error
 
 	typ, err := d.readByte()
@@ -308,6 +616,17 @@ func (d *decodeState) decodeReflectValue(v reflect.Value) error {
 		return err
 	}
 
+	if d.requireShortestInts {
+		if err := d.checkShortestArgument(start, typ); err != nil {
+			return err
+		}
+	}
+
+	// fast path for small integers, which dominate many payloads.
+	if typ <= 0x17 && d.decodeSmallInt(uint64(typ), v) {
+		return nil
+	}
+
 	isNull := typ == 0xf6 || typ == 0xf7 // null or undefined
 	u, v := indirect(v, isNull)
 
@@ -701,6 +1020,28 @@ func (d *decodeState) decodeReflectValue(v reflect.Value) error {
 	return nil
 }
 
+// checkShortestArgument checks the argument of the head starting at start is encoded in the shortest form.
+// typ is the initial byte of the head, and d.off must point to the next byte of it.
+func (d *decodeState) checkShortestArgument(start int, typ byte) error {
+	info := typ & 0x1f
+	if majorType(typ>>5) == majorTypeOther || info < 24 || info > 27 {
+		return nil
+	}
+
+	off := d.off
+	arg, err := d.readArgument(info)
+	d.off = off
+	if err != nil {
+		// the error is reported by the caller.
+		return nil
+	}
+	if !isShortestArgument(info, arg) {
+		d.off = start
+		return d.newSyntaxError("cbor: integer is not encoded in the shortest form")
+	}
+	return nil
+}
+
 func (d *decodeState) setAny(start int, value string, w any, v reflect.Value) error {
 	rw := reflect.ValueOf(w)
 	tw := rw.Type()
@@ -721,11 +1062,53 @@ func (d *decodeState) setAny(start int, value string, w any, v reflect.Value) er
 	return nil
 }
 
+// decodeSmallInt decodes the small integer 0..23 into v
+// without indirect and the dispatch of decodePositiveInt,
+// if v is int, int64 or any that doesn't hold a pointer.
+// It reports whether v is decoded.
+func (d *decodeState) decodeSmallInt(w uint64, v reflect.Value) bool {
+	if !v.CanSet() {
+		return false
+	}
+	switch v.Type() {
+	case intType, int64Type:
+		v.SetInt(int64(w))
+		return true
+	case anyType:
+		if !v.IsNil() && v.Elem().Kind() == reflect.Pointer {
+			// indirect may decode into the pointer.
+			return false
+		}
+		if d.useInteger {
+			v.Set(reflect.ValueOf(Integer{Value: w}))
+		} else {
+			v.Set(reflect.ValueOf(int64(w)))
+		}
+		return true
+	}
+	return false
+}
+
 func (d *decodeState) decodePositiveInt(start int, w uint64, v reflect.Value) error {
 	switch v.Type() {
 	case integerType:
 		v.Set(reflect.ValueOf(Integer{Value: w}))
 		return nil
+	case bigIntType:
+		v.Addr().Interface().(*big.Int).SetUint64(w)
+		return nil
+	case float16Type:
+		d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+		return nil
+	case durationType:
+		if d.durationMode == DurationModeSeconds {
+			if w > math.MaxInt64/uint64(time.Second) {
+				d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+				return nil
+			}
+			v.SetInt(int64(w) * int64(time.Second))
+			return nil
+		}
 	}
 
 	switch v.Kind() {
@@ -741,6 +1124,12 @@ func (d *decodeState) decodePositiveInt(start int, w uint64, v reflect.Value) er
 			break
 		}
 		v.SetUint(uint64(w))
+	case reflect.Float32, reflect.Float64:
+		if !d.allowIntToFloat {
+			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+			break
+		}
+		v.SetFloat(float64(w))
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
@@ -771,6 +1160,23 @@ func (d *decodeState) decodeNegativeInt(start int, w uint64, v reflect.Value) er
 	case integerType:
 		v.Set(reflect.ValueOf(Integer{Sign: true, Value: w}))
 		return nil
+	case bigIntType:
+		i := v.Addr().Interface().(*big.Int)
+		i.SetUint64(w)
+		i.Sub(minusOne, i)
+		return nil
+	case float16Type:
+		d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+		return nil
+	case durationType:
+		if d.durationMode == DurationModeSeconds {
+			if w >= math.MaxInt64/uint64(time.Second) {
+				d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+				return nil
+			}
+			v.SetInt((-1 - int64(w)) * int64(time.Second))
+			return nil
+		}
 	}
 
 	switch v.Kind() {
@@ -780,6 +1186,12 @@ func (d *decodeState) decodeNegativeInt(start int, w uint64, v reflect.Value) er
 			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
 		}
 		v.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		if !d.allowIntToFloat {
+			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
+			break
+		}
+		v.SetFloat(-1 - float64(w))
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
@@ -808,6 +1220,11 @@ func (d *decodeState) decodeNegativeInt(start int, w uint64, v reflect.Value) er
 
 func (d *decodeState) decodeFloat16(start int, w uint16, v reflect.Value) error {
 	f := float16.FromBits(w)
+	if v.Type() == float16Type && !d.decodingKeys {
+		// preserve the bits exactly, including NaN payloads.
+		v.SetUint(uint64(w))
+		return nil
+	}
 	return d.decodeFloat(start, f.Float64(), v)
 }
 
@@ -828,12 +1245,47 @@ func (d *decodeState) decodeFloat(start int, f float64, v reflect.Value) error {
 		return newSemanticError("cbor: cannot use NaN as a map key")
 	}
 
+	if v.Type() == durationType && d.durationMode == DurationModeSeconds {
+		// -2^63 <= ns < 2^63
+		ns := math.Round(f * float64(time.Second))
+		if math.IsNaN(ns) || ns < -(1<<63) || ns >= 1<<63 {
+			d.saveError(&UnmarshalTypeError{Value: "float", Type: v.Type(), Offset: int64(start)})
+			return nil
+		}
+		v.SetInt(int64(ns))
+		return nil
+	}
+
+	if v.Type() == float16Type {
+		f16 := float16.FromFloat64(f)
+		if f16.Float64() != f && !math.IsNaN(f) {
+			d.saveError(&UnmarshalTypeError{Value: "float", Type: v.Type(), Offset: int64(start)})
+			return nil
+		}
+		v.SetUint(uint64(f16.Bits()))
+		return nil
+	}
+
 	switch v.Kind() {
 	case reflect.Float32, reflect.Float64:
 		if v.OverflowFloat(f) {
 			d.saveError(&UnmarshalTypeError{Value: "float", Type: v.Type(), Offset: int64(start)})
 		}
 		v.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// -2^63 <= f < 2^63
+		if !d.allowIntegralFloatToInt || f != math.Trunc(f) || f < -(1<<63) || f >= 1<<63 || v.OverflowInt(int64(f)) {
+			d.saveError(&UnmarshalTypeError{Value: "float", Type: v.Type(), Offset: int64(start)})
+			break
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// 0 <= f < 2^64
+		if !d.allowIntegralFloatToInt || f != math.Trunc(f) || f < 0 || f >= 1<<64 || v.OverflowUint(uint64(f)) {
+			d.saveError(&UnmarshalTypeError{Value: "float", Type: v.Type(), Offset: int64(start)})
+			break
+		}
+		v.SetUint(uint64(f))
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			d.saveError(&UnmarshalTypeError{Value: "integer", Type: v.Type(), Offset: int64(start)})
@@ -865,6 +1317,7 @@ func (d *decodeState) decodeBytesIndefinite(start int, u Unmarshaler, v reflect.
 LOOP:
 	for {
 		var n uint64
+		chunkStart := d.off
 		typ, err := d.readByte()
 		if err != nil {
 			return err
@@ -901,6 +1354,10 @@ LOOP:
 		default:
 			return d.newSyntaxError("cbor: invalid byte string chunk type")
 		}
+		if d.requireShortestInts && !isShortestArgument(typ&0x1f, n) {
+			d.off = chunkStart
+			return d.newSyntaxError("cbor: integer is not encoded in the shortest form")
+		}
 		if !d.isAvailable(n) {
 			return ErrUnexpectedEnd
 		}
@@ -916,6 +1373,13 @@ LOOP:
 }
 
 func (d *decodeState) setBytes(start int, data []byte, v reflect.Value) error {
+	if v.Type() == bytesBufferType {
+		buf := v.Addr().Interface().(*bytes.Buffer)
+		buf.Reset()
+		buf.Write(data)
+		return nil
+	}
+
 	switch v.Kind() {
 	case reflect.Slice:
 		if v.Type().Elem().Kind() != reflect.Uint8 {
@@ -923,6 +1387,19 @@ func (d *decodeState) setBytes(start int, data []byte, v reflect.Value) error {
 			break
 		}
 		v.SetBytes(data)
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			d.saveError(&UnmarshalTypeError{Value: "bytes", Type: v.Type(), Offset: int64(start)})
+			break
+		}
+		// same as arrays: extra bytes are discarded, and missing bytes are zero.
+		for i := 0; i < v.Len(); i++ {
+			var b byte
+			if i < len(data) {
+				b = data[i]
+			}
+			v.Index(i).SetUint(uint64(b))
+		}
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			d.saveError(&UnmarshalTypeError{Value: "bytes", Type: v.Type(), Offset: int64(start)})
@@ -955,10 +1432,19 @@ func (d *decodeState) decodeString(start int, n uint64, u Unmarshaler, v reflect
 		return u.UnmarshalCBOR(d.data[start:d.off])
 	}
 
-	if !utf8.Valid(d.data[off:d.off]) {
-		return newSemanticError("cbor: invalid UTF-8 string")
+	b := d.data[off:d.off]
+	var s string
+	switch {
+	case !utf8.Valid(b):
+		if !d.replaceInvalidUTF8 {
+			return newSemanticError("cbor: invalid UTF-8 string")
+		}
+		s = d.transformString(strings.ToValidUTF8(string(b), "\ufffd"))
+	case d.internKeys && d.decodingKeys:
+		s = d.intern(b)
+	default:
+		s = d.transformString(string(b))
 	}
-	s := string(d.data[off:d.off])
 	return d.setString(start, s, v)
 }
 
@@ -974,6 +1460,7 @@ func (d *decodeState) decodeStringIndefinite(start int, u Unmarshaler, v reflect
 LOOP:
 	for {
 		var n uint64
+		chunkStart := d.off
 		typ, err := d.readByte()
 		if err != nil {
 			return err
@@ -1010,6 +1497,10 @@ LOOP:
 		default:
 			return d.newSyntaxError("cbor: invalid byte string chunk type")
 		}
+		if d.requireShortestInts && !isShortestArgument(typ&0x1f, n) {
+			d.off = chunkStart
+			return d.newSyntaxError("cbor: integer is not encoded in the shortest form")
+		}
 		if !d.isAvailable(n) {
 			return ErrUnexpectedEnd
 		}
@@ -1021,15 +1512,53 @@ LOOP:
 	}
 	s := builder.String()
 	if !utf8.ValidString(s) {
-		return d.newSyntaxError("cbor: invalid UTF-8 string")
+		if !d.replaceInvalidUTF8 {
+			return d.newSyntaxError("cbor: invalid UTF-8 string")
+		}
+		s = strings.ToValidUTF8(s, "\ufffd")
 	}
-	return d.setString(start, s, v)
+	return d.setString(start, d.transformString(s), v)
+}
+
+// intern returns the decoded text string of the map key b.
+// The string is shared with the same keys decoded before.
+func (d *decodeState) intern(b []byte) string {
+	if s, ok := d.internTable[string(b)]; ok {
+		return s
+	}
+	key := string(b)
+	s := d.transformString(key)
+	if d.internTable == nil {
+		d.internTable = make(map[string]string)
+	}
+	d.internTable[key] = s
+	return s
+}
+
+// transformString applies StripBOM and NormalizeText to s.
+func (d *decodeState) transformString(s string) string {
+	if d.stripBOM {
+		s = strings.TrimPrefix(s, "\uFEFF")
+	}
+	if d.normalizeText != nil {
+		s = d.normalizeText.String(s)
+	}
+	return s
 }
 
 func (d *decodeState) setString(start int, s string, v reflect.Value) error {
 	switch v.Kind() {
 	case reflect.String:
 		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// enums encoded by Options.EnumAsString
+		if v.CanAddr() {
+			if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+				return u.UnmarshalText([]byte(s))
+			}
+		}
+		d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(start)})
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
 			d.saveError(&UnmarshalTypeError{Value: "string", Type: v.Type(), Offset: int64(start)})
@@ -1054,6 +1583,9 @@ func (d *decodeState) decodeArray(start int, n uint64, u Unmarshaler, v reflect.
 
 	switch v.Kind() {
 	case reflect.Slice:
+		// Reuse the backing array if it has enough capacity,
+		// so that repeated decodes into the same destination don't allocate.
+		// A nil slice is set to an empty slice even if the array is empty.
 		if c := uint64(v.Cap()); c < n || (c == 0 && n == 0) {
 			v.Set(reflect.MakeSlice(v.Type(), int(n), int(n)))
 		}
@@ -1080,6 +1612,28 @@ func (d *decodeState) decodeArray(start int, n uint64, u Unmarshaler, v reflect.
 		for j := i; j < l; j++ {
 			v.Index(j).Set(reflect.Zero(v.Type().Elem()))
 		}
+		if (d.requireExactArrayLength || d.decodingKeys) && n != uint64(l) {
+			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(start)})
+		}
+
+	case reflect.Complex64, reflect.Complex128:
+		if d.complexMode != ComplexModeArray || n != 2 {
+			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(start)})
+			for i := 0; i < int(n); i++ {
+				if err := d.checkWellFormedChild(); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		var re, im float64
+		if err := d.decode(&re); err != nil {
+			return err
+		}
+		if err := d.decode(&im); err != nil {
+			return err
+		}
+		v.SetComplex(complex(re, im))
 
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
@@ -1100,18 +1654,39 @@ func (d *decodeState) decodeArray(start int, n uint64, u Unmarshaler, v reflect.
 		v.Set(s)
 
 	case reflect.Struct:
-		st := cachedStructType(v.Type())
-		if !st.toArray {
+		t := v.Type()
+		st := cachedStructType(t)
+		if !st.toArray && d.structMode != StructModeArray {
 			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(start)})
 		}
+		fields := st.arrayFields
+
+		// save original error context
+		var origErrorContext errorContext
+		if d.errorContext != nil {
+			origErrorContext = *d.errorContext
+		} else {
+			d.errorContext = new(errorContext)
+		}
+
 		i := 0
-		for i = 0; i < int(n) && i < len(st.fields); i++ {
-			f := v.FieldByIndex(st.fields[i].index)
-			if err := d.decodeReflectValue(f); err != nil {
+		for i = 0; i < int(n) && i < len(fields); i++ {
+			d.errorContext.Struct = t
+			d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], fields[i].name)
+			if err := d.decodeField(&fields[i], v); err != nil {
 				return err
 			}
 		}
 
+		// restore original error context
+		if d.errorContext != nil {
+			// Reset errorContext to its original state.
+			// Keep the same underlying array for FieldStack, to reuse the
+			// space and avoid unnecessary allocs.
+			d.errorContext.FieldStack = d.errorContext.FieldStack[:len(origErrorContext.FieldStack)]
+			d.errorContext.Struct = origErrorContext.Struct
+		}
+
 		// skip remaining fields
 		for j := i; j < int(n); j++ {
 			if err := d.checkWellFormedChild(); err != nil {
@@ -1120,8 +1695,8 @@ func (d *decodeState) decodeArray(start int, n uint64, u Unmarshaler, v reflect.
 		}
 
 		// fill zero values for omitted fields
-		for j := i; j < len(st.fields); j++ {
-			f := v.FieldByIndex(st.fields[j].index)
+		for j := i; j < len(fields); j++ {
+			f := v.FieldByIndex(fields[j].index)
 			f.Set(reflect.Zero(f.Type()))
 		}
 
@@ -1177,7 +1752,7 @@ func (d *decodeState) decodeArrayIndefinite(start int, u Unmarshaler, v reflect.
 			i++
 		}
 		v.SetLen(i)
-		if i == 0 {
+		if i == 0 && v.IsNil() {
 			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
 		}
 
@@ -1207,6 +1782,9 @@ func (d *decodeState) decodeArrayIndefinite(start int, u Unmarshaler, v reflect.
 		for j := i; j < l; j++ {
 			v.Index(j).Set(reflect.Zero(v.Type().Elem()))
 		}
+		if (d.requireExactArrayLength || d.decodingKeys) && i != l {
+			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(start)})
+		}
 
 	case reflect.Interface:
 		if v.NumMethod() != 0 {
@@ -1244,10 +1822,20 @@ func (d *decodeState) decodeArrayIndefinite(start int, u Unmarshaler, v reflect.
 		}
 
 	case reflect.Struct:
-		st := cachedStructType(v.Type())
-		if !st.toArray {
+		t := v.Type()
+		st := cachedStructType(t)
+		if !st.toArray && d.structMode != StructModeArray {
 			d.saveError(&UnmarshalTypeError{Value: "array", Type: v.Type(), Offset: int64(start)})
 		}
+		fields := st.arrayFields
+
+		// save original error context
+		var origErrorContext errorContext
+		if d.errorContext != nil {
+			origErrorContext = *d.errorContext
+		} else {
+			d.errorContext = new(errorContext)
+		}
 
 		i := 0
 		for {
@@ -1260,9 +1848,10 @@ func (d *decodeState) decodeArrayIndefinite(start int, u Unmarshaler, v reflect.
 				break
 			}
 
-			if i < len(st.fields) {
-				f := v.FieldByIndex(st.fields[i].index)
-				if err := d.decodeReflectValue(f); err != nil {
+			if i < len(fields) {
+				d.errorContext.Struct = t
+				d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], fields[i].name)
+				if err := d.decodeField(&fields[i], v); err != nil {
 					return err
 				}
 			} else {
@@ -1273,9 +1862,18 @@ func (d *decodeState) decodeArrayIndefinite(start int, u Unmarshaler, v reflect.
 			i++
 		}
 
+		// restore original error context
+		if d.errorContext != nil {
+			// Reset errorContext to its original state.
+			// Keep the same underlying array for FieldStack, to reuse the
+			// space and avoid unnecessary allocs.
+			d.errorContext.FieldStack = d.errorContext.FieldStack[:len(origErrorContext.FieldStack)]
+			d.errorContext.Struct = origErrorContext.Struct
+		}
+
 		// fill zero values for omitted fields
-		for j := i; j < len(st.fields); j++ {
-			f := v.FieldByIndex(st.fields[j].index)
+		for j := i; j < len(fields); j++ {
+			f := v.FieldByIndex(fields[j].index)
 			f.Set(reflect.Zero(f.Type()))
 		}
 
@@ -1300,7 +1898,8 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 		return u.UnmarshalCBOR(d.data[start:d.off])
 	}
 
-	if d.decodingKeys {
+	// maps can't be map keys in Go, except for the maps decoded into comparable structs.
+	if d.decodingKeys && v.Kind() != reflect.Struct {
 		return d.newSyntaxError("cbor: unexpected map key")
 	}
 
@@ -1309,24 +1908,27 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 		if v.IsNil() {
 			v.Set(reflect.MakeMapWithSize(v.Type(), int(n)))
 		}
-		kt := v.Type().Key()
-		et := v.Type().Elem()
+		// SetMapIndex copies the key and the element into the map,
+		// so reuse them across the entries to avoid allocations.
+		key := reflect.New(v.Type().Key()).Elem()
+		elem := reflect.New(v.Type().Elem()).Elem()
 		for i := 0; i < int(n); i++ {
 			// decode the key.
-			d.decodingKeys = true
-			key := reflect.New(kt).Elem()
-			err := d.decodeReflectValue(key)
-			d.decodingKeys = false
+			key.SetZero()
+			ok, err := d.decodeMapKey(key)
 			if err != nil {
 				return err
 			}
+			if !ok {
+				continue
+			}
 			if v.MapIndex(key).IsValid() {
 				return newSemanticError("cbor: duplicate map key")
 			}
 
 			// decode the element.
-			elem := reflect.New(et).Elem()
-			if err := d.decodeReflectValue(elem); err != nil {
+			elem.SetZero()
+			if err := d.decodeMapElem(elem); err != nil {
 				return err
 			}
 			v.SetMapIndex(key, elem)
@@ -1361,6 +1963,9 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 		} else {
 			m := map[string]any{}
 			for i := 0; i < int(n); i++ {
+				if err := d.checkTextKey(); err != nil {
+					return err
+				}
 				d.decodingKeys = true
 				var key string
 				err := d.decode(&key)
@@ -1382,6 +1987,10 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 		}
 
 	case reflect.Struct:
+		// decodingKeys is true if the struct is a map key.
+		// The fields are decoded as map keys too, so that they are hashable.
+		decodingKeys := d.decodingKeys
+
 		// save original error context
 		var origErrorContext errorContext
 		if d.errorContext != nil {
@@ -1390,32 +1999,42 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 			d.errorContext = new(errorContext)
 		}
 
-		seen := map[any]struct{}{}
+		seen := structKeySet{
+			strings: map[string]struct{}{},
+			others:  map[any]struct{}{},
+		}
 
 		t := v.Type()
 		st := cachedStructType(t)
 		for i := 0; i < int(n); i++ {
 			// decode the key.
-			var key any
 			d.decodingKeys = true
-			err := d.decode(&key)
-			d.decodingKeys = false
+			key, err := d.decodeStructKey()
+			d.decodingKeys = decodingKeys
 			if err != nil {
 				d.saveError(err)
 				break
 			}
 
 			// check for duplicate keys
-			if _, ok := seen[key]; ok {
+			if !seen.add(key) {
 				return newSemanticError("cbor: duplicate map key")
 			}
-			seen[key] = struct{}{}
 
 			// decode the element.
-			if f, ok := st.maps[key]; ok {
+			if f, ok := d.lookupField(t, st, key); ok {
 				d.errorContext.Struct = t
-				d.errorContext.FieldStack = append(d.errorContext.FieldStack, f.name)
-				if err := d.decodeReflectValue(v.FieldByIndex(f.index)); err != nil {
+				d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], f.name)
+				off := d.off
+				if err := d.decodeField(f, v); err != nil {
+					d.saveError(err)
+					break
+				}
+				d.captureRawField(start, f, off)
+			} else if st.inline != nil {
+				d.errorContext.Struct = t
+				d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], st.inline.name)
+				if err := d.decodeInlineField(v.FieldByIndex(st.inline.index), key.value()); err != nil {
 					d.saveError(err)
 					break
 				}
@@ -1442,6 +2061,180 @@ func (d *decodeState) decodeMap(start int, n uint64, u Unmarshaler, v reflect.Va
 	return nil
 }
 
+// checkTextKey checks that the next map key can be decoded into map[string]any.
+// The keys other than text strings, e.g. byte strings and integers, require UseAnyKey.
+func (d *decodeState) checkTextKey() error {
+	ok, err := d.isTextKey()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newSemanticError("cbor: map key is not a text string, use UseAnyKey to decode it")
+	}
+	return nil
+}
+
+// isTextKey reports whether the next map key is a text string.
+// Tagged keys are text strings if their contents are, e.g. full-date strings (tag number 1004),
+// and the decoders of the tags check them further.
+// It doesn't consume the key.
+func (d *decodeState) isTextKey() (bool, error) {
+	off := d.off
+	defer func() { d.off = off }()
+
+	for {
+		typ, err := d.readByte()
+		if err != nil {
+			return false, err
+		}
+		switch majorType(typ >> 5) {
+		case majorTypeString:
+			return true, nil
+		case majorTypeTag:
+			if _, err := d.readArgument(typ & 0x1f); err != nil {
+				return false, err
+			}
+		default:
+			return false, nil
+		}
+	}
+}
+
+// isTextKeyType reports whether the map keys of t must be text strings.
+// The types implementing Unmarshaler may accept any keys.
+func isTextKeyType(t reflect.Type) bool {
+	return t.Kind() == reflect.String && !reflect.PointerTo(t).Implements(unmarshalerType)
+}
+
+// decodeMapKey decodes the next map key into key.
+// It reports false if the key can't be decoded into key,
+// and the error is saved to be reported after decoding the rest.
+// Then the key and its element are skipped, not to store the zero key.
+func (d *decodeState) decodeMapKey(key reflect.Value) (bool, error) {
+	if isTextKeyType(key.Type()) {
+		ok, err := d.isTextKey()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			if err := d.checkWellFormedChild(); err != nil {
+				return false, err
+			}
+			d.saveError(newSemanticError("cbor: map key is not a text string"))
+			return false, d.checkWellFormedChild()
+		}
+	}
+
+	saved := d.savedError
+	d.savedError = nil
+	d.decodingKeys = true
+	err := d.decodeReflectValue(key)
+	d.decodingKeys = false
+	keyErr := d.savedError
+	if saved != nil {
+		d.savedError = saved
+	}
+	if err != nil {
+		return false, err
+	}
+	if keyErr != nil {
+		return false, d.checkWellFormedChild()
+	}
+	return true, nil
+}
+
+// decodeMapElem decodes the next data item into the map element elem.
+// The data item is skipped for the elements of struct{},
+// so that sets such as map[string]struct{} can be decoded from maps that have any values, e.g. true or null.
+func (d *decodeState) decodeMapElem(elem reflect.Value) error {
+	if elem.Type() == emptyStructType {
+		return d.checkWellFormedChild()
+	}
+	return d.decodeReflectValue(elem)
+}
+
+// lookupField returns the field of the struct type t for the key.
+func (d *decodeState) lookupField(t reflect.Type, st *structType, key structKey) (*field, bool) {
+	if d.fieldResolver != nil {
+		if name, ok := d.fieldResolver(t, key.value()); ok {
+			f, ok := st.names[name]
+			return f, ok
+		}
+	}
+	return st.lookup(key)
+}
+
+// decodeStructKey decodes the next map key for the fields of a struct.
+// Integers are decoded as int64, or uint64 if they overflow int64,
+// to match the keys of the fields tagged with "keyasint" regardless of UseInteger.
+func (d *decodeState) decodeStructKey() (structKey, error) {
+	typ, err := d.peekByte()
+	if err != nil {
+		return structKey{}, err
+	}
+	switch majorType(typ >> 5) {
+	case majorTypeString:
+		var s string
+		if err := d.decode(&s); err != nil {
+			return structKey{}, err
+		}
+		return structKey{str: s, isString: true}, nil
+	case majorTypePositiveInt, majorTypeNegativeInt:
+		var i Integer
+		if err := d.decode(&i); err != nil {
+			return structKey{}, err
+		}
+		if v, err := i.Int64(); err == nil {
+			return structKey{other: v}, nil
+		}
+		if v, err := i.Uint64(); err == nil {
+			return structKey{other: v}, nil
+		}
+		return structKey{other: i}, nil
+	}
+
+	var key any
+	err = d.decode(&key)
+	return structKey{other: key}, err
+}
+
+// decodeField decodes the next data item into the field f of the struct v.
+// If f has the expected conversion, the tag of the conversion is skipped.
+func (d *decodeState) decodeField(f *field, v reflect.Value) error {
+	if f.expected != 0 && d.off < len(d.data) && d.data[d.off] == 0xc0|byte(f.expected) {
+		d.off++
+	}
+	return d.decodeReflectValue(v.FieldByIndex(f.index))
+}
+
+// captureRawField records the raw encoding of the field f, which is data[off:d.off],
+// if CaptureRawFields is enabled and the map at start is the top-level data item.
+func (d *decodeState) captureRawField(start int, f *field, off int) {
+	if d.rawFields == nil || start != 0 {
+		return
+	}
+	d.rawFields[f.name] = RawMessage(slices.Clone(d.data[off:d.off]))
+}
+
+// decodeInlineField decodes the element of the key into the inline map m.
+// If m can't hold the key, the element is skipped.
+func (d *decodeState) decodeInlineField(m reflect.Value, key any) error {
+	kv := reflect.ValueOf(key)
+	if !kv.IsValid() || !kv.Type().AssignableTo(m.Type().Key()) {
+		return d.checkWellFormedChild()
+	}
+
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if err := d.decodeMapElem(elem); err != nil {
+		return err
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	m.SetMapIndex(kv, elem)
+	return nil
+}
+
 func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Value) error {
 	if u != nil {
 		for {
@@ -1463,7 +2256,8 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 		return u.UnmarshalCBOR(d.data[start:d.off])
 	}
 
-	if d.decodingKeys {
+	// maps can't be map keys in Go, except for the maps decoded into comparable structs.
+	if d.decodingKeys && v.Kind() != reflect.Struct {
 		return d.newSyntaxError("cbor: unexpected map key")
 	}
 
@@ -1472,8 +2266,9 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 		if v.IsNil() {
 			v.Set(reflect.MakeMapWithSize(v.Type(), 0))
 		}
-		kt := v.Type().Key()
-		et := v.Type().Elem()
+		// reuse the key and the element. see decodeMap.
+		key := reflect.New(v.Type().Key()).Elem()
+		elem := reflect.New(v.Type().Elem()).Elem()
 		for {
 			typ, err := d.peekByte()
 			if err != nil {
@@ -1484,19 +2279,20 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 				break
 			}
 
-			key := reflect.New(kt).Elem()
-			d.decodingKeys = true
-			err = d.decodeReflectValue(key)
-			d.decodingKeys = false
+			key.SetZero()
+			ok, err := d.decodeMapKey(key)
 			if err != nil {
 				return err
 			}
+			if !ok {
+				continue
+			}
 			if v.MapIndex(key).IsValid() {
 				return newSemanticError("cbor: duplicate map key")
 			}
 
-			elem := reflect.New(et).Elem()
-			if err := d.decodeReflectValue(elem); err != nil {
+			elem.SetZero()
+			if err := d.decodeMapElem(elem); err != nil {
 				return err
 			}
 			v.SetMapIndex(key, elem)
@@ -1551,6 +2347,9 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 				}
 
 				// decode the key
+				if err := d.checkTextKey(); err != nil {
+					return err
+				}
 				var key string
 				d.decodingKeys = true
 				err = d.decode(&key)
@@ -1573,6 +2372,10 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 		}
 
 	case reflect.Struct:
+		// decodingKeys is true if the struct is a map key.
+		// The fields are decoded as map keys too, so that they are hashable.
+		decodingKeys := d.decodingKeys
+
 		// save original error context
 		var origErrorContext errorContext
 		if d.errorContext != nil {
@@ -1581,7 +2384,10 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 			d.errorContext = new(errorContext)
 		}
 
-		seen := map[any]struct{}{}
+		seen := structKeySet{
+			strings: map[string]struct{}{},
+			others:  map[any]struct{}{},
+		}
 
 		t := v.Type()
 		st := cachedStructType(t)
@@ -1596,25 +2402,32 @@ func (d *decodeState) decodeMapIndefinite(start int, u Unmarshaler, v reflect.Va
 			}
 
 			// decode the key.
-			var key any
 			d.decodingKeys = true
-			err = d.decode(&key)
-			d.decodingKeys = false
+			key, err := d.decodeStructKey()
+			d.decodingKeys = decodingKeys
 			if err != nil {
 				d.saveError(err)
 				break
 			}
 			// check for duplicate keys
-			if _, ok := seen[key]; ok {
+			if !seen.add(key) {
 				return newSemanticError("cbor: duplicate map key")
 			}
-			seen[key] = struct{}{}
 
 			// decode the element.
-			if f, ok := st.maps[key]; ok {
+			if f, ok := d.lookupField(t, st, key); ok {
+				d.errorContext.Struct = t
+				d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], f.name)
+				off := d.off
+				if err := d.decodeField(f, v); err != nil {
+					d.saveError(err)
+					break
+				}
+				d.captureRawField(start, f, off)
+			} else if st.inline != nil {
 				d.errorContext.Struct = t
-				d.errorContext.FieldStack = append(d.errorContext.FieldStack, f.name)
-				if err := d.decodeReflectValue(v.FieldByIndex(f.index)); err != nil {
+				d.errorContext.FieldStack = append(d.errorContext.FieldStack[:len(origErrorContext.FieldStack)], st.inline.name)
+				if err := d.decodeInlineField(v.FieldByIndex(st.inline.index), key.value()); err != nil {
 					d.saveError(err)
 					break
 				}
@@ -1649,7 +2462,7 @@ func (d *decodeState) decodeTag(start int, n TagNumber, u Unmarshaler, v reflect
 		return u.UnmarshalCBOR(d.data[start:d.off])
 	}
 
-	if d.decodingKeys {
+	if d.decodingKeys && v.Kind() == reflect.Interface {
 		var content any
 		if err := d.decode(&content); err != nil {
 			return err
@@ -1672,12 +2485,21 @@ func (d *decodeState) decodeTag(start int, n TagNumber, u Unmarshaler, v reflect
 		return nil
 	}
 
+	if d.preserveTags && v.Kind() == reflect.Interface && tagType.Implements(v.Type()) {
+		var content any
+		if err := d.decode(&content); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(Tag{Number: n, Content: content}))
+		return nil
+	}
+
 	contentStart := d.off
 	if err := d.checkWellFormedChild(); err != nil {
 		return err
 	}
 	tag := RawTag{Number: n, Content: d.data[contentStart:d.off]}
-	return tag.decodeReflectValue(v, d.options())
+	return tag.decodeReflectValue(start, v, d.options())
 }
 
 func (d *decodeState) setSimple(start int, s Simple, v reflect.Value) error {
@@ -1700,6 +2522,37 @@ func (d *decodeState) setSimple(start int, s Simple, v reflect.Value) error {
 }
 
 func (d *decodeState) setBool(start int, b bool, v reflect.Value) error {
+	if v.Type() == simpleType {
+		if b {
+			v.Set(reflect.ValueOf(Simple(21)))
+		} else {
+			v.Set(reflect.ValueOf(Simple(20)))
+		}
+		return nil
+	}
+
+	if d.allowBoolCoercion {
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if b {
+				v.SetInt(1)
+			} else {
+				v.SetInt(0)
+			}
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if b {
+				v.SetUint(1)
+			} else {
+				v.SetUint(0)
+			}
+			return nil
+		case reflect.String:
+			v.SetString(strconv.FormatBool(b))
+			return nil
+		}
+	}
+
 	switch v.Kind() {
 	case reflect.Bool:
 		v.SetBool(b)
@@ -1720,11 +2573,19 @@ func (d *decodeState) setNull(start int, v reflect.Value) error {
 		v.SetZero()
 		return nil
 	}
+	if v.Type() == simpleType {
+		v.Set(reflect.ValueOf(Simple(22)))
+		return nil
+	}
 
 	switch v.Kind() {
 	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
 		v.Set(reflect.Zero(v.Type()))
 	default:
+		if d.allowNullToZero {
+			v.SetZero()
+			return nil
+		}
 		d.saveError(&UnmarshalTypeError{Value: "null", Type: v.Type(), Offset: int64(start)})
 	}
 	return nil
@@ -1735,6 +2596,10 @@ func (d *decodeState) setUndefined(start int, v reflect.Value) error {
 		v.SetZero()
 		return nil
 	}
+	if v.Type() == simpleType {
+		v.Set(reflect.ValueOf(Simple(23)))
+		return nil
+	}
 
 	switch v.Kind() {
 	case reflect.Interface:
@@ -1753,6 +2618,83 @@ func WellFormed(data []byte) bool {
 	return d.checkWellFormed() == nil
 }
 
+// DecodeAll calls fn for each top-level data item in data, that is a CBOR sequence defined by RFC 8742.
+// It stops at the first error returned by fn or the first malformed data item, and returns the error.
+// The RawMessage passed to fn shares the underlying array with data, so copy it to retain it after fn returns.
+// The data items before a malformed data item are passed to fn.
+func DecodeAll(data []byte, fn func(RawMessage) error) error {
+	d := newDecodeState(data)
+	for d.off < len(d.data) {
+		start := d.off
+		if err := d.checkWellFormedChild(); err != nil {
+			return err
+		}
+		if err := fn(RawMessage(data[start:d.off:d.off])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalSequenceInto parses the CBOR sequence defined by RFC 8742 in data and
+// stores its top-level data items in the slice pointed to by slicePtr, e.g. *[]T.
+// Each data item is decoded into a new element in the same way as Unmarshal,
+// and the slice grows as needed.
+// The slice is set to an empty slice if data is empty.
+//
+// UnmarshalSequenceInto decodes with the options set by SetDefaultDecodeOptions if any.
+func UnmarshalSequenceInto(data []byte, slicePtr any) error {
+	return DefaultDecodeOptions().UnmarshalSequenceInto(data, slicePtr)
+}
+
+// UnmarshalSequenceInto parses the CBOR sequence in data with the options and stores its data items
+// in the slice pointed to by slicePtr. See the package-level UnmarshalSequenceInto for details.
+func (o Options) UnmarshalSequenceInto(data []byte, slicePtr any) error {
+	rv := reflect.ValueOf(slicePtr)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(slicePtr)}
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Slice {
+		return errors.New("cbor: UnmarshalSequenceInto(non-slice " + rv.Type().String() + ")")
+	}
+
+	d := newDecodeState(data)
+	o.set(d)
+
+	// Check for well-formedness of all data items.
+	// Avoids filling out half a slice
+	// before discovering a CBOR syntax error.
+	for d.off < len(d.data) {
+		if err := d.checkWellFormedChild(); err != nil {
+			return err
+		}
+	}
+
+	d.init(data)
+	s := reflect.MakeSlice(rv.Type(), 0, 0)
+	if !rv.IsNil() {
+		// reuse the underlying array.
+		s = rv.Slice(0, 0)
+	}
+	for i := 0; d.off < len(d.data); i++ {
+		if i < s.Cap() {
+			s = s.Slice(0, i+1)
+			s.Index(i).SetZero()
+		} else {
+			s = reflect.Append(s, reflect.Zero(rv.Type().Elem()))
+		}
+		if err := d.decodeReflectValue(s.Index(i)); err != nil {
+			return err
+		}
+	}
+	rv.Set(s)
+	if d.savedError != nil {
+		return d.savedError
+	}
+	return nil
+}
+
 func (d *decodeState) checkWellFormed() error {
 	if err := d.checkWellFormedChild(); err != nil {
 		return err
@@ -1763,7 +2705,21 @@ func (d *decodeState) checkWellFormed() error {
 	return nil
 }
 
+// maxNestingDepth is the hard limit of the nesting depth of data items.
+// It prevents stack exhaustion and timeouts on deeply nested untrusted input,
+// e.g. a long sequence of 0x9f (indefinite-length arrays).
+const maxNestingDepth = 10000
+
 func (d *decodeState) checkWellFormedChild() error {
+	if d.depth++; d.depth > maxNestingDepth {
+		return d.newSyntaxError("cbor: exceeded max nesting depth")
+	}
+	err := d.checkWellFormedItem()
+	d.depth--
+	return err
+}
+
+func (d *decodeState) checkWellFormedItem() error {
 	typ, err := d.readByte()
 	if err != nil {
 		return err
@@ -1949,8 +2905,12 @@ func (d *decodeState) checkWellFormedChild() error {
 
 	// text string (indefinite length)
 	case 0x7f:
+		// well-formedness doesn't depend on the options.
 		var s string
+		requireShortestInts := d.requireShortestInts
+		d.requireShortestInts = false
 		err := d.decodeStringIndefinite(d.off-1, nil, reflect.ValueOf(&s).Elem())
+		d.requireShortestInts = requireShortestInts
 		if err != nil {
 			return err
 		}
@@ -1,10 +1,16 @@
 package cbor
 
 import (
+	"bytes"
+	"errors"
 	"math"
+	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -324,6 +330,30 @@ var unmarshalTests = []struct {
 		new(Simple),
 		ptr(Simple(255)),
 	},
+	{
+		"false to simple",
+		[]byte{0xf4},
+		new(Simple),
+		ptr(Simple(20)),
+	},
+	{
+		"true to simple",
+		[]byte{0xf5},
+		new(Simple),
+		ptr(Simple(21)),
+	},
+	{
+		"null to simple",
+		[]byte{0xf6},
+		new(Simple),
+		ptr(Simple(22)),
+	},
+	{
+		"undefined to simple",
+		[]byte{0xf7},
+		new(Simple),
+		ptr(Simple(23)),
+	},
 	{
 		"tag 0",
 		[]byte{0xc0, 0x74, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31, 0x54, 0x32, 0x30, 0x3a, 0x30, 0x34, 0x3a, 0x30, 0x30, 0x5a},
@@ -682,6 +712,18 @@ var unmarshalTests = []struct {
 			Host:   "www.example.com",
 		})),
 	},
+	{
+		"url.Values",
+		[]byte{0xa1, 0x61, 0x61, 0x82, 0x61, 0x31, 0x61, 0x32},
+		new(url.Values),
+		&url.Values{"a": {"1", "2"}},
+	},
+	{
+		"http.Header",
+		[]byte{0xa2, 0x61, 0x41, 0x81, 0x61, 0x31, 0x61, 0x42, 0x82, 0x61, 0x32, 0x61, 0x33},
+		new(http.Header),
+		&http.Header{"A": {"1"}, "B": {"2", "3"}},
+	},
 	{
 		"base64 string",
 		[]byte{0xd8, 0x22, 0x6c, 0x38, 0x4a, 0x2b, 0x4e, 0x6f, 0x2f, 0x43, 0x66, 0x6a, 0x62, 0x6f, 0x3d},
@@ -732,6 +774,42 @@ var unmarshalTests = []struct {
 		new(any),
 		ptr(any([]byte{0x01, 0x02, 0x03, 0x04})),
 	},
+	{
+		"byte string to named []byte",
+		[]byte{0x44, 0x01, 0x02, 0x03, 0x04},
+		new(digest),
+		ptr(digest{0x01, 0x02, 0x03, 0x04}),
+	},
+	{
+		"byte string to slice of named byte",
+		[]byte{0x44, 0x01, 0x02, 0x03, 0x04},
+		new([]namedByte),
+		ptr([]namedByte{0x01, 0x02, 0x03, 0x04}),
+	},
+	{
+		"byte string to byte array",
+		[]byte{0x44, 0x01, 0x02, 0x03, 0x04},
+		new([4]byte),
+		ptr([4]byte{0x01, 0x02, 0x03, 0x04}),
+	},
+	{
+		"short byte string to array of named byte",
+		[]byte{0x42, 0x01, 0x02},
+		&[4]namedByte{0xff, 0xff, 0xff, 0xff},
+		ptr([4]namedByte{0x01, 0x02, 0x00, 0x00}),
+	},
+	{
+		"long byte string to byte array",
+		[]byte{0x44, 0x01, 0x02, 0x03, 0x04},
+		new([2]byte),
+		ptr([2]byte{0x01, 0x02}),
+	},
+	{
+		"indefinite-length byte string to named []byte",
+		[]byte{0x5f, 0x42, 0x01, 0x02, 0x41, 0x03, 0xff},
+		new(digest),
+		ptr(digest{0x01, 0x02, 0x03}),
+	},
 	{
 		"indefinite-length multi-chunk byte string",
 		[]byte{
@@ -1012,6 +1090,84 @@ var unmarshalTests = []struct {
 		new(FooB),
 		&FooB{Alg: 42, Kit: []byte("kit")},
 	},
+	{
+		"map to struct d with inline map",
+		[]byte{0xa3, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x32, 0x01, 0x02},
+		new(FooD),
+		&FooD{A: 1, Extra: map[string]RawMessage{"B": {0x61, 0x32}}},
+	},
+	{
+		"indefinite-length map to struct d with inline map",
+		[]byte{0xbf, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x32, 0xff},
+		new(FooD),
+		&FooD{A: 1, Extra: map[string]RawMessage{"B": {0x61, 0x32}}},
+	},
+	{
+		"map to struct d without unknown keys",
+		[]byte{0xa1, 0x61, 0x41, 0x01},
+		new(FooD),
+		&FooD{A: 1},
+	},
+	{
+		"map to struct e with inline map",
+		[]byte{0xa3, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x32, 0x01, 0x02},
+		new(FooE),
+		&FooE{A: 1, Extra: map[any]any{"B": "2", int64(1): int64(2)}},
+	},
+	{
+		"map to struct f with dash key",
+		[]byte{0xa2, 0x61, 0x41, 0x01, 0x61, 0x2d, 0x02},
+		new(FooF),
+		&FooF{B: 2},
+	},
+	{
+		"map to struct i with expected conversions",
+		[]byte{0xa3, 0x61, 0x41, 0xd6, 0x41, 0x01, 0x61, 0x42, 0xd5, 0x41, 0x02, 0x61, 0x43, 0xd7, 0x41, 0x03},
+		new(FooI),
+		&FooI{A: []byte{0x01}, B: []byte{0x02}, C: []byte{0x03}},
+	},
+	{
+		"map to struct i without tags",
+		[]byte{0xa2, 0x61, 0x41, 0x41, 0x01, 0x61, 0x42, 0x41, 0x02},
+		new(FooI),
+		&FooI{A: []byte{0x01}, B: []byte{0x02}},
+	},
+	{
+		"map to struct j with uint64 keys",
+		[]byte{0xa3, 0x01, 0x01, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02, 0x20, 0x03},
+		new(FooJ),
+		&FooJ{A: 1, B: 2, C: 3},
+	},
+	{
+		"map to struct h with the minimum Integer",
+		[]byte{0xa1, 0x61, 0x41, 0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		new(FooH),
+		&FooH{A: Integer{Sign: true, Value: 18446744073709551615}},
+	},
+	{
+		"map to struct h with the maximum Integer",
+		[]byte{0xa1, 0x61, 0x41, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		new(FooH),
+		&FooH{A: Integer{Value: 18446744073709551615}},
+	},
+	{
+		"map to struct h with a negative Integer",
+		[]byte{0xa1, 0x61, 0x41, 0x3b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		new(FooH),
+		&FooH{A: Integer{Sign: true, Value: 0x8000000000000000}},
+	},
+	{
+		"map to struct h with a negative bignum",
+		[]byte{0xa1, 0x61, 0x41, 0xc3, 0x48, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		new(FooH),
+		&FooH{A: Integer{Sign: true, Value: 18446744073709551615}},
+	},
+	{
+		"map to struct g with undefined",
+		[]byte{0xa2, 0x61, 0x41, 0xf7, 0x61, 0x42, 0xf7},
+		new(FooG),
+		&FooG{A: 23, B: Undefined},
+	},
 	{
 		"array to struct c",
 		[]byte{0x82, 0x01, 0x61, 0x32},
@@ -1050,6 +1206,9 @@ var unmarshalTests = []struct {
 	},
 }
 
+// digest is a named []byte type.
+type digest []byte
+
 func TestUnmarshal(t *testing.T) {
 	for _, tt := range unmarshalTests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1065,6 +1224,203 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+// upperNormalizer is a TextNormalizer for testing.
+type upperNormalizer struct{}
+
+func (upperNormalizer) String(s string) string {
+	return strings.ToUpper(s)
+}
+
+func TestOptions_Unmarshal(t *testing.T) {
+	t.Run("UseInteger", func(t *testing.T) {
+		opts := Options{UseInteger: true}
+		input := []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		var got any
+		if err := opts.Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := Integer{Sign: true, Value: 18446744073709551615}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("UseAnyKey", func(t *testing.T) {
+		opts := Options{UseAnyKey: true}
+		input := []byte{0xa2, 0x01, 0x02, 0x61, 0x61, 0x03}
+		var got any
+		if err := opts.Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[any]any{int64(1): int64(2), "a": int64(3)}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("RequireShortestInts", func(t *testing.T) {
+		opts := Options{RequireShortestInts: true}
+		input := []byte{0x18, 0x0a} // 10 encoded in two bytes
+		var got any
+		err := opts.Unmarshal(input, &got)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Unmarshal() should return SyntaxError, got %v", err)
+		}
+	})
+
+	t.Run("StripBOM", func(t *testing.T) {
+		opts := Options{StripBOM: true}
+		input := []byte{0xa1, 0x64, 0xef, 0xbb, 0xbf, 0x61, 0x66, 0xef, 0xbb, 0xbf, 0xef, 0xbb, 0xbf} // {"\ufeffa": "\ufeff\ufeff"}
+		var got any
+		if err := opts.Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]any{"a": "\ufeff"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NormalizeText", func(t *testing.T) {
+		opts := Options{StripBOM: true, NormalizeText: upperNormalizer{}}
+		input := []byte{0x82, 0x64, 0xef, 0xbb, 0xbf, 0x61, 0x41, 0x62} // ["\ufeffa", h'62']
+		var got any
+		if err := opts.Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []any{"A", []byte{0x62}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("nested in a tag", func(t *testing.T) {
+		opts := Options{UseAnyKey: true, UseInteger: true}
+		input := []byte{0xd9, 0xd9, 0xf7, 0xa1, 0x01, 0x02} // 55799({1: 2})
+		var got any
+		if err := opts.Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[any]any{Integer{Value: 1}: Integer{Value: 2}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("not well-formed", func(t *testing.T) {
+		var opts Options
+		var got any
+		if err := opts.Unmarshal([]byte{0x01, 0x02}, &got); err == nil {
+			t.Error("Unmarshal() should fail")
+		}
+	})
+}
+
+func TestSetDefaultDecodeOptions(t *testing.T) {
+	input := []byte{0xa1, 0x01, 0x02} // {1: 2}
+
+	// the default options are the zero Options if they are not set.
+	var got any
+	if err := Unmarshal(input, &got); err == nil {
+		t.Errorf("Unmarshal() error = nil, want error")
+	}
+
+	SetDefaultDecodeOptions(Options{UseInteger: true, UseAnyKey: true})
+	t.Cleanup(func() {
+		SetDefaultDecodeOptions(Options{})
+	})
+
+	got = nil
+	if err := Unmarshal(input, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[any]any{Integer{Value: 1}: Integer{Value: 2}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Options.Unmarshal doesn't use the default options.
+	got = nil
+	if err := (Options{UseAnyKey: true}).Unmarshal(input, &got); err != nil {
+		t.Fatal(err)
+	}
+	want = map[any]any{int64(1): int64(2)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Options.Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetDefaultDecodeOptions_Internal(t *testing.T) {
+	// the default options must not leak into the decoding inside the package.
+	SetDefaultDecodeOptions(Options{
+		UseInteger:          true,
+		RequireShortestInts: true,
+		DecodeHook: func(v any) (any, error) {
+			return nil, errors.New("the hook must not be called")
+		},
+	})
+	t.Cleanup(func() {
+		SetDefaultDecodeOptions(Options{})
+	})
+
+	t.Run("tag 1 with decimal fraction", func(t *testing.T) {
+		// 1(4([-3, 1700000000123]))
+		input := []byte{0xc1, 0xc4, 0x82, 0x22, 0x1b, 0x00, 0x00, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x7b}
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := time.Unix(1700000000, 123000000); !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("tag 40", func(t *testing.T) {
+		// 40([[1, 2], [3, 4]])
+		input := []byte{0xd8, 0x28, 0x82, 0x82, 0x01, 0x02, 0x82, 0x03, 0x04}
+		var got [][]int
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([][]int{{3, 4}}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("RawMessage.MarshalJSON", func(t *testing.T) {
+		got, err := RawMessage{0x82, 0x01, 0x61, 0x61}.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `[1,"a"]`; string(got) != want {
+			t.Errorf("MarshalJSON() = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		// {"a": 1} in the non-shortest form
+		l := Lazy{0xa1, 0x61, 0x61, 0x18, 0x01}
+		got, ok, err := l.GetInt64("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || got != 1 {
+			t.Errorf("GetInt64() = %d, %v, want 1, true", got, ok)
+		}
+	})
+
+	t.Run("UnmarshalSafe", func(t *testing.T) {
+		var got any
+		if err := UnmarshalSafe([]byte{0x01}, &got, Limits{}); err != nil {
+			t.Fatal(err)
+		}
+		if got != int64(1) {
+			t.Errorf("UnmarshalSafe() = %#v, want int64(1)", got)
+		}
+	})
+}
+
 func TestUnmarshal_Unmarshaler(t *testing.T) {
 	for _, tt := range unmarshalTests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1169,6 +1525,38 @@ func TestUnmarshal_UnmarshalTypeError(t *testing.T) {
 			new(FooA),
 			&UnmarshalTypeError{Value: "string", Type: typeOf[int](), Offset: 3, Struct: "FooA", Field: "A"},
 		},
+		{
+			"map to struct, second field",
+			[]byte{0xa2, 0x61, 0x41, 0x01, 0x61, 0x42, 0x01}, // {A: 1, B: 1}
+			new(FooA),
+			&UnmarshalTypeError{Value: "integer", Type: typeOf[string](), Offset: 6, Struct: "FooA", Field: "B"},
+		},
+		{
+			"array to toarray struct",
+			[]byte{0x82, 0x01, 0x02}, // [1, 2]
+			new(FooC),
+			&UnmarshalTypeError{Value: "integer", Type: typeOf[string](), Offset: 2, Struct: "FooC", Field: "B"},
+		},
+		{
+			"indefinite-length array to toarray struct",
+			[]byte{0x9f, 0x01, 0x02, 0xff}, // [_ 1, 2]
+			new(FooC),
+			&UnmarshalTypeError{Value: "integer", Type: typeOf[string](), Offset: 2, Struct: "FooC", Field: "B"},
+		},
+
+		// tags
+		{
+			"tag to incompatible type",
+			[]byte{0x82, 0x01, 0xc1, 0x00}, // [1, 1(0)]
+			new([]int),
+			&UnmarshalTypeError{Value: "datetime", Type: typeOf[int](), Offset: 2},
+		},
+		{
+			"tag to struct field of incompatible type",
+			[]byte{0xa1, 0x61, 0x41, 0xd8, 0x20, 0x61, 0x30}, // {A: 32("0")}
+			new(FooA),
+			&UnmarshalTypeError{Value: "uri", Type: typeOf[int](), Offset: 3, Struct: "FooA", Field: "A"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1238,6 +1626,240 @@ func TestUnmarshal_InvalidUnmarshalError(t *testing.T) {
 	})
 }
 
+func TestDecodeAll(t *testing.T) {
+	t.Run("sequence", func(t *testing.T) {
+		input := []byte{0x01, 0x82, 0x02, 0x03, 0x9f, 0xff, 0x61, 0x61}
+		var got []RawMessage
+		err := DecodeAll(input, func(m RawMessage) error {
+			got = append(got, m)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []RawMessage{{0x01}, {0x82, 0x02, 0x03}, {0x9f, 0xff}, {0x61, 0x61}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("DecodeAll() mismatch (-want +got):\n%s", diff)
+		}
+
+		// appending to the message must not overwrite the next item.
+		_ = append(got[0], 0xff)
+		if input[1] != 0x82 {
+			t.Error("DecodeAll() passed a message that has extra capacity")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		err := DecodeAll(nil, func(m RawMessage) error {
+			t.Errorf("unexpected call with %x", m)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("error from fn", func(t *testing.T) {
+		errStop := errors.New("stop")
+		var n int
+		err := DecodeAll([]byte{0x01, 0x02, 0x03}, func(m RawMessage) error {
+			n++
+			if n == 2 {
+				return errStop
+			}
+			return nil
+		})
+		if err != errStop {
+			t.Errorf("DecodeAll() error = %v, want %v", err, errStop)
+		}
+		if n != 2 {
+			t.Errorf("fn is called %d times, want 2", n)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var got []RawMessage
+		err := DecodeAll([]byte{0x01, 0x82, 0x02}, func(m RawMessage) error {
+			got = append(got, m)
+			return nil
+		})
+		if err != ErrUnexpectedEnd {
+			t.Errorf("DecodeAll() error = %v, want %v", err, ErrUnexpectedEnd)
+		}
+		if diff := cmp.Diff([]RawMessage{{0x01}}, got); diff != "" {
+			t.Errorf("DecodeAll() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestUnmarshalSequenceInto(t *testing.T) {
+	t.Run("structs", func(t *testing.T) {
+		// {"A": 1, "B": "a"} {"A": 2} {"B": "c"}
+		input := []byte{
+			0xa2, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x61,
+			0xa1, 0x61, 0x41, 0x02,
+			0xa1, 0x61, 0x42, 0x61, 0x63,
+		}
+		// the elements are not merged into the existing values.
+		got := make([]FooA, 1, 2)
+		got[0] = FooA{A: 100, B: "x"}
+		if err := UnmarshalSequenceInto(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []FooA{{A: 1, B: "a"}, {A: 2}, {B: "c"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("UnmarshalSequenceInto() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		input := []byte{0x01, 0x82, 0x02, 0x03, 0x61, 0x61}
+		var got []any
+		if err := UnmarshalSequenceInto(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []any{int64(1), []any{int64(2), int64(3)}, "a"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("UnmarshalSequenceInto() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		input := []byte{0x01, 0x20}
+		var got []any
+		if err := (Options{UseInteger: true}).UnmarshalSequenceInto(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := []any{Integer{Value: 1}, Integer{Sign: true, Value: 0}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("UnmarshalSequenceInto() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		got := []int{1, 2, 3}
+		if err := UnmarshalSequenceInto(nil, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("UnmarshalSequenceInto() = %#v, want an empty slice", got)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		got := []int{1}
+		err := UnmarshalSequenceInto([]byte{0x01, 0x82, 0x02}, &got)
+		if err != ErrUnexpectedEnd {
+			t.Errorf("UnmarshalSequenceInto() error = %v, want %v", err, ErrUnexpectedEnd)
+		}
+		// nothing is decoded.
+		if diff := cmp.Diff([]int{1}, got); diff != "" {
+			t.Errorf("UnmarshalSequenceInto() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("type error", func(t *testing.T) {
+		var got []int
+		err := UnmarshalSequenceInto([]byte{0x01, 0x61, 0x61, 0x03}, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("UnmarshalSequenceInto() error = %v, want *UnmarshalTypeError", err)
+		}
+		// the other elements are decoded.
+		if diff := cmp.Diff([]int{1, 0, 3}, got); diff != "" {
+			t.Errorf("UnmarshalSequenceInto() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid argument", func(t *testing.T) {
+		var got []int
+		if err := UnmarshalSequenceInto([]byte{0x01}, got); err == nil {
+			t.Error("UnmarshalSequenceInto() with non-pointer should fail")
+		}
+		var n int
+		if err := UnmarshalSequenceInto([]byte{0x01}, &n); err == nil {
+			t.Error("UnmarshalSequenceInto() with non-slice should fail")
+		}
+	})
+}
+
+func TestUnmarshal_DeepNesting(t *testing.T) {
+	t.Run("within limit", func(t *testing.T) {
+		// [[[...[]...]]]
+		input := append(bytes.Repeat([]byte{0x81}, maxNestingDepth-1), 0x80)
+		var v any
+		if err := Unmarshal(input, &v); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"indefinite-length arrays", bytes.Repeat([]byte{0x9f}, 20000)},
+		{"arrays", append(bytes.Repeat([]byte{0x81}, maxNestingDepth), 0x80)},
+		{"tags", append(bytes.Repeat([]byte{0xc6}, maxNestingDepth), 0x00)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v any
+			err := Unmarshal(tt.input, &v)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Unmarshal() error = %v, want *SyntaxError", err)
+			}
+			if se.Offset != maxNestingDepth {
+				t.Errorf("unexpected offset: got %d, want %d", se.Offset, maxNestingDepth)
+			}
+
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			if err := dec.Decode(&v); !errors.As(err, &se) {
+				t.Errorf("Decode() error = %v, want *SyntaxError", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_ShortArray(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"definite", []byte{0x81, 0x05}},         // [5]
+		{"indefinite", []byte{0x9f, 0x05, 0xff}}, // [_ 5]
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// omitted fields of the reused struct are zeroed.
+			got := FooC{A: 1, B: "foo"}
+			if err := Unmarshal(tt.input, &got); err != nil {
+				t.Fatal(err)
+			}
+			if want := (FooC{A: 5}); got != want {
+				t.Errorf("Unmarshal() got = %v, want %v", got, want)
+			}
+
+			// the same for structs decoded in StructModeArray.
+			gotA := FooA{A: 1, B: "foo"}
+			if err := (Options{StructMode: StructModeArray}).Unmarshal(tt.input, &gotA); err != nil {
+				t.Fatal(err)
+			}
+			if want := (FooA{A: 5}); gotA != want {
+				t.Errorf("Unmarshal() got = %v, want %v", gotA, want)
+			}
+
+			// and for arrays.
+			gotArray := [3]int{1, 2, 3}
+			if err := Unmarshal(tt.input, &gotArray); err != nil {
+				t.Fatal(err)
+			}
+			if want := [3]int{5, 0, 0}; gotArray != want {
+				t.Errorf("Unmarshal() got = %v, want %v", gotArray, want)
+			}
+		})
+	}
+}
+
 func TestUnmarshal_SemanticError(t *testing.T) {
 	t.Run("duplicated map key decoded to map", func(t *testing.T) {
 		data := []byte{
@@ -1254,6 +1876,81 @@ func TestUnmarshal_SemanticError(t *testing.T) {
 		}
 	})
 
+	t.Run("non-text map key decoded to any", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data []byte
+		}{
+			{"byte string", []byte{0xa1, 0x41, 0x01, 0x01}},                                   // {h'01': 1}
+			{"integer", []byte{0xa1, 0x01, 0x01}},                                             // {1: 1}
+			{"indefinite-length map", []byte{0xbf, 0x61, 0x61, 0x01, 0x41, 0x01, 0x01, 0xff}}, // {_ "a": 1, h'01': 1}
+			{"nested", []byte{0x81, 0xa1, 0xf5, 0x01}},                                        // [{true: 1}]
+			{"tagged integer", []byte{0xa1, 0xc1, 0x00, 0x01}},                                // {1(0): 1}
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var v any
+				err := Unmarshal(tt.data, &v)
+				if _, ok := err.(*SemanticError); !ok {
+					t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+				}
+
+				// it is decoded with UseAnyKey.
+				if err := (Options{UseAnyKey: true}).Unmarshal(tt.data, &v); err != nil {
+					t.Errorf("Options.Unmarshal() error = %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("non-text map key decoded to map[string]any", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data []byte
+			want map[string]any
+		}{
+			{"byte string", []byte{0xa1, 0x41, 0x01, 0x01}, map[string]any{}},                                                // {h'01': 1}
+			{"tagged integer", []byte{0xa1, 0xc1, 0x00, 0x01}, map[string]any{}},                                             // {1(0): 1}
+			{"skips the pair", []byte{0xa2, 0x01, 0x01, 0x61, 0x61, 0x02}, map[string]any{"a": int64(2)}},                    // {1: 1, "a": 2}
+			{"indefinite-length map", []byte{0xbf, 0x41, 0x01, 0x01, 0x61, 0x61, 0x02, 0xff}, map[string]any{"a": int64(2)}}, // {_ h'01': 1, "a": 2}
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var m map[string]any
+				err := Unmarshal(tt.data, &m)
+				if _, ok := err.(*SemanticError); !ok {
+					t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+				}
+				if diff := cmp.Diff(tt.want, m); diff != "" {
+					t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("tagged text map key", func(t *testing.T) {
+		data := []byte{0xa1, 0xd9, 0xd9, 0xf7, 0x61, 0x61, 0x01} // {55799("a"): 1}
+		var m map[string]any
+		if err := Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]any{"a": int64(1)}, m); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid map key not stored", func(t *testing.T) {
+		data := []byte{0xa2, 0x61, 0x61, 0x01, 0x02, 0x02} // {"a": 1, 2: 2}
+		var m map[int]int
+		err := Unmarshal(data, &m)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+		if diff := cmp.Diff(map[int]int{2: 2}, m); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("duplicated map key decoded to any", func(t *testing.T) {
 		data := []byte{
 			0xa2,             // map of length 2
@@ -1531,6 +2228,397 @@ func TestWellFormed_invalid(t *testing.T) {
 	}
 }
 
+// set is a generic set type for testing.
+type set[T comparable] map[T]struct{}
+
+func TestUnmarshal_Set(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"null", []byte{0xa2, 0x61, 0x61, 0xf6, 0x61, 0x62, 0xf6}},                        // {"a": null, "b": null}
+		{"true", []byte{0xa2, 0x61, 0x61, 0xf5, 0x61, 0x62, 0xf5}},                        // {"a": true, "b": true}
+		{"undefined", []byte{0xa2, 0x61, 0x61, 0xf7, 0x61, 0x62, 0xf7}},                   // {"a": undefined, "b": undefined}
+		{"empty map", []byte{0xa2, 0x61, 0x61, 0xa0, 0x61, 0x62, 0xa0}},                   // {"a": {}, "b": {}}
+		{"mixed values", []byte{0xa2, 0x61, 0x61, 0x81, 0x01, 0x61, 0x62, 0x41, 0x00}},    // {"a": [1], "b": h'00'}
+		{"indefinite-length map", []byte{0xbf, 0x61, 0x61, 0xf6, 0x61, 0x62, 0xf5, 0xff}}, // {_ "a": null, "b": true}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]struct{}
+			if err := Unmarshal(tt.data, &m); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(map[string]struct{}{"a": {}, "b": {}}, m); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+
+			var s set[string]
+			if err := Unmarshal(tt.data, &s); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(set[string]{"a": {}, "b": {}}, s); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := Marshal(set[int64]{1: {}, 2: {}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got set[int64]
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(set[int64]{1: {}, 2: {}}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("malformed value", func(t *testing.T) {
+		data := []byte{0xa1, 0x61, 0x61, 0x1c} // {"a": <reserved>}
+		var got set[string]
+		if err := Unmarshal(data, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+}
+
+func TestUnmarshal_CompositeKey(t *testing.T) {
+	t.Run("array key", func(t *testing.T) {
+		// {[1, 2]: "a", [_ 3, 4]: "b"}
+		input := []byte{0xa2, 0x82, 0x01, 0x02, 0x61, 0x61, 0x9f, 0x03, 0x04, 0xff, 0x61, 0x62}
+		var got map[[2]int]string
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[[2]int]string{{1, 2}: "a", {3, 4}: "b"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("array key of different length", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input []byte
+			v     any
+		}{
+			{"longer", []byte{0xa1, 0x83, 0x01, 0x02, 0x03, 0x61, 0x61}, new(map[[2]int]string)},          // {[1, 2, 3]: "a"}
+			{"shorter", []byte{0xa1, 0x81, 0x01, 0x61, 0x61}, new(map[[2]int]string)},                     // {[1]: "a"}
+			{"indefinite-length", []byte{0xa1, 0x9f, 0x01, 0xff, 0x61, 0x61}, new(map[[2]int]string)},     // {[_ 1]: "a"}
+			{"nested", []byte{0xa1, 0x81, 0x83, 0x01, 0x02, 0x03, 0x61, 0x61}, new(map[[1][2]int]string)}, // {[[1, 2, 3]]: "a"}
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := Unmarshal(tt.input, tt.v)
+				if _, ok := err.(*UnmarshalTypeError); !ok {
+					t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+				}
+				if m := reflect.ValueOf(tt.v).Elem(); m.Len() != 0 {
+					t.Errorf("Unmarshal() = %v, want empty", m)
+				}
+			})
+		}
+	})
+
+	t.Run("struct key", func(t *testing.T) {
+		// {{"A": 1, "B": "a"}: 1, {_ "A": 2}: 2}
+		input := []byte{
+			0xa2,
+			0xa2, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x61, 0x01,
+			0xbf, 0x61, 0x41, 0x02, 0xff, 0x02,
+		}
+		var got map[FooA]int
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[FooA]int{{A: 1, B: "a"}: 1, {A: 2}: 2}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("struct key in indefinite-length map", func(t *testing.T) {
+		// {_ {"A": 1}: 1}
+		input := []byte{0xbf, 0xa1, 0x61, 0x41, 0x01, 0x01, 0xff}
+		var got map[FooA]int
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[FooA]int{{A: 1}: 1}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("struct key that has an interface field", func(t *testing.T) {
+		type key struct{ A any }
+		// {{"A": [1, 2]}: 1}
+		input := []byte{0xa1, 0xa1, 0x61, 0x41, 0x82, 0x01, 0x02, 0x01}
+		var got map[key]int
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		// the arrays in the key are decoded as Go arrays, which are hashable.
+		want := map[key]int{{A: [2]any{int64(1), int64(2)}}: 1}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+
+		// maps in the key are not hashable.
+		// {{"A": {}}: 1}
+		input = []byte{0xa1, 0xa1, 0x61, 0x41, 0xa0, 0x01}
+		if err := Unmarshal(input, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+
+	t.Run("map key into interface", func(t *testing.T) {
+		// {{}: 1}
+		input := []byte{0xa1, 0xa0, 0x01}
+		var got any
+		if err := (Options{UseAnyKey: true}).Unmarshal(input, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+}
+
+func TestUnmarshal_BytesBuffer(t *testing.T) {
+	t.Run("decode into *bytes.Buffer", func(t *testing.T) {
+		input := []byte{0x43, 0x66, 0x6f, 0x6f} // h'666f6f'
+		var got *bytes.Buffer
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != "foo" {
+			t.Errorf("Unmarshal() = %q, want %q", got.String(), "foo")
+		}
+	})
+
+	t.Run("reset the buffer", func(t *testing.T) {
+		input := []byte{0x5f, 0x42, 0x66, 0x6f, 0x41, 0x6f, 0xff} // (_ h'666f', h'6f')
+		got := bytes.NewBufferString("bar")
+		if err := Unmarshal(input, got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != "foo" {
+			t.Errorf("Unmarshal() = %q, want %q", got.String(), "foo")
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		input := []byte{0x63, 0x66, 0x6f, 0x6f} // "foo"
+		var got bytes.Buffer
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestUnmarshal_ReuseSlice(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []int
+	}{
+		{"array", []byte{0x83, 0x01, 0x02, 0x03}, []int{1, 2, 3}},
+		{"short array", []byte{0x81, 0x01}, []int{1}},
+		{"empty array", []byte{0x80}, []int{}},
+		{"indefinite-length array", []byte{0x9f, 0x01, 0x02, 0x03, 0xff}, []int{1, 2, 3}},
+		{"empty indefinite-length array", []byte{0x9f, 0xff}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := make([]int, 0, 3)
+			backing := &dst[:1][0]
+			allocs := testing.AllocsPerRun(10, func() {
+				if err := Unmarshal(tt.data, &dst); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("Unmarshal() allocs = %v, want 0", allocs)
+			}
+			if &dst[:1][0] != backing {
+				t.Error("Unmarshal() should reuse the backing array")
+			}
+			if diff := cmp.Diff(tt.want, dst); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_Interface(t *testing.T) {
+	t.Run("pointer to any", func(t *testing.T) {
+		var p *any
+		if err := Unmarshal([]byte{0x63, 0x61, 0x62, 0x63}, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p == nil {
+			t.Fatal("Unmarshal() should allocate *any")
+		}
+		if diff := cmp.Diff(any("abc"), *p); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("pointer to pointer to any", func(t *testing.T) {
+		var p **any
+		if err := Unmarshal([]byte{0x01}, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p == nil || *p == nil {
+			t.Fatal("Unmarshal() should allocate **any")
+		}
+		if diff := cmp.Diff(any(int64(1)), **p); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("null into pointer to any", func(t *testing.T) {
+		v := any("abc")
+		p := &v
+		if err := Unmarshal([]byte{0xf6}, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p != nil {
+			t.Errorf("Unmarshal() = %v, want nil", p)
+		}
+	})
+
+	// the value held by the interface doesn't constrain the decoded type.
+	replaceTests := []struct {
+		name string
+		data []byte
+		old  any
+		want any
+	}{
+		{"int to string", []byte{0x63, 0x61, 0x62, 0x63}, 5, "abc"},
+		{"string to int", []byte{0x01}, "abc", int64(1)},
+		{"slice to map", []byte{0xa1, 0x61, 0x61, 0x01}, []any{"x"}, map[string]any{"a": int64(1)}},
+		{"map is not merged", []byte{0xa1, 0x61, 0x62, 0x02}, map[string]any{"a": int64(1)}, map[string]any{"b": int64(2)}},
+		{"struct to array", []byte{0x81, 0x01}, FooA{A: 1}, []any{int64(1)}},
+		{"nil pointer", []byte{0x07}, (*int)(nil), int64(7)},
+		{"null", []byte{0xf6}, 5, nil},
+	}
+	for _, tt := range replaceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.old
+			if err := Unmarshal(tt.data, &v); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, v); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("decode into pointer", func(t *testing.T) {
+		var i int
+		v := any(&i)
+		if err := Unmarshal([]byte{0x07}, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v != any(&i) || i != 7 {
+			t.Errorf("Unmarshal() = %v, %d, want &i, 7", v, i)
+		}
+
+		// the type of the pointer constrains the decoded type.
+		err := Unmarshal([]byte{0x63, 0x61, 0x62, 0x63}, &v)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("merge into pointer", func(t *testing.T) {
+		m := map[string]int{"a": 1}
+		v := any(&m)
+		if err := Unmarshal([]byte{0xa1, 0x61, 0x62, 0x02}, &v); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(map[string]int{"a": 1, "b": 2}, m); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+
+		foo := &FooA{A: 1, B: "foo"}
+		v = foo
+		// {"B": "bar"}
+		if err := Unmarshal([]byte{0xa1, 0x61, 0x42, 0x63, 0x62, 0x61, 0x72}, &v); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FooA{A: 1, B: "bar"}, v); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("null into pointer", func(t *testing.T) {
+		var i int
+		v := any(&i)
+		if err := Unmarshal([]byte{0xf6}, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v != nil {
+			t.Errorf("Unmarshal() = %v, want nil", v)
+		}
+	})
+}
+
+func BenchmarkUnmarshal_ReuseSlice(b *testing.B) {
+	input := []byte{0x83, 0x01, 0x02, 0x03}
+	dst := make([]int, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Unmarshal(input, &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal_MapOfStructs(b *testing.B) {
+	m := make(map[string]FooA, 10000)
+	for i := 0; i < 10000; i++ {
+		m[strconv.Itoa(i)] = FooA{A: i, B: "foo"}
+	}
+	input, err := Marshal(m)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var dst map[string]FooA
+		if err := Unmarshal(input, &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal_StructWithManyFields(b *testing.B) {
+	type manyFields struct {
+		Alpha, Bravo, Charlie, Delta, Echo, Foxtrot, Golf, Hotel  int
+		India, Juliett, Kilo, Lima, Mike, November, Oscar, Papa   string
+		Quebec, Romeo, Sierra, Tango, Uniform, Victor, Whiskey, X bool
+	}
+	input, err := Marshal(manyFields{Alpha: 1, India: "foo", Quebec: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var dst manyFields
+		if err := Unmarshal(input, &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkMaliciousCBORData(b *testing.B) {
 	var v any
 	input := []byte{0x9B, 0x00, 0x00, 0x42, 0xFA, 0x42, 0xFA, 0x42, 0xFA, 0x42}
@@ -1540,3 +2628,66 @@ func BenchmarkMaliciousCBORData(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkUnmarshal_SmallInts(b *testing.B) {
+	v := make([]int, 1000)
+	for i := range v {
+		v[i] = i % 24
+	}
+	input, err := Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("int", func(b *testing.B) {
+		b.ReportAllocs()
+		var dst []int
+		for i := 0; i < b.N; i++ {
+			if err := Unmarshal(input, &dst); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("any", func(b *testing.B) {
+		b.ReportAllocs()
+		var dst []any
+		for i := 0; i < b.N; i++ {
+			if err := Unmarshal(input, &dst); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkUnmarshal_InternKeys(b *testing.B) {
+	v := make([]map[string]any, 1000)
+	for i := range v {
+		v[i] = map[string]any{"id": i, "name": "foo", "value": 1.5}
+	}
+	input, err := Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("default", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var dst []map[string]any
+			if err := Unmarshal(input, &dst); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("InternKeys", func(b *testing.B) {
+		opts := Options{InternKeys: true}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var dst []map[string]any
+			if err := opts.Unmarshal(input, &dst); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
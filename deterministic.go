@@ -0,0 +1,403 @@
+package cbor
+
+import (
+	"bytes"
+	"math"
+	"slices"
+
+	"github.com/shogo82148/float16"
+)
+
+// CheckDeterministic reports whether data is encoded in the deterministic form
+// defined by RFC 8949 Section 4.2.1 (Core Deterministic Encoding Requirements).
+// It returns nil if data is well-formed and deterministic.
+//
+// The following requirements are checked:
+//
+//   - integers, lengths and tag numbers are encoded in the shortest form.
+//   - floating-point values are encoded in the shortest form that preserves the value.
+//   - indefinite-length items are not used.
+//   - the keys in every map are sorted in the bytewise lexicographic order of their encodings, and are unique.
+//   - the byte strings of bignums (tag number 2 and 3) have no leading zero bytes.
+func CheckDeterministic(data []byte) error {
+	d := newDecodeState(data)
+	if err := d.checkWellFormed(); err != nil {
+		return err
+	}
+
+	d.init(data)
+	return d.checkDeterministicChild()
+}
+
+// checkDeterministicChild checks the next data item is encoded deterministically.
+// The data must be well-formed.
+func (d *decodeState) checkDeterministicChild() error {
+	start := d.off
+	typ, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	major := majorType(typ >> 5)
+	info := typ & 0x1f
+
+	if major == majorTypeOther {
+		return d.checkDeterministicOther(start, info)
+	}
+
+	if info == 31 {
+		d.off = start
+		return d.newSyntaxError("cbor: indefinite-length item is not deterministic")
+	}
+	arg, err := d.readArgument(info)
+	if err != nil {
+		return err
+	}
+	if !isShortestArgument(info, arg) {
+		d.off = start
+		return d.newSyntaxError("cbor: integer is not encoded in the shortest form")
+	}
+
+	switch major {
+	case majorTypeBytes, majorTypeString:
+		d.off += int(arg)
+
+	case majorTypeArray:
+		for i := uint64(0); i < arg; i++ {
+			if err := d.checkDeterministicChild(); err != nil {
+				return err
+			}
+		}
+
+	case majorTypeMap:
+		var prevKey []byte
+		for i := uint64(0); i < arg; i++ {
+			keyStart := d.off
+			if err := d.checkDeterministicChild(); err != nil {
+				return err
+			}
+			key := d.data[keyStart:d.off]
+			if i > 0 {
+				switch bytes.Compare(prevKey, key) {
+				case 0:
+					return newSemanticError("cbor: duplicate map key")
+				case 1:
+					d.off = keyStart
+					return d.newSyntaxError("cbor: map keys are not sorted")
+				}
+			}
+			prevKey = key
+
+			if err := d.checkDeterministicChild(); err != nil {
+				return err
+			}
+		}
+
+	case majorTypeTag:
+		if isBignumTag(arg) && d.hasBignumLeadingZero() {
+			d.off = start
+			return d.newSyntaxError("cbor: bignum has leading zero bytes")
+		}
+		return d.checkDeterministicChild()
+	}
+	return nil
+}
+
+// isBignumTag reports whether n is the tag number of bignums (tag number 2 and 3).
+func isBignumTag(n uint64) bool {
+	return TagNumber(n) == tagNumberPositiveBignum || TagNumber(n) == tagNumberNegativeBignum
+}
+
+// hasBignumLeadingZero reports whether the next data item is
+// a definite-length byte string that starts with zero.
+// It doesn't consume the data item.
+func (d *decodeState) hasBignumLeadingZero() bool {
+	off := d.off
+	defer func() { d.off = off }()
+
+	typ, err := d.readByte()
+	if err != nil || majorType(typ>>5) != majorTypeBytes || typ&0x1f == 31 {
+		return false
+	}
+	n, err := d.readArgument(typ & 0x1f)
+	if err != nil {
+		return false
+	}
+	return n > 0 && d.off < len(d.data) && d.data[d.off] == 0x00
+}
+
+func (d *decodeState) checkDeterministicOther(start int, info byte) error {
+	switch info {
+	// simple value (one-byte uint8_t follows)
+	case 24:
+		d.off++
+
+	// half-precision float (two-byte IEEE 754)
+	case 25:
+		d.off += 2
+
+	// single-precision float (four-byte IEEE 754)
+	case 26:
+		w, err := d.readUint32()
+		if err != nil {
+			return err
+		}
+		if !isShortestFloat(float64(math.Float32frombits(w)), 5) {
+			d.off = start
+			return d.newSyntaxError("cbor: float is not encoded in the shortest form")
+		}
+
+	// double-precision float (eight-byte IEEE 754)
+	case 27:
+		w, err := d.readUint64()
+		if err != nil {
+			return err
+		}
+		if !isShortestFloat(math.Float64frombits(w), 9) {
+			d.off = start
+			return d.newSyntaxError("cbor: float is not encoded in the shortest form")
+		}
+	}
+	return nil
+}
+
+// readArgument reads the argument of the head.
+// info is the additional information of the initial byte.
+func (d *decodeState) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		w, err := d.readByte()
+		return uint64(w), err
+	case info == 25:
+		w, err := d.readUint16()
+		return uint64(w), err
+	case info == 26:
+		w, err := d.readUint32()
+		return uint64(w), err
+	case info == 27:
+		return d.readUint64()
+	}
+	return 0, d.newSyntaxError("cbor: invalid additional information")
+}
+
+// isShortestArgument reports whether arg is encoded in the shortest form.
+func isShortestArgument(info byte, arg uint64) bool {
+	switch info {
+	case 24:
+		return arg >= 24
+	case 25:
+		return arg > math.MaxUint8
+	case 26:
+		return arg > math.MaxUint16
+	case 27:
+		return arg > math.MaxUint32
+	}
+	return true
+}
+
+// isShortestFloat reports whether the shortest encoding of f has n bytes.
+// NaN is always encoded as 0xf97e00 in the shortest form.
+func isShortestFloat(f float64, n int) bool {
+	var e encodeState
+	e.encodeFloat64(f)
+	return e.buf.Len() == n
+}
+
+// Canonicalize returns the deterministic encoding of data
+// defined by RFC 8949 Section 4.2.1 (Core Deterministic Encoding Requirements).
+// Unlike CheckDeterministic, it accepts any well-formed data item and
+// re-encodes it in the shortest form, with definite lengths and sorted map keys.
+// Tags and bignums are preserved, but the leading zero bytes of bignums are removed.
+// It returns a SemanticError if a map has duplicate keys.
+//
+// It is useful to normalize the payload before signing.
+func Canonicalize(data []byte) ([]byte, error) {
+	d := newDecodeState(data)
+	if err := d.checkWellFormed(); err != nil {
+		return nil, err
+	}
+
+	d.init(data)
+	var e encodeState
+	if err := d.canonicalizeChild(&e); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// canonicalizeChild writes the deterministic encoding of the next data item to e.
+// The data must be well-formed.
+func (d *decodeState) canonicalizeChild(e *encodeState) error {
+	typ, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	major := majorType(typ >> 5)
+	info := typ & 0x1f
+
+	if major == majorTypeOther {
+		switch info {
+		// simple value (one-byte uint8_t follows)
+		case 24:
+			w, err := d.readByte()
+			if err != nil {
+				return err
+			}
+			e.writeByte(typ)
+			e.writeByte(w)
+
+		// half-precision float (two-byte IEEE 754)
+		case 25:
+			w, err := d.readUint16()
+			if err != nil {
+				return err
+			}
+			return e.encodeFloat64(float16.FromBits(w).Float64())
+
+		// single-precision float (four-byte IEEE 754)
+		case 26:
+			w, err := d.readUint32()
+			if err != nil {
+				return err
+			}
+			return e.encodeFloat64(float64(math.Float32frombits(w)))
+
+		// double-precision float (eight-byte IEEE 754)
+		case 27:
+			w, err := d.readUint64()
+			if err != nil {
+				return err
+			}
+			return e.encodeFloat64(math.Float64frombits(w))
+
+		default:
+			e.writeByte(typ)
+		}
+		return nil
+	}
+
+	indefinite := info == 31
+	var arg uint64
+	if !indefinite {
+		arg, err = d.readArgument(info)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch major {
+	case majorTypePositiveInt, majorTypeNegativeInt:
+		e.writeUint(major, arg)
+
+	case majorTypeBytes, majorTypeString:
+		if !indefinite {
+			e.writeUint(major, arg)
+			e.buf.Write(d.data[d.off : d.off+int(arg)])
+			d.off += int(arg)
+			return nil
+		}
+
+		// concatenate the chunks.
+		var s []byte
+		for d.hasNext(true, 0, 0) {
+			chunk, err := d.readByte()
+			if err != nil {
+				return err
+			}
+			n, err := d.readArgument(chunk & 0x1f)
+			if err != nil {
+				return err
+			}
+			s = append(s, d.data[d.off:d.off+int(n)]...)
+			d.off += int(n)
+		}
+		e.writeUint(major, uint64(len(s)))
+		e.buf.Write(s)
+
+	case majorTypeArray:
+		var items encodeState
+		var n uint64
+		for ; d.hasNext(indefinite, n, arg); n++ {
+			if err := d.canonicalizeChild(&items); err != nil {
+				return err
+			}
+		}
+		e.writeUint(majorTypeArray, n)
+		e.buf.Write(items.buf.Bytes())
+
+	case majorTypeMap:
+		type pair struct {
+			key, value []byte
+		}
+		var pairs []pair
+		var n uint64
+		for ; d.hasNext(indefinite, n, arg); n++ {
+			var key, value encodeState
+			if err := d.canonicalizeChild(&key); err != nil {
+				return err
+			}
+			if err := d.canonicalizeChild(&value); err != nil {
+				return err
+			}
+			pairs = append(pairs, pair{key.buf.Bytes(), value.buf.Bytes()})
+		}
+		slices.SortFunc(pairs, func(a, b pair) int {
+			return bytes.Compare(a.key, b.key)
+		})
+		for i := 1; i < len(pairs); i++ {
+			if bytes.Equal(pairs[i-1].key, pairs[i].key) {
+				return newSemanticError("cbor: duplicate map key")
+			}
+		}
+		e.writeUint(majorTypeMap, n)
+		for _, p := range pairs {
+			e.buf.Write(p.key)
+			e.buf.Write(p.value)
+		}
+
+	case majorTypeTag:
+		e.writeUint(majorTypeTag, arg)
+		if !isBignumTag(arg) {
+			return d.canonicalizeChild(e)
+		}
+
+		// strip the leading zero bytes of bignums. See RFC 8949 Section 3.4.3.
+		var content encodeState
+		if err := d.canonicalizeChild(&content); err != nil {
+			return err
+		}
+		b := content.buf.Bytes()
+		if majorType(b[0]>>5) != majorTypeBytes {
+			e.buf.Write(b)
+			return nil
+		}
+		c := newDecodeState(b)
+		c.off = 1
+		n, err := c.readArgument(b[0] & 0x1f)
+		if err != nil {
+			return err
+		}
+		s := b[c.off : c.off+int(n)]
+		for len(s) > 0 && s[0] == 0x00 {
+			s = s[1:]
+		}
+		e.writeUint(majorTypeBytes, uint64(len(s)))
+		e.buf.Write(s)
+	}
+	return nil
+}
+
+// hasNext reports whether the next data item of an array or a map is available.
+// For indefinite-length items, it consumes the "break" stop code.
+// Otherwise, i is the number of read items and n is the length.
+func (d *decodeState) hasNext(indefinite bool, i, n uint64) bool {
+	if !indefinite {
+		return i < n
+	}
+	if d.data[d.off] == 0xff {
+		d.off++
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCheckDeterministic(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"integer zero", []byte{0x00}},
+		{"integer twenty-four", []byte{0x18, 0x18}},
+		{"integer 256", []byte{0x19, 0x01, 0x00}},
+		{"integer 65536", []byte{0x1a, 0x00, 0x01, 0x00, 0x00}},
+		{"integer 2^32", []byte{0x1b, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{"negative integer -25", []byte{0x38, 0x18}},
+		{"float 1.0", []byte{0xf9, 0x3c, 0x00}},
+		{"float 100000.0", []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}},
+		{"float 1.1", []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{"NaN", []byte{0xf9, 0x7e, 0x00}},
+		{"byte string", []byte{0x44, 0x01, 0x02, 0x03, 0x04}},
+		{"text string", []byte{0x64, 0x49, 0x45, 0x54, 0x46}},
+		{"array", []byte{0x83, 0x01, 0x82, 0x02, 0x03, 0x82, 0x04, 0x05}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x03, 0x04}},
+		{"map with mixed keys", []byte{0xa3, 0x0a, 0x01, 0x20, 0x02, 0x61, 0x61, 0x03}},
+		{"tag", []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}},
+		{"simple", []byte{0xf8, 0xff}},
+		{"bignum", []byte{0xc2, 0x42, 0x01, 0x00}},
+		{"empty bignum", []byte{0xc3, 0x40}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckDeterministic(tt.data); err != nil {
+				t.Errorf("CheckDeterministic(%x) = %v, want nil", tt.data, err)
+			}
+		})
+	}
+}
+
+func TestCheckDeterministic_SyntaxError(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int64
+	}{
+		{"non-shortest integer", []byte{0x18, 0x0a}, 0},
+		{"non-shortest uint16", []byte{0x19, 0x00, 0xff}, 0},
+		{"non-shortest uint32", []byte{0x1a, 0x00, 0x00, 0xff, 0xff}, 0},
+		{"non-shortest uint64", []byte{0x1b, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, 0},
+		{"non-shortest negative integer", []byte{0x38, 0x00}, 0},
+		{"non-shortest length", []byte{0x58, 0x01, 0x00}, 0},
+		{"non-shortest tag number", []byte{0xd8, 0x01, 0x00}, 0},
+		{"non-shortest array element", []byte{0x82, 0x00, 0x18, 0x01}, 2},
+		{"non-shortest float32", []byte{0xfa, 0x3f, 0x80, 0x00, 0x00}, 0},
+		{"non-shortest float64", []byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, 0},
+		{"NaN in float32", []byte{0xfa, 0x7f, 0xc0, 0x00, 0x00}, 0},
+		{"indefinite-length array", []byte{0x9f, 0xff}, 0},
+		{"indefinite-length map", []byte{0xbf, 0xff}, 0},
+		{"indefinite-length byte string", []byte{0x5f, 0xff}, 0},
+		{"indefinite-length text string", []byte{0x7f, 0xff}, 0},
+		{"unsorted map keys", []byte{0xa2, 0x03, 0x04, 0x01, 0x02}, 3},
+		{"unsorted map keys by length", []byte{0xa2, 0x62, 0x61, 0x61, 0x01, 0x61, 0x62, 0x02}, 5},
+		{"bignum with leading zero", []byte{0xc2, 0x42, 0x00, 0x01}, 0},
+		{"negative bignum with leading zero", []byte{0xc3, 0x41, 0x00}, 0},
+		{"bignum with leading zero in array", []byte{0x81, 0xc2, 0x42, 0x00, 0x01}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDeterministic(tt.data)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("CheckDeterministic(%x) = %v, want *SyntaxError", tt.data, err)
+			}
+			if se.Offset != tt.offset {
+				t.Errorf("unexpected Offset: got %d, want %d", se.Offset, tt.offset)
+			}
+		})
+	}
+}
+
+func TestCheckDeterministic_SemanticError(t *testing.T) {
+	data := []byte{0xa2, 0x01, 0x02, 0x01, 0x03}
+	err := CheckDeterministic(data)
+	var se *SemanticError
+	if !errors.As(err, &se) {
+		t.Errorf("CheckDeterministic(%x) = %v, want *SemanticError", data, err)
+	}
+}
+
+func TestCheckDeterministic_NotWellFormed(t *testing.T) {
+	for _, data := range notWellFormed {
+		if err := CheckDeterministic(data); err == nil {
+			t.Errorf("CheckDeterministic(%x) should fail", data)
+		}
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"integer", []byte{0x18, 0x0a}, []byte{0x0a}},
+		{"negative integer", []byte{0x39, 0x00, 0x18}, []byte{0x38, 0x18}},
+		{"float", []byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, []byte{0xf9, 0x3c, 0x00}},
+		{"indefinite-length string", []byte{0x7f, 0x61, 0x61, 0x61, 0x62, 0xff}, []byte{0x62, 0x61, 0x62}},
+		{"indefinite-length array", []byte{0x9f, 0x18, 0x01, 0xff}, []byte{0x81, 0x01}},
+		{
+			"map keys",
+			[]byte{0xbf, 0x61, 0x61, 0x01, 0x20, 0x02, 0x0a, 0x03, 0xff}, // {_ "a": 1, -1: 2, 10: 3}
+			[]byte{0xa3, 0x0a, 0x03, 0x20, 0x02, 0x61, 0x61, 0x01},       // {10: 3, -1: 2, "a": 1}
+		},
+		{
+			"tag",
+			[]byte{0xd9, 0x00, 0x01, 0x1a, 0x00, 0x00, 0x00, 0x01}, // 1(1) with long heads
+			[]byte{0xc1, 0x01},
+		},
+		{
+			"bignum",
+			[]byte{0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			[]byte{0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{"bignum with leading zeros", []byte{0xc2, 0x43, 0x00, 0x00, 0x01}, []byte{0xc2, 0x41, 0x01}},
+		{"negative bignum of zero bytes", []byte{0xc3, 0x42, 0x00, 0x00}, []byte{0xc3, 0x40}},
+		{
+			"indefinite-length bignum",
+			[]byte{0xc2, 0x5f, 0x41, 0x00, 0x41, 0x01, 0xff},
+			[]byte{0xc2, 0x41, 0x01},
+		},
+		{"bignum of non-bytes", []byte{0xc2, 0x18, 0x01}, []byte{0xc2, 0x01}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Canonicalize(%x) = %x, want %x", tt.data, got, tt.want)
+			}
+			if err := CheckDeterministic(got); err != nil {
+				t.Errorf("CheckDeterministic(%x) = %v, want nil", got, err)
+			}
+		})
+	}
+
+	t.Run("duplicate map key", func(t *testing.T) {
+		data := []byte{0xa2, 0x01, 0x02, 0x18, 0x01, 0x03} // {1: 2, 1: 3}
+		_, err := Canonicalize(data)
+		var se *SemanticError
+		if !errors.As(err, &se) {
+			t.Errorf("Canonicalize(%x) = %v, want *SemanticError", data, err)
+		}
+	})
+
+	t.Run("not well-formed", func(t *testing.T) {
+		for _, data := range notWellFormed {
+			if _, err := Canonicalize(data); err == nil {
+				t.Errorf("Canonicalize(%x) should fail", data)
+			}
+		}
+	})
+}
@@ -7,9 +7,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"io"
 	"math"
 	"math/big"
 	"strconv"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/shogo82148/float16"
@@ -18,6 +20,8 @@ import (
 type encodingIndicator int
 
 // DecodeEDN parses the Extended Diagnostic Notation encoded data and returns the result.
+// The byte strings in the form b64'...' may use either the base64url or the classic base64 alphabet,
+// with or without padding. Comments are not allowed inside them, because '/' is a character of base64.
 func DecodeEDN(data []byte) (RawMessage, error) {
 	s := ednDecState{data: data}
 	s.decode()
@@ -640,15 +644,12 @@ func (s *ednDecState) decodeString(buf []byte) ([]byte, bool) {
 		return append(buf, data...), true
 	}
 
-	// base64url format
+	// base64url or base64 format
 	if bytes.HasPrefix(s.data[s.off:], []byte("b64'")) {
 		s.off += len("b64'")
 		var tmp bytes.Buffer
 		for {
-			s.skipWhitespace()
-			if s.err != nil {
-				return buf, false
-			}
+			// '/' is a character of base64, so comments are not allowed here.
 			ch, err := s.readByte()
 			if err != nil {
 				s.err = err
@@ -658,10 +659,20 @@ func (s *ednDecState) decodeString(buf []byte) ([]byte, bool) {
 				// end of byte string
 				break
 			}
+			switch ch {
+			case ' ', '\t', '\r', '\n':
+				continue
+			}
 			tmp.WriteByte(ch)
 		}
-		b64 := base64.URLEncoding.WithPadding(base64.NoPadding)
-		data, err := b64.DecodeString(tmp.String())
+		// both base64url and base64 are accepted with or without padding.
+		// See RFC 8610 Appendix G.2.
+		str := strings.TrimRight(tmp.String(), "=")
+		enc := base64.RawURLEncoding
+		if strings.ContainsAny(str, "+/") {
+			enc = base64.RawStdEncoding
+		}
+		data, err := enc.DecodeString(str)
 		if err != nil {
 			s.err = err
 			return buf, false
@@ -1091,6 +1102,15 @@ func (s *ednDecState) convertSimple() {
 }
 
 // EncodeEDN returns the Extended Diagnostic Notation encoding of msg.
+//
+// Positive and negative bignums (tag number 2 and 3) are rendered as decimal integers.
+// Decimal fractions and bigfloats (tag number 4 and 5) are rendered in the array form,
+// e.g. 4([-2, 27315]) for 273.15, as RFC 8949 Appendix A does.
+// The array form keeps the exponent and the mantissa distinguishable from floating-point numbers.
+// Encoded CBOR data items (tag number 24) are rendered as the embedded CBOR,
+// e.g. 24(<<"IETF">>) instead of 24(h'6449455446'), unless the byte string is not well-formed.
+// The byte strings in the expected conversions to base64url and base64 (tag number 21 and 22)
+// are rendered in the base64 form of the conversion, e.g. 21(b64'AQID') and 22(b64'+/8=').
 func (m RawMessage) EncodeEDN() ([]byte, error) {
 	s := ednEncState{data: m}
 	s.encode()
@@ -1100,11 +1120,124 @@ func (m RawMessage) EncodeEDN() ([]byte, error) {
 	return s.buf.Bytes(), nil
 }
 
+// EncodeEDNIndent is like EncodeEDN but applies Indent to format the output.
+// Each element in an array or a map begins on a new line beginning with prefix
+// followed by one or more copies of indent according to the indentation nesting.
+func (m RawMessage) EncodeEDNIndent(prefix, indent string) ([]byte, error) {
+	s := ednEncState{data: m, pretty: true, prefix: prefix, indent: indent}
+	s.encode()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.buf.Bytes(), nil
+}
+
+// An EDNEncoder writes the Extended Diagnostic Notation of CBOR data items to an output stream.
+// The output is written while the data items are rendered,
+// so that a large data item doesn't need to be held in memory as EDN.
+type EDNEncoder struct {
+	w      io.Writer
+	pretty bool
+	prefix string
+	indent string
+}
+
+// NewEDNEncoder returns a new EDN encoder that writes to w.
+func NewEDNEncoder(w io.Writer) *EDNEncoder {
+	return &EDNEncoder{w: w}
+}
+
+// SetIndent instructs the encoder to format each subsequent data item
+// as if indented by EncodeEDNIndent.
+// Calling SetIndent("", "") disables indentation.
+func (enc *EDNEncoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+	enc.pretty = prefix != "" || indent != ""
+}
+
+// Encode writes the EDN of the data item in data to the stream, followed by a newline character.
+// See EncodeEDN for the notation.
+// If data is malformed, Encode returns an error and
+// the part of the EDN that is rendered before the error may have been written.
+func (enc *EDNEncoder) Encode(data RawMessage) error {
+	s := ednEncState{
+		w:      enc.w,
+		data:   data,
+		pretty: enc.pretty,
+		prefix: enc.prefix,
+		indent: enc.indent,
+	}
+	s.encode()
+	if s.err != nil {
+		return s.err
+	}
+	s.buf.WriteByte('\n')
+	_, err := s.buf.WriteTo(enc.w)
+	return err
+}
+
 type ednEncState struct {
 	buf  bytes.Buffer
+	w    io.Writer // if not nil, buf is flushed to w while encoding
 	data RawMessage
 	off  int // next read offset in data
 	err  error
+
+	// options for EncodeEDNIndent
+	pretty bool
+	prefix string
+	indent string
+	depth  int // nesting depth of arrays and maps
+
+	// expected is the tag number of the expected conversion (tag number 21, 22 and 23)
+	// that applies to the byte strings being rendered, or zero if there is none.
+	expected TagNumber
+}
+
+// ednFlushSize is the size of the buffered output that EDNEncoder writes at once.
+const ednFlushSize = 4096
+
+// flush writes the buffered output to w if it is large enough.
+func (s *ednEncState) flush() {
+	if s.w == nil || s.err != nil || s.buf.Len() < ednFlushSize {
+		return
+	}
+	if _, err := s.buf.WriteTo(s.w); err != nil {
+		s.err = err
+	}
+}
+
+// writeElemSep writes the separator before the i-th element of an array or a map.
+func (s *ednEncState) writeElemSep(i int) {
+	s.flush()
+	if i > 0 {
+		s.buf.WriteByte(',')
+		if !s.pretty {
+			s.buf.WriteByte(' ')
+		}
+	}
+	if s.pretty {
+		s.newline()
+	}
+}
+
+// writeClose writes the separator before the closing bracket of an array or a map with n elements.
+func (s *ednEncState) writeClose(n int, indefinite bool) {
+	if s.pretty && n > 0 {
+		s.newline()
+	} else if indefinite && s.pretty {
+		// "[_ ]" and "{_ }"
+		s.buf.WriteByte(' ')
+	}
+}
+
+func (s *ednEncState) newline() {
+	s.buf.WriteByte('\n')
+	s.buf.WriteString(s.prefix)
+	for i := 0; i < s.depth; i++ {
+		s.buf.WriteString(s.indent)
+	}
 }
 
 func (s *ednEncState) readByte() (byte, error) {
@@ -1474,9 +1607,13 @@ func (s *ednEncState) encode() {
 
 	// array (indefinite length)
 	case 0x9f:
-		s.buf.WriteString("[_ ")
-		first := true
-		for {
+		s.buf.WriteString("[_")
+		if !s.pretty {
+			s.buf.WriteByte(' ')
+		}
+		s.depth++
+		var i int
+		for ; ; i++ {
 			typ, err := s.peekByte()
 			if err != nil {
 				s.err = err
@@ -1486,12 +1623,11 @@ func (s *ednEncState) encode() {
 				s.off++
 				break
 			}
-			if !first {
-				s.buf.WriteString(", ")
-			}
-			first = false
+			s.writeElemSep(i)
 			s.encode()
 		}
+		s.depth--
+		s.writeClose(i, true)
 		s.buf.WriteByte(']')
 
 	// map (0x00..0x17 pairs of data items follow)
@@ -1537,9 +1673,13 @@ func (s *ednEncState) encode() {
 
 	// map (indefinite length)
 	case 0xbf:
-		s.buf.WriteString("{_ ")
-		first := true
-		for {
+		s.buf.WriteString("{_")
+		if !s.pretty {
+			s.buf.WriteByte(' ')
+		}
+		s.depth++
+		var i int
+		for ; ; i++ {
 			typ, err := s.peekByte()
 			if err != nil {
 				s.err = err
@@ -1549,10 +1689,7 @@ func (s *ednEncState) encode() {
 				s.off++
 				break
 			}
-			if !first {
-				s.buf.WriteString(", ")
-			}
-			first = false
+			s.writeElemSep(i)
 			s.encode()
 			if s.err != nil {
 				return
@@ -1564,6 +1701,8 @@ func (s *ednEncState) encode() {
 				return
 			}
 		}
+		s.depth--
+		s.writeClose(i, true)
 		s.buf.WriteByte('}')
 
 	// positive big int
@@ -1691,6 +1830,19 @@ func (s *ednEncState) convertBytes(n uint64) {
 	off := s.off
 	s.off += int(n)
 
+	switch s.expected {
+	case tagNumberExpectedBase64URL:
+		s.buf.WriteString("b64'")
+		s.buf.WriteString(b64url.EncodeToString(s.data[off:s.off]))
+		s.buf.WriteByte('\'')
+		return
+	case tagNumberExpectedBase64:
+		s.buf.WriteString("b64'")
+		s.buf.WriteString(b64.EncodeToString(s.data[off:s.off]))
+		s.buf.WriteByte('\'')
+		return
+	}
+
 	s.buf.WriteByte('h')
 	s.buf.WriteByte('\'')
 	b := s.buf.AvailableBuffer()
@@ -1727,26 +1879,24 @@ func (s *ednEncState) convertString(n uint64) {
 
 func (s *ednEncState) convertArray(n uint64) {
 	s.buf.WriteByte('[')
+	s.depth++
 	for i := uint64(0); i < n; i++ {
-		if i > 0 {
-			s.buf.WriteByte(',')
-			s.buf.WriteByte(' ')
-		}
+		s.writeElemSep(int(i))
 		s.encode()
 		if s.err != nil {
 			return
 		}
 	}
+	s.depth--
+	s.writeClose(int(n), false)
 	s.buf.WriteByte(']')
 }
 
 func (s *ednEncState) convertMap(n uint64) {
 	s.buf.WriteByte('{')
+	s.depth++
 	for i := uint64(0); i < n; i++ {
-		if i > 0 {
-			s.buf.WriteByte(',')
-			s.buf.WriteByte(' ')
-		}
+		s.writeElemSep(int(i))
 		s.encode()
 		if s.err != nil {
 			return
@@ -1758,6 +1908,8 @@ func (s *ednEncState) convertMap(n uint64) {
 			return
 		}
 	}
+	s.depth--
+	s.writeClose(int(n), false)
 	s.buf.WriteByte('}')
 }
 
@@ -1766,6 +1918,19 @@ func (s *ednEncState) convertTag(n uint64) {
 	b = strconv.AppendUint(b, n, 10)
 	s.buf.Write(b)
 	s.buf.WriteByte('(')
+	switch TagNumber(n) {
+	case tagNumberEncodedData:
+		if s.convertEmbeddedCBOR() {
+			s.buf.WriteByte(')')
+			return
+		}
+	case tagNumberExpectedBase64URL, tagNumberExpectedBase64, tagNumberExpectedBase16:
+		// the expected conversion applies to all byte strings in the content,
+		// except for those in the nested expected conversions.
+		orig := s.expected
+		s.expected = TagNumber(n)
+		defer func() { s.expected = orig }()
+	}
 	s.encode()
 	if s.err != nil {
 		return
@@ -1773,6 +1938,43 @@ func (s *ednEncState) convertTag(n uint64) {
 	s.buf.WriteByte(')')
 }
 
+// convertEmbeddedCBOR renders the byte string of an encoded CBOR data item (tag number 24)
+// as the embedded CBOR, e.g. <<"IETF">> for h'6449455446'.
+// It reports false without reading the data if the byte string doesn't contain
+// a well-formed data item that can be rendered.
+func (s *ednEncState) convertEmbeddedCBOR() bool {
+	major, arg, indefinite, headLen, err := PeekHead(s.data[s.off:])
+	if err != nil || major != MajorTypeBytes || indefinite {
+		return false
+	}
+	start := s.off + headLen
+	if arg > uint64(len(s.data)-start) {
+		return false
+	}
+	end := start + int(arg)
+	content := s.data[start:end]
+	if !WellFormed(content) {
+		return false
+	}
+
+	t := &ednEncState{
+		data:   RawMessage(content),
+		pretty: s.pretty,
+		prefix: s.prefix,
+		indent: s.indent,
+		depth:  s.depth,
+	}
+	t.encode()
+	if t.err != nil {
+		return false
+	}
+	s.buf.WriteString("<<")
+	t.buf.WriteTo(&s.buf)
+	s.buf.WriteString(">>")
+	s.off = end
+	return true
+}
+
 func (s *ednEncState) convertFloat(v float64) {
 	// special cases
 	switch {
@@ -1797,47 +1999,65 @@ func (s *ednEncState) convertFloat(v float64) {
 }
 
 func (s *ednEncState) convertBigInt(sign int) {
-	i := new(big.Int)
-
-	tye, err := s.readByte()
+	typ, err := s.peekByte()
 	if err != nil {
 		s.err = err
 		return
 	}
 
-	switch tye {
-	case 0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f, 0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57:
-		n := int(tye & 0x1f)
-		i.SetBytes(s.data[s.off : s.off+n])
-		s.off += n
-	case 0x58:
-		n, err := s.readByte()
+	var n uint64
+	switch {
+	case typ >= 0x40 && typ <= 0x57:
+		s.off++
+		n = uint64(typ & 0x1f)
+	case typ == 0x58:
+		s.off++
+		m, err := s.readByte()
 		if err != nil {
 			s.err = err
+			return
 		}
-		i.SetBytes(s.data[s.off : s.off+int(n)])
-		s.off += int(n)
-	case 0x59:
-		n, err := s.readUint16()
+		n = uint64(m)
+	case typ == 0x59:
+		s.off++
+		m, err := s.readUint16()
 		if err != nil {
 			s.err = err
+			return
 		}
-		i.SetBytes(s.data[s.off : s.off+int(n)])
-		s.off += int(n)
-	case 0x5a:
-		n, err := s.readUint32()
+		n = uint64(m)
+	case typ == 0x5a:
+		s.off++
+		m, err := s.readUint32()
 		if err != nil {
 			s.err = err
+			return
 		}
-		i.SetBytes(s.data[s.off : s.off+int(n)])
-		s.off += int(n)
-	case 0x5b:
-		n, err := s.readUint64()
+		n = uint64(m)
+	case typ == 0x5b:
+		s.off++
+		m, err := s.readUint64()
 		if err != nil {
 			s.err = err
+			return
+		}
+		n = m
+	default:
+		// the content is not a definite-length byte string.
+		// fall back to the generic notation.
+		if sign < 0 {
+			s.convertTag(uint64(tagNumberNegativeBignum))
+		} else {
+			s.convertTag(uint64(tagNumberPositiveBignum))
 		}
-		i.SetBytes(s.data[s.off : s.off+int(n)])
+		return
 	}
+	if !s.isAvailable(n) {
+		s.err = ErrUnexpectedEnd
+		return
+	}
+	i := new(big.Int).SetBytes(s.data[s.off : s.off+int(n)])
+	s.off += int(n)
 
 	if sign < 0 {
 		i.Not(i)
@@ -3,7 +3,10 @@ package cbor
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDecodeEDN(t *testing.T) {
@@ -140,6 +143,10 @@ func TestDecodeEDN(t *testing.T) {
 			in:  `<<>>`,
 			out: RawMessage{0x40},
 		},
+		{
+			in:  `24(<<[1, 24(<<2>>)]>>)`,
+			out: RawMessage{0xd8, 0x18, 0x46, 0x82, 0x01, 0xd8, 0x18, 0x41, 0x02},
+		},
 
 		// from RFC 8610 Appendix G.1. and G.6.
 		{
@@ -204,6 +211,31 @@ func TestDecodeEDN(t *testing.T) {
 			in:  "b64'EjRWeA'",
 			out: RawMessage{0x44, 0x12, 0x34, 0x56, 0x78},
 		},
+		{
+			// base64 with padding
+			in:  "b64'+/8='",
+			out: RawMessage{0x42, 0xfb, 0xff},
+		},
+		{
+			// base64url with padding
+			in:  "b64'-_8='",
+			out: RawMessage{0x42, 0xfb, 0xff},
+		},
+		{
+			// base64 without padding
+			in:  "b64'+/8'",
+			out: RawMessage{0x42, 0xfb, 0xff},
+		},
+		{
+			// '/' is a character of base64, not the start of a comment
+			in:  "b64'/w'",
+			out: RawMessage{0x41, 0xff},
+		},
+		{
+			// whitespace is ignored
+			in:  "b64' EjRW\n eA '",
+			out: RawMessage{0x44, 0x12, 0x34, 0x56, 0x78},
+		},
 
 		// text strings
 		{
@@ -940,6 +972,67 @@ func TestEncodeEDN(t *testing.T) {
 			},
 			out: `18446744073709551616`,
 		},
+		{
+			in: RawMessage{
+				0xc3,
+				0x49,
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			out: `-18446744073709551617`,
+		},
+		{
+			in: RawMessage{
+				0x82,
+				0xc2,
+				0x5b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09,
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x01,
+			},
+			out: `[18446744073709551616, 1]`,
+		},
+		{
+			in:  RawMessage{0xc2, 0x40},
+			out: `0`,
+		},
+		{
+			in:  RawMessage{0xc3, 0x40},
+			out: `-1`,
+		},
+		{
+			in:  RawMessage{0xc2, 0x01},
+			out: `2(1)`,
+		},
+		{
+			in:  RawMessage{0xc2, 0x5f, 0x41, 0x01, 0xff},
+			out: `2((_ h'01'))`,
+		},
+		{
+			in: RawMessage{
+				0xc4,             // tag(4)
+				0x82,             // array(2)
+				0x21,             // -2
+				0x19, 0x6a, 0xb3, // 27315
+			},
+			out: `4([-2, 27315])`,
+		},
+		{
+			in: RawMessage{
+				0xc5, // tag(5)
+				0x82, // array(2)
+				0x20, // -1
+				0x03, // 3
+			},
+			out: `5([-1, 3])`,
+		},
+		{
+			in: RawMessage{
+				0xc4, // tag(4)
+				0x82, // array(2)
+				0x20, // -1
+				0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			out: `4([-1, 18446744073709551616])`,
+		},
 
 		// simple values
 		{
@@ -1204,6 +1297,55 @@ func TestEncodeEDN(t *testing.T) {
 				0x76, 0x68, 0x74, 0x74, 0x70, 0x3a, 0x2f, 0x2f, 0x77, 0x77, 0x77, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d},
 			out: `32("http://www.example.com")`,
 		},
+		{
+			in:  RawMessage{0xd8, 0x18, 0x45, 0x64, 0x49, 0x45, 0x54, 0x46},
+			out: `24(<<"IETF">>)`,
+		},
+		{
+			// nested encoded CBOR data items
+			in:  RawMessage{0xd8, 0x18, 0x46, 0x82, 0x01, 0xd8, 0x18, 0x41, 0x02},
+			out: `24(<<[1, 24(<<2>>)]>>)`,
+		},
+		{
+			in:  RawMessage{0xd5, 0x42, 0xfb, 0xff},
+			out: `21(b64'-_8')`,
+		},
+		{
+			in:  RawMessage{0xd6, 0x42, 0xfb, 0xff},
+			out: `22(b64'+/8=')`,
+		},
+		{
+			in:  RawMessage{0xd7, 0x42, 0xfb, 0xff},
+			out: `23(h'fbff')`,
+		},
+		{
+			// the expected conversion applies to the nested byte strings,
+			// except for those in the nested expected conversions.
+			in:  RawMessage{0xd5, 0x83, 0x41, 0x01, 0xa1, 0x61, 0x61, 0x41, 0x02, 0xd7, 0x41, 0x03},
+			out: `21([b64'AQ', {"a": b64'Ag'}, 23(h'03')])`,
+		},
+		{
+			in:  RawMessage{0x82, 0xd5, 0x41, 0x01, 0x41, 0x02},
+			out: `[21(b64'AQ'), h'02']`,
+		},
+		{
+			// not well-formed
+			in:  RawMessage{0xd8, 0x18, 0x42, 0x82, 0x01},
+			out: `24(h'8201')`,
+		},
+		{
+			// more than one data item
+			in:  RawMessage{0xd8, 0x18, 0x42, 0x01, 0x02},
+			out: `24(h'0102')`,
+		},
+		{
+			in:  RawMessage{0xd8, 0x18, 0x40},
+			out: `24(h'')`,
+		},
+		{
+			in:  RawMessage{0xd8, 0x18, 0x5f, 0x41, 0x01, 0xff},
+			out: `24((_ h'01'))`,
+		},
 		{
 			in:  RawMessage{0x40},
 			out: `h''`,
@@ -1442,3 +1584,153 @@ func TestEncodeEDN(t *testing.T) {
 		}
 	}
 }
+
+func TestEDN_NegativeZero(t *testing.T) {
+	data, err := Marshal(math.Copysign(0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edn, err := RawMessage(data).EncodeEDN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(edn) != "-0.0" {
+		t.Errorf("EncodeEDN() = %s, want -0.0", edn)
+	}
+
+	got, err := DecodeEDN(edn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f float64
+	if err := Unmarshal(got, &f); err != nil {
+		t.Fatal(err)
+	}
+	if f != 0 || !math.Signbit(f) {
+		t.Errorf("DecodeEDN(%q) = %x, want -0.0", edn, []byte(got))
+	}
+}
+
+func TestEncodeEDNIndent(t *testing.T) {
+	tests := []struct {
+		in  RawMessage
+		out string
+	}{
+		{
+			in:  RawMessage{0x01},
+			out: `1`,
+		},
+		{
+			in:  RawMessage{0x80},
+			out: `[]`,
+		},
+		{
+			in:  RawMessage{0x9f, 0xff},
+			out: `[_ ]`,
+		},
+		{
+			in:  RawMessage{0x83, 0x01, 0x82, 0x02, 0x03, 0x82, 0x04, 0x05},
+			out: "[\n>\t1,\n>\t[\n>\t\t2,\n>\t\t3\n>\t],\n>\t[\n>\t\t4,\n>\t\t5\n>\t]\n>]",
+		},
+		{
+			in:  RawMessage{0xa2, 0x61, 0x61, 0x01, 0x61, 0x62, 0xc1, 0x82, 0x02, 0x03},
+			out: "{\n>\t\"a\": 1,\n>\t\"b\": 1([\n>\t\t2,\n>\t\t3\n>\t])\n>}",
+		},
+		{
+			in:  RawMessage{0x9f, 0x01, 0xbf, 0x61, 0x61, 0x02, 0xff, 0xff},
+			out: "[_\n>\t1,\n>\t{_\n>\t\t\"a\": 2\n>\t}\n>]",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.in.EncodeEDNIndent(">", "\t")
+		if err != nil {
+			t.Errorf("EncodeEDNIndent() error = %v", err)
+			continue
+		}
+		if string(got) != tt.out {
+			t.Errorf("EncodeEDNIndent(%x) = %q, want %q", []byte(tt.in), got, tt.out)
+		}
+	}
+}
+
+// countWriter counts the calls of Write.
+type countWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n++
+	return w.Buffer.Write(p)
+}
+
+func TestEDNEncoder(t *testing.T) {
+	t.Run("sequence", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := NewEDNEncoder(&buf)
+		for _, data := range []RawMessage{{0x01}, {0x82, 0x61, 0x61, 0xf5}, {0xa1, 0x01, 0x02}} {
+			if err := enc.Encode(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := "1\n[\"a\", true]\n{1: 2}\n"
+		if got := buf.String(); got != want {
+			t.Errorf("Encode() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("indent", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := NewEDNEncoder(&buf)
+		enc.SetIndent(">", "\t")
+		if err := enc.Encode(RawMessage{0x82, 0x01, 0x02}); err != nil {
+			t.Fatal(err)
+		}
+		want := "[\n>\t1,\n>\t2\n>]\n"
+		if got := buf.String(); got != want {
+			t.Errorf("Encode() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("large array", func(t *testing.T) {
+		// an indefinite-length array of 10000 text strings.
+		data := RawMessage{0x9f}
+		for i := 0; i < 10000; i++ {
+			data = append(data, 0x63, 'a', 'b', 'c')
+		}
+		data = append(data, 0xff)
+
+		var w countWriter
+		enc := NewEDNEncoder(&w)
+		if err := enc.Encode(data); err != nil {
+			t.Fatal(err)
+		}
+		want, err := data.EncodeEDN()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(want)+"\n", w.String()); diff != "" {
+			t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+		}
+		// the output is written while encoding.
+		if w.n < 2 {
+			t.Errorf("Write is called %d times, want more", w.n)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := NewEDNEncoder(&buf)
+		if err := enc.Encode(RawMessage{0x82, 0x01}); err != ErrUnexpectedEnd {
+			t.Errorf("Encode() error = %v, want %v", err, ErrUnexpectedEnd)
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		enc := NewEDNEncoder(errWriter{})
+		if err := enc.Encode(RawMessage{0x01}); err != errWrite {
+			t.Errorf("Encode() error = %v, want %v", err, errWrite)
+		}
+	})
+}
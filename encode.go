@@ -2,12 +2,15 @@ package cbor
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
+	"net"
 	"net/url"
 	"reflect"
 	"slices"
@@ -42,19 +45,155 @@ func (e *UnsupportedValueError) Error() string {
 	return "cbor: unsupported value: " + e.Str
 }
 
-type majorType byte
+// A MarshalerError is returned by Marshal when the output of MarshalCBOR is not well-formed.
+// See Options.ValidateMarshalerOutput.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (e *MarshalerError) Error() string {
+	return "cbor: error calling MarshalCBOR for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error { return e.Err }
+
+// majorType is the internal name of MajorType.
+type majorType = MajorType
+
+const (
+	majorTypePositiveInt = MajorTypePositiveInt
+	majorTypeNegativeInt = MajorTypeNegativeInt
+	majorTypeBytes       = MajorTypeBytes
+	majorTypeString      = MajorTypeString
+	majorTypeArray       = MajorTypeArray
+	majorTypeMap         = MajorTypeMap
+	majorTypeTag         = MajorTypeTag
+	majorTypeOther       = MajorTypeOther
+)
+
+// FloatMode specifies how to encode floating-point numbers.
+type FloatMode int
+
+const (
+	// FloatModeShortest encodes floating-point numbers in the shortest form
+	// that preserves the value (half, single or double precision).
+	FloatModeShortest FloatMode = iota
+
+	// FloatModeFloat64Only encodes all floating-point numbers as double-precision floats.
+	// Float16 values are still encoded as half-precision floats.
+	FloatModeFloat64Only
+
+	// FloatModeIntIfExact encodes floating-point numbers that are integral and fit in int64 or uint64
+	// as integers, e.g. 10.0 as 10, and the others in the same way as FloatModeShortest.
+	// -0.0 is encoded as a floating-point number because integers can't keep its sign.
+	// It changes the major type of the data items, so use it only for the profiles that permit it.
+	// Decoding the integers into Go floating-point types requires Options.AllowIntToFloat.
+	FloatModeIntIfExact
+)
+
+// MapKeySort specifies how to sort the keys of maps.
+type MapKeySort int
+
+const (
+	// MapKeySortCanonical sorts the keys in the bytewise lexicographic order of their encodings.
+	// Keys that contain the output of Marshalers are canonicalized before sorting,
+	// because it may not be in the deterministic form.
+	// The other keys are encoded under the options, e.g. FloatMode, in the same way as the values.
+	// Note that it is not the numeric order:
+	// non-negative integers (major type 0) come before negative integers (major type 1),
+	// e.g. the keys of map[int]int{-2: 0, -1: 0, 0: 0, 1: 0, 24: 0} are sorted as 0, 1, 24, -1, -2.
+	// Use MapKeySortByValue to sort them numerically.
+	// See RFC 8949 Section 4.2.1.
+	MapKeySortCanonical MapKeySort = iota
+
+	// MapKeySortByValue sorts the keys by their values for human-facing output.
+	// Numbers are sorted numerically, and strings and byte strings are sorted lexically.
+	// Keys of different types are sorted in the order of numbers, strings, byte strings, booleans and others.
+	MapKeySortByValue
+)
+
+// StructMode specifies how to encode structs.
+type StructMode int
+
+const (
+	// StructModeMap encodes structs as maps.
+	// Structs tagged with "toarray" are still encoded as arrays.
+	StructModeMap StructMode = iota
+
+	// StructModeArray encodes all structs as arrays of their fields in the declaration order.
+	// The "omitempty" option is ignored, and inline maps must be empty.
+	StructModeArray
+)
+
+// ComplexMode specifies how to encode complex numbers.
+type ComplexMode int
+
+const (
+	// ComplexModeError returns an UnsupportedTypeError for complex numbers.
+	ComplexModeError ComplexMode = iota
+
+	// ComplexModeArray encodes complex numbers as two-element arrays [real, imag] of floating-point numbers.
+	// The decoder also accepts such arrays for complex numbers if it is ComplexModeArray.
+	ComplexModeArray
+)
+
+// DurationMode specifies how to encode time.Duration values.
+type DurationMode int
+
+const (
+	// DurationModeNanoseconds encodes time.Duration values as integers of nanoseconds.
+	DurationModeNanoseconds DurationMode = iota
+
+	// DurationModeSeconds encodes time.Duration values as floating-point numbers of seconds.
+	// The decoder also interprets numbers as seconds for time.Duration if it is DurationModeSeconds.
+	// Note that float64 can't represent nanoseconds exactly for durations longer than about 104 days.
+	DurationModeSeconds
+)
+
+// TimeMode specifies how to encode time.Time values.
+type TimeMode int
 
 const (
-	majorTypePositiveInt majorType = 0
-	majorTypeNegativeInt majorType = 1
-	majorTypeBytes       majorType = 2
-	majorTypeString      majorType = 3
-	majorTypeArray       majorType = 4
-	majorTypeMap         majorType = 5
-	majorTypeTag         majorType = 6
-	majorTypeOther       majorType = 7
+	// TimeModeEpoch encodes time.Time values as epoch-based date/time (tag number 1).
+	// The seconds are encoded as a floating-point number, so the present times lose
+	// the precision of a few hundred nanoseconds.
+	// Use TimeModeRFC3339 to keep nanosecond precision.
+	TimeModeEpoch TimeMode = iota
+
+	// TimeModeRFC3339 encodes time.Time values as RFC 3339 date/time strings (tag number 0).
+	// The time.Time values round-trip in nanosecond precision unless Options.TimePrecision is set.
+	// The location and the precision are controlled by Options.TimeLocation and Options.TimePrecision.
+	TimeModeRFC3339
 )
 
+// Marshal returns the CBOR encoding of v.
+//
+// The struct field tag "omitempty" omits the field if its value is empty:
+// false, 0, a nil pointer, a nil interface value, any empty array, slice, map, or string,
+// the zero Integer (Integer{}) and Simple(0).
+//
+// The struct field tag options "base64url", "base64" and "base16" wrap the field value
+// in the tag of the expected conversion (tag number 21, 22 and 23).
+// It is useful to convert the CBOR data to JSON later.
+// When decoding, the tag is removed if it is present.
+//
+// The struct field tag options "tag=0" and "tag=1" encode the time.Time values of the field
+// as date/time strings (tag number 0) and epoch-based date/time (tag number 1) respectively,
+// regardless of Options.TimeMode, e.g. `cbor:"ts,tag=1"`.
+// Both are decoded into time.Time.
+//
+// If a value implements CBORMarshaler, Marshal calls its MarshalCBOR method
+// and writes the result as the encoding of the value.
+// This applies to the values nested in struct fields, array and slice elements,
+// and map keys and values, as well as to v itself.
+// As encoding/json does, MarshalCBOR with a pointer receiver is called only for addressable values,
+// such as the elements of slices and the fields of the structs referenced by pointers,
+// and a nil pointer is encoded as null without calling the method.
+//
+// bytes.Buffer is encoded as a byte string of its unread contents.
+// The types of sync/atomic, such as atomic.Int64 and atomic.Value,
+// are encoded as the values returned by their Load methods.
 func Marshal(v any) ([]byte, error) {
 	e := newEncodeState()
 	err := e.encode(v)
@@ -64,12 +203,79 @@ func Marshal(v any) ([]byte, error) {
 	return e.buf.Bytes(), nil
 }
 
+// Marshal returns the CBOR encoding of v with the options.
+func (o Options) Marshal(v any) ([]byte, error) {
+	e := newEncodeState()
+	o.setEncodeState(e)
+	err := e.encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// MarshalTo writes the CBOR encoding of v to w with a single Write call.
+// It returns the number of bytes written.
+// Nothing is written if v can't be encoded.
+func MarshalTo(w io.Writer, v any) (int, error) {
+	return Options{}.MarshalTo(w, v)
+}
+
+// MarshalTo writes the CBOR encoding of v with the options to w.
+// See MarshalTo for details.
+func (o Options) MarshalTo(w io.Writer, v any) (int, error) {
+	e := newEncodeState()
+	o.setEncodeState(e)
+	if err := e.encode(v); err != nil {
+		return 0, err
+	}
+	n, err := e.buf.WriteTo(w)
+	return int(n), err
+}
+
+func (o Options) setEncodeState(e *encodeState) {
+	e.floatMode = o.FloatMode
+	e.enumAsString = o.EnumAsString
+	e.mapKeySort = o.MapKeySort
+	e.timeMode = o.TimeMode
+	e.timeLocation = o.TimeLocation
+	e.timePrecision = o.TimePrecision
+	e.structMode = o.StructMode
+	e.complexMode = o.ComplexMode
+	e.durationMode = o.DurationMode
+	e.homogeneousArrayTag = o.HomogeneousArrayTag
+	e.stringerAsText = o.StringerAsText
+	e.validateMarshalerOutput = o.ValidateMarshalerOutput
+}
+
+func (e *encodeState) options() Options {
+	return Options{
+		FloatMode:               e.floatMode,
+		EnumAsString:            e.enumAsString,
+		MapKeySort:              e.mapKeySort,
+		TimeMode:                e.timeMode,
+		TimeLocation:            e.timeLocation,
+		TimePrecision:           e.timePrecision,
+		StructMode:              e.structMode,
+		ComplexMode:             e.complexMode,
+		DurationMode:            e.durationMode,
+		HomogeneousArrayTag:     e.homogeneousArrayTag,
+		StringerAsText:          e.stringerAsText,
+		ValidateMarshalerOutput: e.validateMarshalerOutput,
+	}
+}
+
 func newEncodeState() *encodeState {
 	return &encodeState{
 		ptrSeen: make(map[any]struct{}),
 	}
 }
 
+// marshal returns the CBOR encoding of v with the same options as e.
+func (e *encodeState) marshal(v any) ([]byte, error) {
+	return e.options().Marshal(v)
+}
+
 type encodeState struct {
 	buf bytes.Buffer
 
@@ -80,6 +286,19 @@ type encodeState struct {
 	// reasonable amount of nested pointers deep.
 	ptrLevel uint
 	ptrSeen  map[any]struct{}
+
+	floatMode               FloatMode
+	enumAsString            bool
+	mapKeySort              MapKeySort
+	timeMode                TimeMode
+	timeLocation            *time.Location
+	timePrecision           time.Duration
+	structMode              StructMode
+	complexMode             ComplexMode
+	durationMode            DurationMode
+	homogeneousArrayTag     bool
+	stringerAsText          bool
+	validateMarshalerOutput bool
 }
 
 const startDetectingCyclesAfter = 1000
@@ -122,12 +341,7 @@ func (s *encodeState) encode(v any) error {
 	case string:
 		return s.encodeString(v)
 	case CBORMarshaler:
-		data, err := v.MarshalCBOR()
-		if err != nil {
-			return err
-		}
-		s.buf.Write(data)
-		return nil
+		return marshalerEncoder(s, reflect.ValueOf(v))
 	}
 
 	return s.encodeReflectValue(reflect.ValueOf(v))
@@ -140,7 +354,14 @@ func (s *encodeState) encodeReflectValue(v reflect.Value) error {
 	return typeEncoder(v.Type())(s, v)
 }
 
+// isEmptyValue reports whether v is empty for the "omitempty" option.
+// false, 0, a nil pointer, a nil interface value, and any empty array, slice, map, or string are empty.
+// Integer{} (zero) and Simple(0) are also empty, but Integer{Sign: true} (-1) is not.
 func isEmptyValue(v reflect.Value) bool {
+	if v.Type() == integerType {
+		return v.IsZero()
+	}
+
 	switch v.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return v.Len() == 0
@@ -196,8 +417,14 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return bigIntEncoder
 	case bigFloatType:
 		return bigFloatEncoder
+	case bigRatType:
+		return bigRatEncoder
+	case bytesBufferType:
+		return bytesBufferEncoder
 	case tagType:
 		return tagEncoder
+	case rawMessageType:
+		return rawMessageEncoder
 	case rawTagType:
 		return rawTagEncoder
 	case simpleType:
@@ -206,8 +433,12 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return undefinedEncoder
 	case integerType:
 		return integerEncoder
+	case float16Type:
+		return float16Encoder
 	case timeType:
 		return timeEncoder
+	case durationType:
+		return durationEncoder
 	case urlType:
 		return urlEncoder
 	case base64StringType:
@@ -216,6 +447,16 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return newBase64Encoder(tagNumberBase64URL, base64.RawURLEncoding.Strict())
 	case encodedDataType:
 		return encodedDataEncoder
+	case fullDateType:
+		return fullDateEncoder
+	case epochDaysType:
+		return epochDaysEncoder
+	case multiDimArrayType:
+		return multiDimArrayEncoder
+	case ipType:
+		return ipEncoder
+	case ipNetType:
+		return ipNetEncoder
 	case expectedBase16Type:
 		return newExpectedEncoder(tagNumberExpectedBase16, t)
 	case expectedBase64Type:
@@ -224,6 +465,34 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return newExpectedEncoder(tagNumberExpectedBase64URL, t)
 	}
 
+	if t.Implements(marshalerType) {
+		return marshalerEncoder
+	}
+
+	enc := newKindEncoder(t)
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface && implementsText(t) {
+		// pointers and interfaces are checked after dereferencing them,
+		// so that the types that have their own encoders are not converted.
+		enc = newTextEncoder(enc, isIntegerKind(t.Kind()))
+	}
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(marshalerType) {
+		return newAddrMarshalerEncoder(enc)
+	}
+	return enc
+}
+
+// isIntegerKind reports whether k is a kind of integer types, which EnumAsString applies to.
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// newKindEncoder returns the encoder of t by its kind.
+func newKindEncoder(t reflect.Type) encoderFunc {
 	switch t.Kind() {
 	case reflect.Bool:
 		return boolEncoder
@@ -233,18 +502,20 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 		return uintEncoder
 	case reflect.Float32, reflect.Float64:
 		return floatEncoder
+	case reflect.Complex64, reflect.Complex128:
+		return complexEncoder
 	case reflect.String:
 		return stringEncoder
 	case reflect.Slice:
-		if t.Elem().Kind() == reflect.Uint8 {
+		if isByteElem(t.Elem()) {
 			return bytesEncoder
 		}
-		return sliceEncoder
+		return newSliceEncoder(t)
 	case reflect.Array:
-		if t.Elem().Kind() == reflect.Uint8 {
+		if isByteElem(t.Elem()) {
 			return arrayBytesEncoder
 		}
-		return arrayEncoder
+		return newArrayEncoder(t)
 	case reflect.Map:
 		return mapEncoder
 	case reflect.Interface:
@@ -252,12 +523,103 @@ func newTypeEncoder(t reflect.Type) encoderFunc {
 	case reflect.Ptr:
 		return newPtrEncoder(t)
 	case reflect.Struct:
+		if t.PkgPath() == "sync/atomic" {
+			return newAtomicEncoder(t)
+		}
 		return newStructEncoder(t)
 	default:
 		return unsupportedTypeEncoder
 	}
 }
 
+// implementsText reports whether t or *t implements error or fmt.Stringer.
+func implementsText(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(errorType) || t.Implements(stringerType) ||
+		pt.Implements(errorType) || pt.Implements(stringerType)
+}
+
+// newTextEncoder returns the encoder that encodes the values as their Error() or String() text
+// if StringerAsText is set, or if EnumAsString is set and enum is true.
+// It falls back to enc otherwise.
+func newTextEncoder(enc encoderFunc, enum bool) encoderFunc {
+	return func(e *encodeState, v reflect.Value) error {
+		if e.stringerAsText || (enum && e.enumAsString) {
+			if s, ok := asText(v); ok {
+				return e.encodeString(s)
+			}
+		}
+		return enc(e, v)
+	}
+}
+
+// asText returns the Error() text of v if v implements error,
+// or the String() text of v if v implements fmt.Stringer.
+func asText(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	switch x := addressable(v).Addr().Interface().(type) {
+	case error:
+		return x.Error(), true
+	case fmt.Stringer:
+		return x.String(), true
+	}
+	return "", false
+}
+
+// isByteElem reports whether the slices and arrays of t are encoded as byte strings.
+// Named byte types are also encoded as byte strings unless they implement CBORMarshaler.
+func isByteElem(t reflect.Type) bool {
+	if t.Kind() != reflect.Uint8 {
+		return false
+	}
+	return !t.Implements(marshalerType) && !reflect.PointerTo(t).Implements(marshalerType)
+}
+
+func marshalerEncoder(e *encodeState, v reflect.Value) error {
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return e.encodeNull()
+	}
+	m := v.Interface().(CBORMarshaler)
+	return e.encodeMarshaler(m)
+}
+
+// newAddrMarshalerEncoder returns the encoder that calls MarshalCBOR with a pointer receiver
+// if the value is addressable, and falls back to enc otherwise.
+func newAddrMarshalerEncoder(enc encoderFunc) encoderFunc {
+	return func(e *encodeState, v reflect.Value) error {
+		if !v.CanAddr() {
+			return enc(e, v)
+		}
+		m := v.Addr().Interface().(CBORMarshaler)
+		return e.encodeMarshaler(m)
+	}
+}
+
+// encodeMarshaler writes the output of m.MarshalCBOR,
+// checking that it is well-formed if ValidateMarshalerOutput is enabled.
+func (e *encodeState) encodeMarshaler(m CBORMarshaler) error {
+	data, err := m.MarshalCBOR()
+	if err != nil {
+		return err
+	}
+	return e.writeMarshalerOutput(reflect.TypeOf(m), data)
+}
+
+// writeMarshalerOutput writes the pre-encoded data of the type t,
+// checking that it is well-formed if ValidateMarshalerOutput is enabled.
+func (e *encodeState) writeMarshalerOutput(t reflect.Type, data []byte) error {
+	if e.validateMarshalerOutput {
+		d := newDecodeState(data)
+		if err := d.checkWellFormed(); err != nil {
+			return &MarshalerError{Type: t, Err: err}
+		}
+	}
+	e.buf.Write(data)
+	return nil
+}
+
 func boolEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeBool(v.Bool())
 }
@@ -266,6 +628,13 @@ func intEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeInt(v.Int())
 }
 
+func durationEncoder(e *encodeState, v reflect.Value) error {
+	if e.durationMode == DurationModeSeconds {
+		return e.encodeFloat64(time.Duration(v.Int()).Seconds())
+	}
+	return intEncoder(e, v)
+}
+
 func uintEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeUint(v.Uint())
 }
@@ -274,6 +643,18 @@ func floatEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeFloat64(v.Float())
 }
 
+func complexEncoder(e *encodeState, v reflect.Value) error {
+	if e.complexMode != ComplexModeArray {
+		return &UnsupportedTypeError{v.Type()}
+	}
+	c := v.Complex()
+	e.writeUint(majorTypeArray, 2)
+	if err := e.encodeFloat64(real(c)); err != nil {
+		return err
+	}
+	return e.encodeFloat64(imag(c))
+}
+
 func stringEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeString(v.String())
 }
@@ -306,6 +687,25 @@ func integerEncoder(e *encodeState, v reflect.Value) error {
 	return nil
 }
 
+func (e *encodeState) encodeBigRat(r *big.Rat) error {
+	e.writeUint(majorTypeTag, uint64(tagNumberRational))
+	e.writeByte(0x82) // array of length 2
+
+	// encode numerator
+	if err := e.encodeBigInt(r.Num()); err != nil {
+		return err
+	}
+
+	// encode denominator
+	return e.encodeBigInt(r.Denom())
+}
+
+func float16Encoder(e *encodeState, v reflect.Value) error {
+	e.writeByte(0xf9) // half-precision float (two-byte IEEE 754)
+	e.writeUint16(uint16(v.Uint()))
+	return nil
+}
+
 var minInteger *big.Int
 
 func init() {
@@ -315,17 +715,56 @@ func init() {
 	}
 }
 
+// addressable returns v if v is addressable.
+// Otherwise, it returns the addressable copy of v,
+// e.g. for big.Int passed to Marshal by value.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	w := reflect.New(v.Type()).Elem()
+	w.Set(v)
+	return w
+}
+
 func bigIntEncoder(e *encodeState, v reflect.Value) error {
-	i := v.Addr().Interface().(*big.Int)
+	i := addressable(v).Addr().Interface().(*big.Int)
 	return e.encodeBigInt(i)
 }
 
 func bigFloatEncoder(e *encodeState, v reflect.Value) error {
 	// breaks into exponent and mantissa
-	f := v.Addr().Interface().(*big.Float)
+	f := addressable(v).Addr().Interface().(*big.Float)
 	return e.encodeBigFloat(f)
 }
 
+func bigRatEncoder(e *encodeState, v reflect.Value) error {
+	r := addressable(v).Addr().Interface().(*big.Rat)
+	return e.encodeBigRat(r)
+}
+
+func bytesBufferEncoder(e *encodeState, v reflect.Value) error {
+	b := addressable(v).Addr().Interface().(*bytes.Buffer).Bytes()
+	e.writeUint(majorTypeBytes, uint64(len(b)))
+	e.buf.Write(b)
+	return nil
+}
+
+// newAtomicEncoder returns the encoder for the types of sync/atomic,
+// which encodes the value returned by their Load method, e.g. int64 for atomic.Int64.
+// The types that don't have Load method are unsupported.
+func newAtomicEncoder(t reflect.Type) encoderFunc {
+	m, ok := reflect.PointerTo(t).MethodByName("Load")
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		return unsupportedTypeEncoder
+	}
+	elemEnc := typeEncoder(m.Type.Out(0))
+	return func(e *encodeState, v reflect.Value) error {
+		elem := addressable(v).Addr().Method(m.Index).Call(nil)[0]
+		return elemEnc(e, elem)
+	}
+}
+
 func tagEncoder(e *encodeState, v reflect.Value) error {
 	tag := v.Interface().(Tag)
 	e.writeUint(majorTypeTag, uint64(tag.Number))
@@ -335,6 +774,10 @@ func tagEncoder(e *encodeState, v reflect.Value) error {
 func rawTagEncoder(e *encodeState, v reflect.Value) error {
 	tag := v.Interface().(RawTag)
 	e.writeUint(majorTypeTag, uint64(tag.Number))
+	if tag.Content == nil {
+		e.writeByte(0xf6) // null
+		return nil
+	}
 	e.buf.Write(tag.Content)
 	return nil
 }
@@ -355,17 +798,42 @@ func simpleEncoder(e *encodeState, v reflect.Value) error {
 }
 
 func timeEncoder(e *encodeState, v reflect.Value) error {
-	t := v.Interface().(time.Time)
+	// strip the monotonic clock reading,
+	// which is meaningless outside the current process.
+	t := v.Interface().(time.Time).Round(0)
 	epoch := t.Unix()
 	nano := t.Nanosecond()
 	if epoch <= minEpoch || epoch >= maxEpoch {
 		return e.encodeNull()
 	}
 
+	if e.timeMode == TimeModeRFC3339 {
+		return e.encodeRFC3339(t)
+	}
+
 	e.writeByte(0xc1) // tag 1: epoch-based date/time
 	return e.encodeFloat64(float64(epoch) + float64(nano)/1e9)
 }
 
+// encodeRFC3339 encodes t as tag number 0.
+// The trailing zeros of the fractional seconds are omitted.
+func (e *encodeState) encodeRFC3339(t time.Time) error {
+	loc := e.timeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	if e.timePrecision > 0 {
+		t = t.Truncate(e.timePrecision)
+	}
+	s := t.Format(time.RFC3339Nano)
+
+	e.writeByte(0xc0) // tag 0: date/time string
+	e.writeUint(majorTypeString, uint64(len(s)))
+	e.buf.WriteString(s)
+	return nil
+}
+
 func urlEncoder(e *encodeState, v reflect.Value) error {
 	u := v.Addr().Interface().(*url.URL)
 	s := u.String()
@@ -413,6 +881,116 @@ func encodedDataEncoder(e *encodeState, v reflect.Value) error {
 	return nil
 }
 
+func fullDateEncoder(e *encodeState, v reflect.Value) error {
+	// validate that the value is a full-date string.
+	data := v.String()
+	if _, err := time.Parse(time.DateOnly, data); err != nil {
+		return wrapSemanticError("cbor: invalid full-date", err)
+	}
+
+	// write tag number 1004: full-date string
+	e.writeUint(majorTypeTag, uint64(tagNumberFullDate))
+
+	// write data
+	e.writeUint(majorTypeString, uint64(len(data)))
+	e.buf.WriteString(data)
+	return nil
+}
+
+func epochDaysEncoder(e *encodeState, v reflect.Value) error {
+	// validate that the value is in the range of years 0001 to 9999.
+	n := v.Int()
+	if n <= minEpoch/secondsPerDay || n >= maxEpoch/secondsPerDay {
+		return newSemanticError("cbor: invalid range of epoch days")
+	}
+
+	// write tag number 100: days since 1970-01-01
+	e.writeUint(majorTypeTag, uint64(tagNumberEpochDays))
+	return e.encodeInt(n)
+}
+
+func multiDimArrayEncoder(e *encodeState, v reflect.Value) error {
+	a := v.Interface().(MultiDimArray)
+	elems := reflect.ValueOf(a.Elements)
+	if elems.Kind() != reflect.Slice && elems.Kind() != reflect.Array {
+		return newSemanticError("cbor: elements of multi-dimensional array must be a slice or an array")
+	}
+	if err := checkDimensions(a.Dimensions, elems.Len()); err != nil {
+		return err
+	}
+
+	// write tag number 40: multi-dimensional array in row-major order,
+	// or tag number 1040: multi-dimensional array in column-major order
+	if a.ColumnMajor {
+		e.writeUint(majorTypeTag, uint64(tagNumberMultiDimArrayColumnMajor))
+	} else {
+		e.writeUint(majorTypeTag, uint64(tagNumberMultiDimArray))
+	}
+	e.writeByte(0x82) // array of length 2
+
+	// write dimensions
+	e.writeUint(majorTypeArray, uint64(len(a.Dimensions)))
+	for _, dim := range a.Dimensions {
+		e.writeUint(majorTypePositiveInt, uint64(dim))
+	}
+
+	// write elements
+	// byte slices are written as arrays of integers instead of byte strings.
+	if isByteElem(elems.Type().Elem()) {
+		e.writeUint(majorTypeArray, uint64(elems.Len()))
+		for i := 0; i < elems.Len(); i++ {
+			e.writeUint(majorTypePositiveInt, elems.Index(i).Uint())
+		}
+		return nil
+	}
+	return e.encodeReflectValue(elems)
+}
+
+func ipEncoder(e *encodeState, v reflect.Value) error {
+	ip := net.IP(v.Bytes())
+	if len(ip) == 0 {
+		return e.encodeNull()
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		// write tag number 52: IPv4 address
+		e.writeUint(majorTypeTag, uint64(tagNumberIPv4))
+		ip = ip4
+	} else if len(ip) == net.IPv6len {
+		// write tag number 54: IPv6 address
+		e.writeUint(majorTypeTag, uint64(tagNumberIPv6))
+	} else {
+		return &UnsupportedValueError{v, "cbor: invalid IP address"}
+	}
+	return e.encodeBytes(ip)
+}
+
+func ipNetEncoder(e *encodeState, v reflect.Value) error {
+	ipnet := v.Interface().(net.IPNet)
+	ones, bits := ipnet.Mask.Size()
+	ip := ipnet.IP.Mask(ipnet.Mask)
+	if ip == nil || bits == 0 {
+		return &UnsupportedValueError{v, "cbor: invalid IP prefix"}
+	}
+
+	switch bits {
+	case net.IPv4len * 8:
+		// write tag number 52: IPv4 prefix
+		e.writeUint(majorTypeTag, uint64(tagNumberIPv4))
+	case net.IPv6len * 8:
+		// write tag number 54: IPv6 prefix
+		e.writeUint(majorTypeTag, uint64(tagNumberIPv6))
+	}
+
+	// the trailing zero bytes of the address are omitted.
+	for len(ip) > 0 && ip[len(ip)-1] == 0 {
+		ip = ip[:len(ip)-1]
+	}
+	e.writeUint(majorTypeArray, 2)
+	e.writeUint(majorTypePositiveInt, uint64(ones))
+	return e.encodeBytes(ip)
+}
+
 func newExpectedEncoder(n TagNumber, t reflect.Type) encoderFunc {
 	if n >= 24 {
 		panic("invalid tag number")
@@ -431,12 +1009,27 @@ func newExpectedEncoder(n TagNumber, t reflect.Type) encoderFunc {
 	}
 }
 
+// rawMessageEncoder is same as marshalerEncoder for RawMessage,
+// but it doesn't convert the value into the interface.
+func rawMessageEncoder(e *encodeState, v reflect.Value) error {
+	if v.IsNil() {
+		return e.encodeNull()
+	}
+	return e.writeMarshalerOutput(rawMessageType, v.Bytes())
+}
+
 func undefinedEncoder(e *encodeState, v reflect.Value) error {
 	return e.encodeUndefined()
 }
 
-func sliceEncoder(e *encodeState, v reflect.Value) error {
-	if v.IsZero() {
+type sliceEncoder struct {
+	elemEnc     encoderFunc
+	homogeneous bool
+}
+
+func (se sliceEncoder) encode(e *encodeState, v reflect.Value) error {
+	// only nil slices are null; empty slices are empty arrays.
+	if v.IsNil() {
 		return e.encodeNull()
 	}
 
@@ -451,10 +1044,13 @@ func sliceEncoder(e *encodeState, v reflect.Value) error {
 		defer delete(e.ptrSeen, ptr)
 	}
 
+	if se.homogeneous && e.homogeneousArrayTag {
+		e.writeUint(majorTypeTag, uint64(tagNumberHomogeneousArray))
+	}
 	l := v.Len()
 	e.writeUint(majorTypeArray, uint64(l))
 	for i := 0; i < l; i++ {
-		err := e.encodeReflectValue(v.Index(i))
+		err := se.elemEnc(e, v.Index(i))
 		if err != nil {
 			return err
 		}
@@ -463,28 +1059,190 @@ func sliceEncoder(e *encodeState, v reflect.Value) error {
 	return nil
 }
 
-func arrayEncoder(e *encodeState, v reflect.Value) error {
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	enc := sliceEncoder{typeEncoder(t.Elem()), isHomogeneousElem(t.Elem())}
+	return enc.encode
+}
+
+type arrayEncoder struct {
+	elemEnc     encoderFunc
+	homogeneous bool
+}
+
+func (ae arrayEncoder) encode(e *encodeState, v reflect.Value) error {
+	// Go arrays are values and can't be nil, so they are always encoded as
+	// a definite-length array even if all elements are zero.
+	if ae.homogeneous && e.homogeneousArrayTag {
+		e.writeUint(majorTypeTag, uint64(tagNumberHomogeneousArray))
+	}
 	l := v.Len()
 	e.writeUint(majorTypeArray, uint64(l))
 	for i := 0; i < l; i++ {
-		err := e.encodeReflectValue(v.Index(i))
+		err := ae.elemEnc(e, v.Index(i))
 		if err != nil {
 			return err
 		}
 	}
-	e.ptrLevel--
 	return nil
 }
 
+func newArrayEncoder(t reflect.Type) encoderFunc {
+	enc := arrayEncoder{typeEncoder(t.Elem()), isHomogeneousElem(t.Elem())}
+	return enc.encode
+}
+
+// isHomogeneousElem reports whether the slices and arrays of t are homogeneous arrays.
+// The elements of interface types may have different types,
+// nil pointers are encoded as null, big numbers and tags may or may not be tagged with the same number,
+// and CBORMarshaler may return anything.
+func isHomogeneousElem(t reflect.Type) bool {
+	switch t {
+	case bigIntType, bigFloatType, tagType, rawTagType:
+		return false
+	}
+	if t.Kind() == reflect.Interface || t.Kind() == reflect.Pointer {
+		return false
+	}
+	return !t.Implements(marshalerType) && !reflect.PointerTo(t).Implements(marshalerType)
+}
+
 type mapKey struct {
 	key     reflect.Value
 	encoded []byte
 }
 
+// hasMarshaler reports whether the map key contains a value that implements CBORMarshaler.
+// The output of Marshalers may not be in the deterministic form, so the key needs to be canonicalized.
+// The other keys are already encoded under the options of the encoder, e.g. FloatMode.
+func hasMarshaler(v reflect.Value) bool {
+	for {
+		t := v.Type()
+		if t.Implements(marshalerType) || (v.CanAddr() && reflect.PointerTo(t).Implements(marshalerType)) {
+			return true
+		}
+		if v.Kind() != reflect.Interface && v.Kind() != reflect.Pointer {
+			break
+		}
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasMarshaler(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if hasMarshaler(v.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func cmpMapKey(a, b mapKey) int {
 	return bytes.Compare(a.encoded, b.encoded)
 }
 
+// cmpMapKeyByValue compares the keys by their values.
+// It falls back to cmpMapKey if the values are equal or not comparable.
+func cmpMapKeyByValue(a, b mapKey) int {
+	ka, va := mapKeyValue(a.key)
+	kb, vb := mapKeyValue(b.key)
+	if ka != kb {
+		return cmp.Compare(ka, kb)
+	}
+
+	var c int
+	switch ka {
+	case mapKeyKindNumber:
+		fa, fb := va.(*big.Float), vb.(*big.Float)
+		if fa != nil && fb != nil {
+			c = fa.Cmp(fb)
+		}
+	case mapKeyKindString:
+		c = strings.Compare(va.(string), vb.(string))
+	case mapKeyKindBytes:
+		c = bytes.Compare(va.([]byte), vb.([]byte))
+	case mapKeyKindBool:
+		c = cmp.Compare(va.(int), vb.(int))
+	}
+	if c != 0 {
+		return c
+	}
+	return cmpMapKey(a, b)
+}
+
+const (
+	mapKeyKindNumber = iota
+	mapKeyKindString
+	mapKeyKindBytes
+	mapKeyKindBool
+	mapKeyKindOther
+)
+
+// mapKeyValue returns the kind and the comparable value of the map key.
+func mapKeyValue(v reflect.Value) (int, any) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return mapKeyKindOther, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case integerType:
+		i := v.Interface().(Integer)
+		return mapKeyKindNumber, new(big.Float).SetInt(i.BigInt())
+	case bigIntType:
+		i := v.Addr().Interface().(*big.Int)
+		return mapKeyKindNumber, new(big.Float).SetInt(i)
+	case float16Type:
+		f := v.Interface().(Float16)
+		if f.IsNaN() {
+			return mapKeyKindNumber, (*big.Float)(nil)
+		}
+		return mapKeyKindNumber, big.NewFloat(f.Float64())
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mapKeyKindNumber, new(big.Float).SetInt64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mapKeyKindNumber, new(big.Float).SetUint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) {
+			return mapKeyKindNumber, (*big.Float)(nil)
+		}
+		return mapKeyKindNumber, big.NewFloat(f)
+	case reflect.String:
+		return mapKeyKindString, v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return mapKeyKindBytes, v.Bytes()
+		}
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return mapKeyKindBytes, b
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			return mapKeyKindBool, 1
+		}
+		return mapKeyKindBool, 0
+	}
+	return mapKeyKindOther, nil
+}
+
 func mapEncoder(e *encodeState, v reflect.Value) error {
 	if v.IsZero() {
 		return e.encodeNull()
@@ -503,14 +1261,34 @@ func mapEncoder(e *encodeState, v reflect.Value) error {
 
 	l := v.Len()
 	keys := make([]mapKey, 0, l)
+	canonical := e.mapKeySort == MapKeySortCanonical
 	for _, key := range v.MapKeys() {
-		encoded, err := Marshal(key.Interface())
+		encoded, err := e.marshal(key.Interface())
 		if err != nil {
 			return err
 		}
+		if canonical && hasMarshaler(key) {
+			// the key may be pre-encoded in a non-deterministic form.
+			encoded, err = Canonicalize(encoded)
+			if err != nil {
+				return err
+			}
+		}
 		keys = append(keys, mapKey{key, encoded})
 	}
-	slices.SortFunc(keys, cmpMapKey)
+	if e.mapKeySort == MapKeySortByValue {
+		slices.SortFunc(keys, cmpMapKeyByValue)
+	} else {
+		slices.SortFunc(keys, cmpMapKey)
+		if canonical {
+			// different keys may have the same deterministic encoding.
+			for i := 1; i < len(keys); i++ {
+				if bytes.Equal(keys[i-1].encoded, keys[i].encoded) {
+					return &UnsupportedValueError{v, "cbor: duplicate map key"}
+				}
+			}
+		}
+	}
 
 	// encode the length
 	e.writeUint(majorTypeMap, uint64(l))
@@ -574,6 +1352,10 @@ type structEncoder struct {
 }
 
 func (se structEncoder) encodeAsMap(e *encodeState, v reflect.Value) error {
+	if se.st.inline != nil {
+		return se.encodeAsMapWithInline(e, v)
+	}
+
 	// count number of fields to encode
 	var l int
 	for _, f := range se.st.fields {
@@ -591,7 +1373,72 @@ func (se structEncoder) encodeAsMap(e *encodeState, v reflect.Value) error {
 			continue
 		}
 		e.buf.Write(f.encodedKey)
-		if err := e.encodeReflectValue(fv); err != nil {
+		if err := e.encodeField(&f, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeAsMapWithInline encodes the struct as a map,
+// merging the entries of the inline map into the fields.
+func (se structEncoder) encodeAsMapWithInline(e *encodeState, v reflect.Value) error {
+	type entry struct {
+		encodedKey []byte
+		field      *field // nil if the entry comes from the inline map
+		value      reflect.Value
+	}
+
+	m := v.FieldByIndex(se.st.inline.index)
+	entries := make([]entry, 0, len(se.st.fields)+m.Len())
+	for i := range se.st.fields {
+		f := &se.st.fields[i]
+		entries = append(entries, entry{f.encodedKey, f, v.FieldByIndex(f.index)})
+	}
+	iter := m.MapRange()
+	for iter.Next() {
+		encoded, err := e.marshal(iter.Key().Interface())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{encoded, nil, iter.Value()})
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.encodedKey, b.encodedKey)
+	})
+
+	// check for key collisions, and count number of entries to encode
+	var l int
+	for i, ent := range entries {
+		if i > 0 && bytes.Equal(entries[i-1].encodedKey, ent.encodedKey) {
+			f := entries[i-1].field
+			if f == nil {
+				f = ent.field
+			}
+			if f != nil {
+				return &UnsupportedValueError{v, fmt.Sprintf("cbor: inline map key collides with field %s", f.name)}
+			}
+			return &UnsupportedValueError{v, "cbor: duplicate map key in inline map"}
+		}
+		if ent.field != nil && ent.field.omitempty && isEmptyValue(ent.value) {
+			continue
+		}
+		l++
+	}
+
+	e.writeUint(majorTypeMap, uint64(l))
+	for _, ent := range entries {
+		if ent.field != nil && ent.field.omitempty && isEmptyValue(ent.value) {
+			continue
+		}
+		e.buf.Write(ent.encodedKey)
+		if ent.field == nil {
+			if err := e.encodeReflectValue(ent.value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.encodeField(ent.field, ent.value); err != nil {
 			return err
 		}
 	}
@@ -599,23 +1446,48 @@ func (se structEncoder) encodeAsMap(e *encodeState, v reflect.Value) error {
 }
 
 func (se structEncoder) encodeAsArray(e *encodeState, v reflect.Value) error {
-	e.writeUint(majorTypeArray, uint64(len(se.st.fields)))
-	for _, f := range se.st.fields {
+	if inline := se.st.inline; inline != nil && v.FieldByIndex(inline.index).Len() > 0 {
+		return &UnsupportedValueError{v, "cbor: inline map can't be encoded as an array"}
+	}
+
+	e.writeUint(majorTypeArray, uint64(len(se.st.arrayFields)))
+	for _, f := range se.st.arrayFields {
 		fv := v.FieldByIndex(f.index)
-		if err := e.encodeReflectValue(fv); err != nil {
+		if err := e.encodeField(&f, fv); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// encodeField encodes the value of the struct field f.
+// The value is wrapped in the tag of the expected conversion if f has,
+// and the time.Time values in it are encoded in the time mode of f if f has.
+func (e *encodeState) encodeField(f *field, v reflect.Value) error {
+	if f.expected != 0 {
+		e.writeUint(majorTypeTag, uint64(f.expected))
+	}
+	if f.hasTimeMode {
+		orig := e.timeMode
+		e.timeMode = f.timeMode
+		err := e.encodeReflectValue(v)
+		e.timeMode = orig
+		return err
+	}
+	return e.encodeReflectValue(v)
+}
+
 func newStructEncoder(t reflect.Type) encoderFunc {
 	st := cachedStructType(t)
 	se := structEncoder{st}
 	if st.toArray {
 		return se.encodeAsArray
-	} else {
-		return se.encodeAsMap
+	}
+	return func(e *encodeState, v reflect.Value) error {
+		if e.structMode == StructModeArray {
+			return se.encodeAsArray(e, v)
+		}
+		return se.encodeAsMap(e, v)
 	}
 }
 
@@ -684,6 +1556,26 @@ func (s *encodeState) encodeFloat64(v float64) error {
 	exp := int((f64>>52)&0x7ff) - 1023
 	frac := f64 & 0xfffffffffffff
 
+	if s.floatMode == FloatModeIntIfExact && v == math.Trunc(v) && f64 != 1<<63 {
+		// -2^63 <= v < 2^64, excluding infinities and -0.0.
+		if -(1<<63) <= v && v < 0 {
+			return s.encodeInt(int64(v))
+		}
+		if 0 <= v && v < 1<<64 {
+			return s.encodeUint(uint64(v))
+		}
+	}
+
+	if s.floatMode == FloatModeFloat64Only {
+		if math.IsNaN(v) {
+			// we don't support NaN payloads or signaling NaNs.
+			f64 = 0x7ff8000000000000
+		}
+		s.writeByte(0xfb) // double-precision float (eight-byte IEEE 754)
+		s.writeUint64(f64)
+		return nil
+	}
+
 	if exp == -1023 && frac == 0 {
 		// 0.0 in float16
 		s.writeByte(0xf9) // half-precision float (two-byte IEEE 754)
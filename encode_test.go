@@ -2,11 +2,19 @@ package cbor
 
 import (
 	"bytes"
+	"errors"
 	"math"
 	"math/big"
+	"net"
+	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestMarshal_Cycles(t *testing.T) {
@@ -38,6 +46,9 @@ func TestMarshal_UnsupportedType(t *testing.T) {
 	tests := []any{
 		func() {},
 		chan int(nil),
+		make(chan int),
+		&[2]chan int{},
+		[]func(){nil},
 		complex(1, 2),
 	}
 
@@ -402,6 +413,51 @@ func TestMarshal(t *testing.T) {
 				0x17, 0x18, 0x18, 0x18, 0x19,
 			},
 		},
+		{
+			"array: Go array of zero values",
+			[3]int{},
+			[]byte{0x83, 0x00, 0x00, 0x00},
+		},
+		{
+			"array: Go array [1, 2, 3]",
+			[...]int{1, 2, 3},
+			[]byte{0x83, 0x01, 0x02, 0x03},
+		},
+		{
+			"array: Go empty array",
+			[0]int{},
+			[]byte{0x80},
+		},
+		{
+			"array: pointer to Go array [1, 2, 3]",
+			&[...]int{1, 2, 3},
+			[]byte{0x83, 0x01, 0x02, 0x03},
+		},
+		{
+			"array: pointer to Go array of zero values",
+			&[3]int{},
+			[]byte{0x83, 0x00, 0x00, 0x00},
+		},
+		{
+			"array: pointer to Go empty array",
+			&[0]int{},
+			[]byte{0x80},
+		},
+		{
+			"array: nil pointer to Go array",
+			(*[3]int)(nil),
+			[]byte{0xf6},
+		},
+		{
+			"array: slice of pointers to Go arrays",
+			[]*[2]int{{1, 2}, nil},
+			[]byte{0x82, 0x82, 0x01, 0x02, 0xf6},
+		},
+		{
+			"array: Go array of nil pointers",
+			&[2]*int{},
+			[]byte{0x82, 0xf6, 0xf6},
+		},
 		{
 			"empty map",
 			map[string]any{},
@@ -649,6 +705,182 @@ func TestMarshal(t *testing.T) {
 				0x1b, 0xc8, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, 0x33, // 0xc833333333333333
 			},
 		},
+		{
+			"Bigfloat value",
+			*newBigFloat("1.5"),
+			[]byte{0xf9, 0x3e, 0x00},
+		},
+		{
+			"bigint value",
+			*newBigInt("-100"),
+			[]byte{0x38, 0x63},
+		},
+
+		// rational number
+		{
+			"multi-dimensional array",
+			MultiDimArray{Dimensions: []int{2, 3}, Elements: []int{2, 4, 8, 4, 16, 256}},
+			[]byte{
+				0xd8, 0x28, // Tag 40
+				0x82,             // Array 2
+				0x82, 0x02, 0x03, // [2, 3]
+				0x86, 0x02, 0x04, 0x08, 0x04, 0x10, 0x19, 0x01, 0x00, // [2, 4, 8, 4, 16, 256]
+			},
+		},
+		{
+			"multi-dimensional array in column-major order",
+			MultiDimArray{Dimensions: []int{2, 3}, Elements: []int{2, 4, 8, 4, 16, 256}, ColumnMajor: true},
+			[]byte{
+				0xd9, 0x04, 0x10, // Tag 1040
+				0x82,             // Array 2
+				0x82, 0x02, 0x03, // [2, 3]
+				0x86, 0x02, 0x04, 0x08, 0x04, 0x10, 0x19, 0x01, 0x00, // [2, 4, 8, 4, 16, 256]
+			},
+		},
+		{
+			"multi-dimensional array of bytes",
+			MultiDimArray{Dimensions: []int{1, 2}, Elements: []byte{1, 2}},
+			[]byte{0xd8, 0x28, 0x82, 0x82, 0x01, 0x02, 0x82, 0x01, 0x02},
+		},
+		{
+			"bytes.Buffer",
+			bytes.NewBufferString("foo"),
+			[]byte{0x43, 0x66, 0x6f, 0x6f}, // h'666f6f'
+		},
+		{
+			"bytes.Buffer value",
+			*bytes.NewBufferString("foo"),
+			[]byte{0x43, 0x66, 0x6f, 0x6f}, // h'666f6f'
+		},
+		{
+			"partially read bytes.Buffer",
+			func() *bytes.Buffer {
+				b := bytes.NewBufferString("foo")
+				b.ReadByte()
+				return b
+			}(),
+			[]byte{0x42, 0x6f, 0x6f}, // h'6f6f'
+		},
+		{
+			"nil bytes.Buffer",
+			(*bytes.Buffer)(nil),
+			[]byte{0xf6},
+		},
+		{
+			"atomic.Int64",
+			func() *atomic.Int64 {
+				var v atomic.Int64
+				v.Store(-42)
+				return &v
+			}(),
+			[]byte{0x38, 0x29},
+		},
+		{
+			"atomic.Bool",
+			func() *atomic.Bool {
+				var v atomic.Bool
+				v.Store(true)
+				return &v
+			}(),
+			[]byte{0xf5},
+		},
+		{
+			"atomic.Value",
+			func() *atomic.Value {
+				var v atomic.Value
+				v.Store("a")
+				return &v
+			}(),
+			[]byte{0x61, 0x61},
+		},
+		{
+			"empty atomic.Value",
+			new(atomic.Value),
+			[]byte{0xf6},
+		},
+		{
+			"atomic.Pointer",
+			func() *atomic.Pointer[int] {
+				var v atomic.Pointer[int]
+				v.Store(ptr(1))
+				return &v
+			}(),
+			[]byte{0x01},
+		},
+		{
+			"atomic field",
+			&struct{ N atomic.Uint32 }{},
+			[]byte{0xa1, 0x61, 0x4e, 0x00}, // {"N": 0}
+		},
+		{
+			"rational 1/3",
+			big.NewRat(1, 3),
+			[]byte{
+				0xd8, 0x1e, // Tag 30
+				0x82, // Array 2
+				0x01, // 1
+				0x03, // 3
+			},
+		},
+		{
+			"rational value -1/3",
+			*big.NewRat(-1, 3),
+			[]byte{0xd8, 0x1e, 0x82, 0x20, 0x03},
+		},
+		{
+			"rational zero",
+			&big.Rat{},
+			[]byte{0xd8, 0x1e, 0x82, 0x00, 0x01},
+		},
+		{
+			"rational zero value",
+			big.Rat{},
+			[]byte{0xd8, 0x1e, 0x82, 0x00, 0x01},
+		},
+		{
+			"rational with bignum",
+			new(big.Rat).SetFrac(newBigInt("18446744073709551616"), big.NewInt(3)),
+			[]byte{
+				0xd8, 0x1e, // Tag 30
+				0x82,                                                             // Array 2
+				0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 18446744073709551616
+				0x03, // 3
+			},
+		},
+		{
+			"full-date",
+			FullDate("2013-03-21"),
+			[]byte{0xd9, 0x03, 0xec, 0x6a, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31},
+		},
+		{
+			"epoch days",
+			EpochDays(-10676),
+			[]byte{0xd8, 0x64, 0x39, 0x29, 0xb3},
+		},
+		{
+			"IPv4 address",
+			net.IPv4(192, 0, 2, 1),
+			[]byte{0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x01},
+		},
+		{
+			"IPv6 address",
+			net.ParseIP("2001:db8:1234:deed:beef:cafe:face:feed"),
+			[]byte{
+				0xd8, 0x36, 0x50,
+				0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34, 0xde, 0xed,
+				0xbe, 0xef, 0xca, 0xfe, 0xfa, 0xce, 0xfe, 0xed,
+			},
+		},
+		{
+			"IPv4 prefix",
+			net.IPNet{IP: net.IPv4(192, 0, 2, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			[]byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x43, 0xc0, 0x00, 0x02},
+		},
+		{
+			"IPv6 prefix",
+			net.IPNet{IP: net.ParseIP("2001:db8:1234::"), Mask: net.CIDRMask(48, 128)},
+			[]byte{0xd8, 0x36, 0x82, 0x18, 0x30, 0x46, 0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34},
+		},
 
 		// marshaler
 		{
@@ -744,6 +976,16 @@ func TestMarshal(t *testing.T) {
 			},
 			[]byte{0xd8, 0x20, 0x76, 0x68, 0x74, 0x74, 0x70, 0x3a, 0x2f, 0x2f, 0x77, 0x77, 0x77, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d},
 		},
+		{
+			"url.Values",
+			url.Values{"a": {"1", "2"}},
+			[]byte{0xa1, 0x61, 0x61, 0x82, 0x61, 0x31, 0x61, 0x32},
+		},
+		{
+			"http.Header",
+			http.Header{"A": {"1"}, "B": {"2", "3"}},
+			[]byte{0xa2, 0x61, 0x41, 0x81, 0x61, 0x31, 0x61, 0x42, 0x82, 0x61, 0x32, 0x61, 0x33},
+		},
 		{
 			"base64 string",
 			Base64String("8J+No/Cfjbo="),
@@ -755,6 +997,13 @@ func TestMarshal(t *testing.T) {
 			[]byte{0xd8, 0x21, 0x6b, 0x38, 0x4a, 0x2d, 0x4e, 0x6f, 0x5f, 0x43, 0x66, 0x6a, 0x62, 0x6f},
 		},
 
+		// marshaler
+		{
+			"nested RawMessage",
+			[]RawMessage{{0x01}, nil},
+			[]byte{0x82, 0x01, 0xf6},
+		},
+
 		// struct
 		{
 			"struct a",
@@ -776,6 +1025,56 @@ func TestMarshal(t *testing.T) {
 			&FooC{A: 1, B: "2"},
 			[]byte{0x82, 0x01, 0x61, 0x32},
 		},
+		{
+			"struct f, skipped field and dash key",
+			&FooF{A: 1, B: 2},
+			[]byte{0xa1, 0x61, 0x2d, 0x02},
+		},
+		{
+			"struct h, empty Integer and Simple",
+			&FooH{},
+			[]byte{0xa0},
+		},
+		{
+			"struct h, non-empty Integer and Simple",
+			&FooH{A: Integer{Sign: true}, B: 20},
+			[]byte{0xa2, 0x61, 0x41, 0x20, 0x61, 0x42, 0xf4},
+		},
+		{
+			"struct i, expected conversions",
+			&FooI{A: []byte{0x01}, B: []byte{0x02}, C: []byte{0x03}},
+			[]byte{0xa3, 0x61, 0x41, 0xd6, 0x41, 0x01, 0x61, 0x42, 0xd5, 0x41, 0x02, 0x61, 0x43, 0xd7, 0x41, 0x03},
+		},
+		{
+			"struct i, omitempty with expected conversion",
+			&FooI{A: []byte{0x01}, B: []byte{0x02}},
+			[]byte{0xa2, 0x61, 0x41, 0xd6, 0x41, 0x01, 0x61, 0x42, 0xd5, 0x41, 0x02},
+		},
+		{
+			"struct j, uint64 keys",
+			&FooJ{A: 1, B: 2, C: 3},
+			[]byte{0xa3, 0x01, 0x01, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02, 0x20, 0x03},
+		},
+		{
+			"struct g, undefined",
+			&FooG{A: 23, B: Undefined},
+			[]byte{0xa2, 0x61, 0x41, 0xf7, 0x61, 0x42, 0xf7},
+		},
+		{
+			"struct d, inline map",
+			&FooD{A: 1, Extra: map[string]RawMessage{"B": {0x61, 0x32}, "AA": {0x02}}},
+			[]byte{0xa3, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x32, 0x62, 0x41, 0x41, 0x02},
+		},
+		{
+			"struct d, nil inline map",
+			&FooD{A: 1},
+			[]byte{0xa1, 0x61, 0x41, 0x01},
+		},
+		{
+			"struct e, inline map",
+			&FooE{A: 1, Extra: map[any]any{"B": "2", 1: 2}},
+			[]byte{0xa3, 0x01, 0x02, 0x61, 0x41, 0x01, 0x61, 0x42, 0x61, 0x32},
+		},
 
 		// invalid runes
 		{
@@ -852,45 +1151,1462 @@ func TestMarshal(t *testing.T) {
 	}
 }
 
-func TestMarshal_NaN(t *testing.T) {
-	nan := math.NaN()
+func TestMarshal_InlineCollision(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"field", &FooD{A: 1, Extra: map[string]RawMessage{"A": {0x02}}}},
+		{"empty field", &FooD{Extra: map[string]RawMessage{"A": {0x02}}}},
+		{"inline map", &FooE{Extra: map[any]any{1: 2, int64(1): 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(tt.v)
+			if _, ok := err.(*UnsupportedValueError); !ok {
+				t.Errorf("Marshal() error = %v, want *UnsupportedValueError", err)
+			}
+		})
+	}
+}
 
-	t.Run("NaN", func(t *testing.T) {
-		// NaN deceives the duplicate check of the Map.
-		// So, we don't accept NaN as a key of the Map.
-		input := map[float64]int{
-			nan: 1,
-			nan: 2,
+func TestMarshal_InvalidIP(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"invalid length", net.IP{0x01, 0x02, 0x03}},
+		{"invalid mask", net.IPNet{IP: net.IPv4(192, 0, 2, 0).To4(), Mask: net.IPMask{0xff, 0x00, 0xff, 0x00}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(tt.v)
+			if _, ok := err.(*UnsupportedValueError); !ok {
+				t.Errorf("Marshal() error = %v, want *UnsupportedValueError", err)
+			}
+		})
+	}
+}
+
+func TestMarshal_InvalidFullDate(t *testing.T) {
+	_, err := Marshal(FullDate("2013-3-21"))
+	if _, ok := err.(*SemanticError); !ok {
+		t.Errorf("Marshal() error = %v, want *SemanticError", err)
+	}
+}
+
+func TestMarshal_InvalidEpochDays(t *testing.T) {
+	_, err := Marshal(EpochDays(3000000))
+	if _, ok := err.(*SemanticError); !ok {
+		t.Errorf("Marshal() error = %v, want *SemanticError", err)
+	}
+}
+
+func TestMarshal_InvalidMultiDimArray(t *testing.T) {
+	tests := []struct {
+		name string
+		v    MultiDimArray
+	}{
+		{"length mismatch", MultiDimArray{Dimensions: []int{2, 3}, Elements: []int{1, 2, 3}}},
+		{"no dimensions", MultiDimArray{Elements: []int{1}}},
+		{"negative dimension", MultiDimArray{Dimensions: []int{-1, -1}, Elements: []int{1}}},
+		{"not a slice", MultiDimArray{Dimensions: []int{1}, Elements: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(tt.v)
+			if _, ok := err.(*SemanticError); !ok {
+				t.Errorf("Marshal() error = %v, want *SemanticError", err)
+			}
+		})
+	}
+}
+
+func TestMarshal_MapKeySort(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         any
+		canonical []byte
+		byValue   []byte
+	}{
+		{
+			name:      "integers",
+			v:         map[int]int{-1: 0, 10: 0, 100: 0},
+			canonical: []byte{0xa3, 0x0a, 0x00, 0x18, 0x64, 0x00, 0x20, 0x00},
+			byValue:   []byte{0xa3, 0x20, 0x00, 0x0a, 0x00, 0x18, 0x64, 0x00},
+		},
+		{
+			name:      "integers of mixed sign",
+			v:         map[int]int{-1: 1, 0: 2, 1: 3, -2: 4},
+			canonical: []byte{0xa4, 0x00, 0x02, 0x01, 0x03, 0x20, 0x01, 0x21, 0x04},
+			byValue:   []byte{0xa4, 0x21, 0x04, 0x20, 0x01, 0x00, 0x02, 0x01, 0x03},
+		},
+		{
+			name:      "strings",
+			v:         map[string]int{"b": 0, "aa": 0},
+			canonical: []byte{0xa2, 0x61, 0x62, 0x00, 0x62, 0x61, 0x61, 0x00},
+			byValue:   []byte{0xa2, 0x62, 0x61, 0x61, 0x00, 0x61, 0x62, 0x00},
+		},
+		{
+			name:      "numbers",
+			v:         map[any]int{1.5: 0, 1: 0, 2: 0, -0.5: 0},
+			canonical: []byte{0xa4, 0x01, 0x00, 0x02, 0x00, 0xf9, 0x3e, 0x00, 0x00, 0xf9, 0xb8, 0x00, 0x00},
+			byValue:   []byte{0xa4, 0xf9, 0xb8, 0x00, 0x00, 0x01, 0x00, 0xf9, 0x3e, 0x00, 0x00, 0x02, 0x00},
+		},
+		{
+			name:      "mixed types",
+			v:         map[any]int{true: 0, "a": 0, 1: 0},
+			canonical: []byte{0xa3, 0x01, 0x00, 0x61, 0x61, 0x00, 0xf5, 0x00},
+			byValue:   []byte{0xa3, 0x01, 0x00, 0x61, 0x61, 0x00, 0xf5, 0x00},
+		},
+		{
+			name:      "integer and float",
+			v:         map[any]int{1.0: 0, 1: 0},
+			canonical: []byte{0xa2, 0x01, 0x00, 0xf9, 0x3c, 0x00, 0x00},
+			byValue:   []byte{0xa2, 0x01, 0x00, 0xf9, 0x3c, 0x00, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.canonical) {
+				t.Errorf("Marshal() = %x, want %x", got, tt.canonical)
+			}
+
+			got, err = Options{MapKeySort: MapKeySortByValue}.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.byValue) {
+				t.Errorf("Options.Marshal() = %x, want %x", got, tt.byValue)
+			}
+		})
+	}
+}
+
+func TestMarshal_AnyKeyRoundTrip(t *testing.T) {
+	// the keys of all kinds in the canonical order of RFC 8949 Section 4.2.1.
+	input := []byte{
+		0xaf,       // 15 items map
+		0x0a, 0x01, // 10
+		0x18, 0x64, 0x02, // 100
+		0x20, 0x03, // -1
+		0x41, 0x01, 0x04, // h'01'
+		0x61, 0x7a, 0x05, // "z"
+		0x62, 0x61, 0x61, 0x06, // "aa"
+		0x81, 0x18, 0x64, 0x07, // [100]
+		0x81, 0x20, 0x08, // [-1]
+		0xc1, 0x01, 0x09, // 1(1)
+		0xe0, 0x0a, // simple(0)
+		0xf4, 0x0b, // false
+		0xf5, 0x0c, // true
+		0xf6, 0x0d, // null
+		0xf7, 0x0e, // undefined
+		0xf9, 0x3e, 0x00, 0x0f, // 1.5
+	}
+
+	t.Run("UseAnyKey", func(t *testing.T) {
+		var v any
+		if err := (Options{UseAnyKey: true}).Unmarshal(input, &v); err != nil {
+			t.Fatal(err)
 		}
-		_, err := Marshal(input)
-		_, ok := err.(*UnsupportedValueError)
-		if !ok {
-			t.Errorf("Marshal() error = %v, want UnsupportedValueError", err)
+		want := map[any]any{
+			int64(10):                         int64(1),
+			int64(100):                        int64(2),
+			int64(-1):                         int64(3),
+			[1]byte{0x01}:                     int64(4),
+			"z":                               int64(5),
+			"aa":                              int64(6),
+			[1]any{int64(100)}:                int64(7),
+			[1]any{int64(-1)}:                 int64(8),
+			Tag{Number: 1, Content: int64(1)}: int64(9),
+			Simple(0):                         int64(10),
+			false:                             int64(11),
+			true:                              int64(12),
+			nil:                               int64(13),
+			Undefined:                         int64(14),
+			float64(1.5):                      int64(15),
+		}
+		if diff := cmp.Diff(want, v); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+
+		got, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, input) {
+			t.Errorf("Marshal() = %x, want %x", got, input)
 		}
 	})
 
-	t.Run("NaN in array", func(t *testing.T) {
-		input := map[[2]float64]int{
-			{nan, 1}: 1,
+	t.Run("UseAnyKey and UseInteger", func(t *testing.T) {
+		var v any
+		if err := (Options{UseAnyKey: true, UseInteger: true}).Unmarshal(input, &v); err != nil {
+			t.Fatal(err)
 		}
-		_, err := Marshal(input)
-		_, ok := err.(*UnsupportedValueError)
-		if !ok {
-			t.Errorf("Marshal() error = %v, want UnsupportedValueError", err)
+		got, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, input) {
+			t.Errorf("Marshal() = %x, want %x", got, input)
 		}
 	})
 }
 
-func BenchmarkMarshal_Uint64(b *testing.B) {
-	r := newXorshift64()
-	for i := 0; i < b.N; i++ {
-		Marshal(r.Uint64())
-	}
+func TestMarshalTo(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := MarshalTo(&buf, []any{1, "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0x82, 0x01, 0x61, 0x61}
+		if n != len(want) {
+			t.Errorf("MarshalTo() = %d, want %d", n, len(want))
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("MarshalTo() wrote %x, want %x", buf.Bytes(), want)
+		}
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		var buf bytes.Buffer
+		if _, err := (Options{FloatMode: FloatModeFloat64Only}).MarshalTo(&buf, 1.0); err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("MarshalTo() wrote %x, want %x", buf.Bytes(), want)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := MarshalTo(&buf, []any{1, func() {}})
+		if _, ok := err.(*UnsupportedTypeError); !ok {
+			t.Errorf("MarshalTo() error = %v, want *UnsupportedTypeError", err)
+		}
+		if n != 0 || buf.Len() != 0 {
+			t.Errorf("MarshalTo() should write nothing, but wrote %x", buf.Bytes())
+		}
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		_, err := MarshalTo(errWriter{}, 1)
+		if err != errWrite {
+			t.Errorf("MarshalTo() error = %v, want %v", err, errWrite)
+		}
+	})
 }
 
-func BenchmarkMarshal_Int64(b *testing.B) {
-	r := newXorshift64()
-	for i := 0; i < b.N; i++ {
-		Marshal(int64(r.Uint64()))
+var errWrite = errors.New("write error")
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+// preEncodedKey is a map key that is encoded as is.
+type preEncodedKey string
+
+func (k preEncodedKey) MarshalCBOR() ([]byte, error) {
+	return []byte(k), nil
+}
+
+func TestMarshal_PreEncodedMapKey(t *testing.T) {
+	t.Run("canonicalized", func(t *testing.T) {
+		input := map[preEncodedKey]int{
+			"\x18\x02":         0, // 2 in the non-shortest form
+			"\x7f\x61\x61\xff": 1, // (_ "a")
+			"\x00":             2,
+		}
+		got, err := Marshal(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa3, 0x00, 0x02, 0x02, 0x00, 0x61, 0x61, 0x01}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("in any", func(t *testing.T) {
+		input := map[any]int{
+			preEncodedKey("\x18\x02"): 0,
+			1:                         1,
+		}
+		got, err := Marshal(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa2, 0x01, 0x01, 0x02, 0x00}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		input := map[preEncodedKey]int{
+			"\x18\x01": 0,
+			"\x01":     1,
+		}
+		_, err := Marshal(input)
+		if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("Marshal() error = %v, want *UnsupportedValueError", err)
+		}
+	})
+
+	t.Run("not well-formed", func(t *testing.T) {
+		input := map[preEncodedKey]int{
+			"\x18": 0,
+		}
+		if _, err := Marshal(input); err == nil {
+			t.Error("Marshal() error = nil, want error")
+		}
+	})
+
+	t.Run("nested in composite keys", func(t *testing.T) {
+		input := map[any]int{
+			[1]preEncodedKey{"\x18\x02"}:               0,
+			struct{ K any }{preEncodedKey("\x18\x03")}: 1,
+		}
+		got, err := Marshal(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa2, 0x81, 0x02, 0x00, 0xa1, 0x61, 0x4b, 0x03, 0x01}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("composite keys keep FloatMode", func(t *testing.T) {
+		opts := Options{FloatMode: FloatModeFloat64Only}
+		got, err := opts.Marshal(map[[1]float64]int{{1.5}: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa1, 0x81, 0xfb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+
+		got, err = opts.Marshal(map[any]int{struct{ F float64 }{1.5}: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = []byte{0xa1, 0xa1, 0x61, 0x46, 0xfb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+}
+
+type namedBytes []byte
+
+type namedByte uint8
+
+// markedByte is a byte type that has its own encoding.
+type markedByte uint8
+
+func (b markedByte) MarshalCBOR() ([]byte, error) {
+	return []byte{0xd8, 0x64, byte(b)}, nil
+}
+
+type namedMap map[string][]string
+
+type namedString string
+
+func TestMarshal_NamedTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{"named []byte", namedBytes{0x01, 0x02}, []byte{0x42, 0x01, 0x02}},
+		{"slice of named byte", []namedByte{0x01, 0x02}, []byte{0x42, 0x01, 0x02}},
+		{"array of named byte", [2]namedByte{0x01, 0x02}, []byte{0x42, 0x01, 0x02}},
+		{"nil named []byte", namedBytes(nil), []byte{0x40}},
+		{"named map", namedMap{"a": {"b"}}, []byte{0xa1, 0x61, 0x61, 0x81, 0x61, 0x62}},
+		{"named string", namedString("a"), []byte{0x61, 0x61}},
+		{
+			"slice of byte marshaler",
+			[]markedByte{0x01, 0x02},
+			[]byte{0x82, 0xd8, 0x64, 0x01, 0xd8, 0x64, 0x02},
+		},
+		{
+			"array of byte marshaler",
+			[1]markedByte{0x01},
+			[]byte{0x81, 0xd8, 0x64, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshal_ArrayPtrLevel(t *testing.T) {
+	// encoding arrays must not change the pointer level.
+	e := newEncodeState()
+	if err := e.encode([][2][]int{{{1}, {2}}, {{3}, {4}}}); err != nil {
+		t.Fatal(err)
+	}
+	if e.ptrLevel != 0 {
+		t.Errorf("unexpected ptrLevel: got %d, want 0", e.ptrLevel)
+	}
+}
+
+func TestMarshal_FloatMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode FloatMode
+		v    any
+		want []byte
+	}{
+		{
+			"shortest 1.0",
+			FloatModeShortest,
+			1.0,
+			[]byte{0xf9, 0x3c, 0x00},
+		},
+		{
+			"float64 only 1.0",
+			FloatModeFloat64Only,
+			1.0,
+			[]byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"float64 only float32 100000.0",
+			FloatModeFloat64Only,
+			float32(100000.0),
+			[]byte{0xfb, 0x40, 0xf8, 0x6a, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"float64 only NaN",
+			FloatModeFloat64Only,
+			math.NaN(),
+			[]byte{0xfb, 0x7f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"float64 only map key",
+			FloatModeFloat64Only,
+			map[float64]float64{0: 1},
+			[]byte{
+				0xa1,
+				0xfb, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+		{
+			"float64 only Float16",
+			FloatModeFloat64Only,
+			Float16(0x3c00),
+			[]byte{0xf9, 0x3c, 0x00},
+		},
+		{
+			"int if exact 10.0",
+			FloatModeIntIfExact,
+			10.0,
+			[]byte{0x0a},
+		},
+		{
+			"int if exact -1000.0",
+			FloatModeIntIfExact,
+			float32(-1000.0),
+			[]byte{0x39, 0x03, 0xe7},
+		},
+		{
+			"int if exact 0.0",
+			FloatModeIntIfExact,
+			0.0,
+			[]byte{0x00},
+		},
+		{
+			"int if exact -0.0",
+			FloatModeIntIfExact,
+			math.Copysign(0, -1),
+			[]byte{0xf9, 0x80, 0x00},
+		},
+		{
+			"int if exact 1.5",
+			FloatModeIntIfExact,
+			1.5,
+			[]byte{0xf9, 0x3e, 0x00},
+		},
+		{
+			"int if exact 2^63",
+			FloatModeIntIfExact,
+			float64(1 << 63),
+			[]byte{0x1b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"int if exact -2^63",
+			FloatModeIntIfExact,
+			float64(-1 << 63),
+			[]byte{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			"int if exact 2^64",
+			FloatModeIntIfExact,
+			float64(1 << 64),
+			[]byte{0xfa, 0x5f, 0x80, 0x00, 0x00},
+		},
+		{
+			"int if exact infinity",
+			FloatModeIntIfExact,
+			math.Inf(1),
+			[]byte{0xf9, 0x7c, 0x00},
+		},
+		{
+			"int if exact NaN",
+			FloatModeIntIfExact,
+			math.NaN(),
+			[]byte{0xf9, 0x7e, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{FloatMode: tt.mode}
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+type testEnum int
+
+const (
+	testEnumFoo testEnum = iota
+	testEnumBar
+)
+
+func (e testEnum) String() string {
+	switch e {
+	case testEnumFoo:
+		return "foo"
+	case testEnumBar:
+		return "bar"
+	}
+	return "unknown"
+}
+
+func (e *testEnum) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "foo":
+		*e = testEnumFoo
+	case "bar":
+		*e = testEnumBar
+	default:
+		return errors.New("unknown enum")
+	}
+	return nil
+}
+
+type testUintEnum uint8
+
+func (e testUintEnum) String() string {
+	return "uint" + strconv.Itoa(int(e))
+}
+
+// testPointerEnum implements fmt.Stringer with the pointer receiver.
+type testPointerEnum int
+
+func (e *testPointerEnum) String() string {
+	if *e == 1 {
+		return "one"
+	}
+	return "unknown"
+}
+
+func TestMarshal_EnumAsString(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{
+			"int enum",
+			testEnumBar,
+			[]byte{0x63, 0x62, 0x61, 0x72}, // "bar"
+		},
+		{
+			"uint enum",
+			testUintEnum(1),
+			[]byte{0x65, 0x75, 0x69, 0x6e, 0x74, 0x31}, // "uint1"
+		},
+		{
+			"enum in struct",
+			struct{ A testEnum }{testEnumFoo},
+			[]byte{0xa1, 0x61, 0x41, 0x63, 0x66, 0x6f, 0x6f}, // {"A": "foo"}
+		},
+		{
+			"enum map key",
+			map[testEnum]int{testEnumFoo: 1},
+			[]byte{0xa1, 0x63, 0x66, 0x6f, 0x6f, 0x01}, // {"foo": 1}
+		},
+		{
+			"plain int",
+			int(1),
+			[]byte{0x01},
+		},
+		{
+			"pointer receiver",
+			testPointerEnum(1),
+			[]byte{0x63, 0x6f, 0x6e, 0x65}, // "one"
+		},
+		{
+			"time.Duration has its own encoding",
+			time.Second,
+			[]byte{0x1a, 0x3b, 0x9a, 0xca, 0x00}, // 1000000000
+		},
+		{
+			"non-integer stringer",
+			pointerStringer{A: 1},
+			[]byte{0xa1, 0x61, 0x41, 0x01}, // {"A": 1}
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{EnumAsString: true}
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, err := Marshal(testEnumBar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []byte{0x01}; !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		var got struct{ A testEnum }
+		if err := Unmarshal([]byte{0xa1, 0x61, 0x41, 0x63, 0x62, 0x61, 0x72}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.A != testEnumBar {
+			t.Errorf("Unmarshal() got = %v, want %v", got.A, testEnumBar)
+		}
+	})
+}
+
+func TestMarshal_HomogeneousArrayTag(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{
+			"int slice",
+			[]int64{1, 2},
+			[]byte{0xd8, 0x29, 0x82, 0x01, 0x02}, // 41([1, 2])
+		},
+		{
+			"string array",
+			[2]string{"a", "b"},
+			[]byte{0xd8, 0x29, 0x82, 0x61, 0x61, 0x61, 0x62}, // 41(["a", "b"])
+		},
+		{
+			"nested slices",
+			[][]int{{1}},
+			[]byte{0xd8, 0x29, 0x81, 0xd8, 0x29, 0x81, 0x01}, // 41([41([1])])
+		},
+		{
+			"nil slice",
+			[]int(nil),
+			[]byte{0xf6}, // null
+		},
+		{
+			"any slice",
+			[]any{1, "a"},
+			[]byte{0x82, 0x01, 0x61, 0x61}, // [1, "a"]
+		},
+		{
+			"pointer slice",
+			[]*int{nil},
+			[]byte{0x81, 0xf6}, // [null]
+		},
+		{
+			"big.Int slice",
+			[]big.Int{*big.NewInt(1)},
+			[]byte{0x81, 0x01}, // [1]
+		},
+		{
+			"byte slice",
+			[]byte{1},
+			[]byte{0x41, 0x01}, // h'01'
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{HomogeneousArrayTag: true}
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, err := Marshal([]int64{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []byte{0x82, 0x01, 0x02}; !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+}
+
+// pointerStringer implements fmt.Stringer with the pointer receiver.
+type pointerStringer struct {
+	A int
+}
+
+func (s *pointerStringer) String() string {
+	return "A=" + strconv.Itoa(s.A)
+}
+
+func TestMarshal_StringerAsText(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{
+			"error",
+			errors.New("boom"),
+			[]byte{0x64, 0x62, 0x6f, 0x6f, 0x6d}, // "boom"
+		},
+		{
+			"error field",
+			struct{ Err error }{errors.New("boom")},
+			[]byte{0xa1, 0x63, 0x45, 0x72, 0x72, 0x64, 0x62, 0x6f, 0x6f, 0x6d}, // {"Err": "boom"}
+		},
+		{
+			"nil error",
+			struct{ Err error }{},
+			[]byte{0xa1, 0x63, 0x45, 0x72, 0x72, 0xf6}, // {"Err": null}
+		},
+		{
+			"pointer receiver",
+			pointerStringer{A: 1},
+			[]byte{0x63, 0x41, 0x3d, 0x31}, // "A=1"
+		},
+		{
+			"pointer to pointer receiver",
+			&pointerStringer{A: 1},
+			[]byte{0x63, 0x41, 0x3d, 0x31}, // "A=1"
+		},
+		{
+			"int enum",
+			testEnumBar,
+			[]byte{0x63, 0x62, 0x61, 0x72}, // "bar"
+		},
+		{
+			"time.Time has its own encoding",
+			time.Unix(0, 0),
+			[]byte{0xc1, 0xf9, 0x00, 0x00}, // 1(0.0)
+		},
+		{
+			"*big.Int has its own encoding",
+			big.NewInt(1),
+			[]byte{0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{StringerAsText: true}
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got, err := Marshal(errors.New("boom"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []byte{0xa0}; !bytes.Equal(got, want) {
+			t.Errorf("Marshal() got = %x, want %x", got, want)
+		}
+	})
+}
+
+func TestMarshal_TimeMode(t *testing.T) {
+	// tag0 returns the encoding of 0(s).
+	tag0 := func(s string) []byte {
+		if len(s) < 24 {
+			return append([]byte{0xc0, 0x60 + byte(len(s))}, s...)
+		}
+		return append([]byte{0xc0, 0x78, byte(len(s))}, s...)
+	}
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+
+	tests := []struct {
+		name string
+		opts Options
+		v    any
+		want []byte
+	}{
+		{
+			"UTC",
+			Options{TimeMode: TimeModeRFC3339},
+			time.Date(2013, 3, 21, 20, 4, 0, 0, jst),
+			tag0("2013-03-21T11:04:00Z"),
+		},
+		{
+			"trim fractional seconds",
+			Options{TimeMode: TimeModeRFC3339},
+			time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC),
+			tag0("2013-03-21T20:04:00.5Z"),
+		},
+		{
+			"location",
+			Options{TimeMode: TimeModeRFC3339, TimeLocation: jst},
+			time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+			tag0("2013-03-22T05:04:00+09:00"),
+		},
+		{
+			"precision",
+			Options{TimeMode: TimeModeRFC3339, TimePrecision: time.Millisecond},
+			time.Date(2013, 3, 21, 20, 4, 0, 123456789, time.UTC),
+			tag0("2013-03-21T20:04:00.123Z"),
+		},
+		{
+			"precision second",
+			Options{TimeMode: TimeModeRFC3339, TimePrecision: time.Second},
+			time.Date(2013, 3, 21, 20, 4, 0, 999999999, time.UTC),
+			tag0("2013-03-21T20:04:00Z"),
+		},
+		{
+			"in struct",
+			Options{TimeMode: TimeModeRFC3339},
+			struct{ A time.Time }{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+			append([]byte{0xa1, 0x61, 0x41}, tag0("2013-03-21T20:04:00Z")...),
+		},
+		{
+			"field tag options",
+			Options{TimeMode: TimeModeRFC3339},
+			struct {
+				A time.Time `cbor:"a,tag=0"`
+				B time.Time `cbor:"b,tag=1"`
+				C time.Time `cbor:"c"`
+			}{
+				time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+				time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+				time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+			},
+			append(append(append(
+				[]byte{0xa3, 0x61, 0x61}, tag0("2013-03-21T20:04:00Z")...),
+				0x61, 0x62, 0xc1, 0xfb, 0x41, 0xd4, 0x52, 0xd9, 0xec, 0x00, 0x00, 0x00,
+				0x61, 0x63), tag0("2013-03-21T20:04:00Z")...),
+		},
+		{
+			"field tag options with the default time mode",
+			Options{},
+			struct {
+				A *time.Time  `cbor:"a,tag=0"`
+				B []time.Time `cbor:"b,tag=1"`
+			}{
+				ptr(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)),
+				[]time.Time{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+			},
+			append(append(
+				[]byte{0xa2, 0x61, 0x61}, tag0("2013-03-21T20:04:00Z")...),
+				0x61, 0x62, 0x81, 0xc1, 0xfb, 0x41, 0xd4, 0x52, 0xd9, 0xec, 0x00, 0x00, 0x00),
+		},
+		{
+			"out of range",
+			Options{TimeMode: TimeModeRFC3339},
+			time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC),
+			[]byte{0xf6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		want := time.Date(2013, 3, 21, 20, 4, 0, 123456789, time.UTC)
+		data, err := Options{TimeMode: TimeModeRFC3339}.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got time.Time
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() got = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestMarshal_TimeMonotonic(t *testing.T) {
+	now := time.Now() // it has the monotonic clock reading.
+
+	t.Run("deterministic", func(t *testing.T) {
+		for _, mode := range []TimeMode{TimeModeEpoch, TimeModeRFC3339} {
+			opts := Options{TimeMode: mode}
+			got, err := opts.Marshal(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := opts.Marshal(now.Round(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Marshal() got = %x, want %x", got, want)
+			}
+		}
+	})
+
+	t.Run("TimeModeRFC3339", func(t *testing.T) {
+		data, err := Options{TimeMode: TimeModeRFC3339}.Marshal(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got time.Time
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(now) {
+			t.Errorf("Unmarshal() got = %v, want %v", got, now)
+		}
+	})
+
+	t.Run("TimeModeEpoch", func(t *testing.T) {
+		data, err := Marshal(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got time.Time
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		// the floating-point epoch loses the precision of a few hundred nanoseconds.
+		if d := got.Sub(now); d < -time.Microsecond || d > time.Microsecond {
+			t.Errorf("Unmarshal() got = %v, want %v", got, now)
+		}
+	})
+}
+
+func TestMarshal_DurationMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode DurationMode
+		v    time.Duration
+		want []byte
+	}{
+		{"nanoseconds", DurationModeNanoseconds, 1500 * time.Millisecond, []byte{0x1a, 0x59, 0x68, 0x2f, 0x00}},
+		{"negative nanoseconds", DurationModeNanoseconds, -time.Nanosecond, []byte{0x20}},
+		{"seconds", DurationModeSeconds, 1500 * time.Millisecond, []byte{0xf9, 0x3e, 0x00}},
+		{"negative seconds", DurationModeSeconds, -2 * time.Second, []byte{0xf9, 0xc0, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{DurationMode: tt.mode}
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+
+			var d time.Duration
+			if err := opts.Unmarshal(got, &d); err != nil {
+				t.Fatal(err)
+			}
+			if d != tt.v {
+				t.Errorf("Unmarshal() got = %v, want %v", d, tt.v)
+			}
+		})
+	}
+
+	t.Run("integer seconds", func(t *testing.T) {
+		opts := Options{DurationMode: DurationModeSeconds}
+		var d time.Duration
+		if err := opts.Unmarshal([]byte{0x18, 0x3c}, &d); err != nil {
+			t.Fatal(err)
+		}
+		if d != time.Minute {
+			t.Errorf("Unmarshal() got = %v, want %v", d, time.Minute)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		opts := Options{DurationMode: DurationModeSeconds}
+		inputs := [][]byte{
+			{0x1b, 0x00, 0x00, 0x00, 0x02, 0x54, 0x0b, 0xe3, 0xf5}, // 10000000501
+			{0x3b, 0x00, 0x00, 0x00, 0x02, 0x54, 0x0b, 0xe3, 0xf5}, // -10000000502
+			{0xfb, 0x7f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, // Infinity
+			{0xf9, 0x7e, 0x00}, // NaN
+		}
+		for _, input := range inputs {
+			var d time.Duration
+			err := opts.Unmarshal(input, &d)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal(%x) error = %v, want *UnmarshalTypeError", input, err)
+			}
+		}
+	})
+}
+
+func TestMarshal_StructMode(t *testing.T) {
+	type T struct {
+		B int
+		A string `cbor:",omitempty"`
+	}
+	opts := Options{StructMode: StructModeArray}
+
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{
+			"declaration order",
+			T{B: 1, A: "a"},
+			[]byte{0x82, 0x01, 0x61, 0x61}, // [1, "a"]
+		},
+		{
+			"omitempty is ignored",
+			T{B: 1},
+			[]byte{0x82, 0x01, 0x60}, // [1, ""]
+		},
+		{
+			"nested",
+			map[string]T{"x": {B: 1, A: "a"}},
+			[]byte{0xa1, 0x61, 0x78, 0x82, 0x01, 0x61, 0x61}, // {"x": [1, "a"]}
+		},
+		{
+			"toarray",
+			&FooC{A: 1, B: "2"},
+			[]byte{0x82, 0x01, 0x61, 0x32},
+		},
+		{
+			"empty inline map",
+			&FooD{A: 1},
+			[]byte{0x81, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("decode", func(t *testing.T) {
+		var got T
+		if err := opts.Unmarshal([]byte{0x82, 0x01, 0x61, 0x61}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := (T{B: 1, A: "a"}); got != want {
+			t.Errorf("Unmarshal() got = %v, want %v", got, want)
+		}
+
+		// arrays are rejected in StructModeMap.
+		err := Unmarshal([]byte{0x82, 0x01, 0x61, 0x61}, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("inline map", func(t *testing.T) {
+		_, err := opts.Marshal(&FooD{A: 1, Extra: map[string]RawMessage{"B": {0x01}}})
+		if _, ok := err.(*UnsupportedValueError); !ok {
+			t.Errorf("Marshal() error = %v, want *UnsupportedValueError", err)
+		}
+	})
+}
+
+func TestMarshal_ComplexMode(t *testing.T) {
+	opts := Options{ComplexMode: ComplexModeArray}
+
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{
+			"complex128",
+			complex(1, 2),
+			[]byte{0x82, 0xf9, 0x3c, 0x00, 0xf9, 0x40, 0x00}, // [1.0, 2.0]
+		},
+		{
+			"complex64",
+			complex64(complex(1.1, -0.5)),
+			[]byte{0x82, 0xfa, 0x3f, 0x8c, 0xcc, 0xcd, 0xf9, 0xb8, 0x00}, // [1.100000023841858, -0.5]
+		},
+		{
+			"in a map",
+			map[string]complex128{"z": 0},
+			[]byte{0xa1, 0x61, 0x7a, 0x82, 0xf9, 0x00, 0x00, 0xf9, 0x00, 0x00}, // {"z": [0.0, 0.0]}
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := opts.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal() got = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("decode", func(t *testing.T) {
+		var got complex128
+		if err := opts.Unmarshal([]byte{0x82, 0xf9, 0x3c, 0x00, 0xf9, 0x40, 0x00}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := complex(1, 2); got != want {
+			t.Errorf("Unmarshal() got = %v, want %v", got, want)
+		}
+
+		var got64 complex64
+		if err := opts.Unmarshal([]byte{0x82, 0xfa, 0x3f, 0x8c, 0xcc, 0xcd, 0xf9, 0xb8, 0x00}, &got64); err != nil {
+			t.Fatal(err)
+		}
+		if want := complex64(complex(1.1, -0.5)); got64 != want {
+			t.Errorf("Unmarshal() got = %v, want %v", got64, want)
+		}
+
+		// arrays are rejected in ComplexModeError.
+		err := Unmarshal([]byte{0x82, 0xf9, 0x3c, 0x00, 0xf9, 0x40, 0x00}, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+
+		// the array must have two elements.
+		err = opts.Unmarshal([]byte{0x83, 0xf9, 0x3c, 0x00, 0xf9, 0x40, 0x00, 0xf9, 0x40, 0x00}, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestMarshal_NaN(t *testing.T) {
+	nan := math.NaN()
+
+	t.Run("NaN", func(t *testing.T) {
+		// NaN deceives the duplicate check of the Map.
+		// So, we don't accept NaN as a key of the Map.
+		input := map[float64]int{
+			nan: 1,
+			nan: 2,
+		}
+		_, err := Marshal(input)
+		_, ok := err.(*UnsupportedValueError)
+		if !ok {
+			t.Errorf("Marshal() error = %v, want UnsupportedValueError", err)
+		}
+	})
+
+	t.Run("NaN in array", func(t *testing.T) {
+		input := map[[2]float64]int{
+			{nan, 1}: 1,
+		}
+		_, err := Marshal(input)
+		_, ok := err.(*UnsupportedValueError)
+		if !ok {
+			t.Errorf("Marshal() error = %v, want UnsupportedValueError", err)
+		}
+	})
+}
+
+func TestMarshal_NegativeZero(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	want := []byte{0xf9, 0x80, 0x00}
+
+	tests := []struct {
+		name string
+		v    any
+		ptr  any
+	}{
+		{"float64", negZero, new(float64)},
+		{"float32", float32(negZero), new(float32)},
+		{"Float16", Float16(0x8000), new(Float16)},
+		{"any", negZero, new(any)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Marshal() got = %x, want %x", got, want)
+			}
+
+			if err := Unmarshal(got, tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			var f float64
+			switch v := tt.ptr.(type) {
+			case *float64:
+				f = *v
+			case *float32:
+				f = float64(*v)
+			case *Float16:
+				f = v.Float64()
+			case *any:
+				f = (*v).(float64)
+			}
+			if f != 0 || !math.Signbit(f) {
+				t.Errorf("Unmarshal() got = %v, want -0.0", f)
+			}
+		})
+	}
+
+	t.Run("wider encodings", func(t *testing.T) {
+		for _, input := range [][]byte{
+			{0xfa, 0x80, 0x00, 0x00, 0x00},
+			{0xfb, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		} {
+			var f float64
+			if err := Unmarshal(input, &f); err != nil {
+				t.Fatal(err)
+			}
+			if f != 0 || !math.Signbit(f) {
+				t.Errorf("Unmarshal(%x) got = %v, want -0.0", input, f)
+			}
+		}
+	})
+}
+
+func BenchmarkMarshal_Uint64(b *testing.B) {
+	r := newXorshift64()
+	for i := 0; i < b.N; i++ {
+		Marshal(r.Uint64())
+	}
+}
+
+func BenchmarkMarshal_Int64(b *testing.B) {
+	r := newXorshift64()
+	for i := 0; i < b.N; i++ {
+		Marshal(int64(r.Uint64()))
+	}
+}
+
+func BenchmarkMarshal_IntSlice(b *testing.B) {
+	v := make([]int, 1000)
+	for i := range v {
+		v[i] = i
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Marshal(v)
+	}
+}
+
+func BenchmarkMarshal_RawMessageSlice(b *testing.B) {
+	v := make([]RawMessage, 1000)
+	for i := range v {
+		v[i] = RawMessage{0x18, byte(i)}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Marshal(v)
+	}
+}
+
+// rawMarshaler returns itself as the CBOR encoding.
+type rawMarshaler []byte
+
+func (m rawMarshaler) MarshalCBOR() ([]byte, error) {
+	return m, nil
+}
+
+func TestMarshal_ValidateMarshalerOutput(t *testing.T) {
+	opts := Options{ValidateMarshalerOutput: true}
+
+	t.Run("well-formed", func(t *testing.T) {
+		got, err := opts.Marshal([]any{rawMarshaler{0x82, 0x01, 0x02}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0x81, 0x82, 0x01, 0x02}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"truncated", rawMarshaler{0x82, 0x01}},
+		{"empty", rawMarshaler{}},
+		{"trailing data", rawMarshaler{0x01, 0x02}},
+		{"in struct", struct{ A rawMarshaler }{rawMarshaler{0xff}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := opts.Marshal(tt.v)
+			var merr *MarshalerError
+			if !errors.As(err, &merr) {
+				t.Fatalf("Marshal() error = %v, want *MarshalerError", err)
+			}
+			if merr.Type != reflect.TypeOf(rawMarshaler{}) {
+				t.Errorf("MarshalerError.Type = %v, want %v", merr.Type, reflect.TypeOf(rawMarshaler{}))
+			}
+
+			// the output is written verbatim without ValidateMarshalerOutput.
+			if _, err := Marshal(tt.v); err != nil {
+				t.Errorf("Marshal() error = %v", err)
+			}
+		})
+	}
+
+	rawTests := []struct {
+		name string
+		v    any
+	}{
+		{"RawMessage", RawMessage{0x18}},
+		{"RawMessage field", struct{ R RawMessage }{RawMessage{0x18}}},
+		{"RawMessage slice", []RawMessage{{0x18}}},
+		{"RawMessage array", [1]RawMessage{{0x18}}},
+		{"RawMessage map value", map[string]RawMessage{"a": {0x18}}},
+		{"empty RawMessage", []RawMessage{{}}},
+	}
+	for _, tt := range rawTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := opts.Marshal(tt.v)
+			var merr *MarshalerError
+			if !errors.As(err, &merr) {
+				t.Fatalf("Marshal() error = %v, want *MarshalerError", err)
+			}
+			if merr.Type != reflect.TypeOf(RawMessage{}) {
+				t.Errorf("MarshalerError.Type = %v, want %v", merr.Type, reflect.TypeOf(RawMessage{}))
+			}
+		})
+	}
+
+	t.Run("nil RawMessage", func(t *testing.T) {
+		got, err := opts.Marshal(struct{ R RawMessage }{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xa1, 0x61, 0x52, 0xf6}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// valueMarshaler implements CBORMarshaler with a value receiver.
+type valueMarshaler string
+
+func (m valueMarshaler) MarshalCBOR() ([]byte, error) {
+	return Marshal("v:" + string(m))
+}
+
+// ptrMarshaler implements CBORMarshaler with a pointer receiver.
+type ptrMarshaler string
+
+func (m *ptrMarshaler) MarshalCBOR() ([]byte, error) {
+	return Marshal("p:" + string(*m))
+}
+
+func TestMarshal_NestedMarshaler(t *testing.T) {
+	p := ptrMarshaler("a")
+	v := valueMarshaler("a")
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		// value receiver
+		{"value", v, []byte{0x63, 0x76, 0x3a, 0x61}},
+		{"pointer to value", &v, []byte{0x63, 0x76, 0x3a, 0x61}},
+		{"nil pointer to value", (*valueMarshaler)(nil), []byte{0xf6}},
+		{"value field", struct{ A valueMarshaler }{v}, []byte{0xa1, 0x61, 0x41, 0x63, 0x76, 0x3a, 0x61}},
+		{"nil pointer field", struct{ A *valueMarshaler }{}, []byte{0xa1, 0x61, 0x41, 0xf6}},
+		{"slice of values", []valueMarshaler{v}, []byte{0x81, 0x63, 0x76, 0x3a, 0x61}},
+		{"array of values", [1]valueMarshaler{v}, []byte{0x81, 0x63, 0x76, 0x3a, 0x61}},
+		{"map value", map[string]valueMarshaler{"k": v}, []byte{0xa1, 0x61, 0x6b, 0x63, 0x76, 0x3a, 0x61}},
+		{"map key", map[valueMarshaler]int{v: 1}, []byte{0xa1, 0x63, 0x76, 0x3a, 0x61, 0x01}},
+		{"interface", []any{v}, []byte{0x81, 0x63, 0x76, 0x3a, 0x61}},
+
+		// pointer receiver, called only for addressable values like encoding/json
+		{"pointer", &p, []byte{0x63, 0x70, 0x3a, 0x61}},
+		{"nil pointer", (*ptrMarshaler)(nil), []byte{0xf6}},
+		{"non-addressable", p, []byte{0x61, 0x61}},
+		{"pointer field", struct{ A *ptrMarshaler }{&p}, []byte{0xa1, 0x61, 0x41, 0x63, 0x70, 0x3a, 0x61}},
+		{"non-addressable field", struct{ A ptrMarshaler }{p}, []byte{0xa1, 0x61, 0x41, 0x61, 0x61}},
+		{"addressable field", &struct{ A ptrMarshaler }{p}, []byte{0xa1, 0x61, 0x41, 0x63, 0x70, 0x3a, 0x61}},
+		{"slice of pointers", []*ptrMarshaler{&p, nil}, []byte{0x82, 0x63, 0x70, 0x3a, 0x61, 0xf6}},
+		{"slice of non-pointers", []ptrMarshaler{p}, []byte{0x81, 0x63, 0x70, 0x3a, 0x61}},
+		{"map value of non-pointer", map[string]ptrMarshaler{"k": p}, []byte{0xa1, 0x61, 0x6b, 0x61, 0x61}},
+		{"array of non-pointers", [1]ptrMarshaler{p}, []byte{0x81, 0x61, 0x61}},
+		{"addressable array", &[1]ptrMarshaler{p}, []byte{0x81, 0x63, 0x70, 0x3a, 0x61}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"math"
 	"testing"
+
+	"github.com/shogo82148/float16"
 )
 
 //go:generate sh -c "perl scripts/float_gen.pl | gofmt > float_gen_test.go"
@@ -81,3 +83,77 @@ func TestFloat_Gen(t *testing.T) {
 		}
 	}
 }
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		f16  Float16
+		data []byte
+	}{
+		{float16.FromBits(0x0000), []byte{0xf9, 0x00, 0x00}}, // zero
+		{float16.FromBits(0x8000), []byte{0xf9, 0x80, 0x00}}, // negative zero
+		{float16.FromBits(0x3c00), []byte{0xf9, 0x3c, 0x00}}, // one
+		{float16.FromBits(0x7bff), []byte{0xf9, 0x7b, 0xff}}, // largest normal float16
+		{float16.FromBits(0x0001), []byte{0xf9, 0x00, 0x01}}, // smallest positive subnormal float16
+		{float16.FromBits(0x7c00), []byte{0xf9, 0x7c, 0x00}}, // inf
+		{float16.FromBits(0x7e00), []byte{0xf9, 0x7e, 0x00}}, // NaN
+		{float16.FromBits(0x7e01), []byte{0xf9, 0x7e, 0x01}}, // NaN with payload
+		{float16.FromBits(0x7d00), []byte{0xf9, 0x7d, 0x00}}, // signaling NaN
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.f16)
+		if err != nil {
+			t.Errorf("Marshal() error = %v", err)
+			continue
+		}
+		if !bytes.Equal(got, tt.data) {
+			t.Errorf("Marshal(%04x) = %x, want %x", tt.f16.Bits(), got, tt.data)
+		}
+
+		var f Float16
+		if err := Unmarshal(tt.data, &f); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+			continue
+		}
+		if f.Bits() != tt.f16.Bits() {
+			t.Errorf("Unmarshal(%x) = %04x, want %04x", tt.data, f.Bits(), tt.f16.Bits())
+		}
+	}
+}
+
+func TestFloat16_Unmarshal(t *testing.T) {
+	t.Run("lossless float32", func(t *testing.T) {
+		var f Float16
+		if err := Unmarshal([]byte{0xfa, 0x3f, 0x80, 0x00, 0x00}, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Bits() != 0x3c00 {
+			t.Errorf("unexpected bits: got %04x, want %04x", f.Bits(), 0x3c00)
+		}
+	})
+
+	t.Run("lossless float64", func(t *testing.T) {
+		var f Float16
+		if err := Unmarshal([]byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Bits() != 0x3c00 {
+			t.Errorf("unexpected bits: got %04x, want %04x", f.Bits(), 0x3c00)
+		}
+	})
+
+	t.Run("lossy float64", func(t *testing.T) {
+		var f Float16
+		err := Unmarshal([]byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}, &f) // 1.1
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("integer", func(t *testing.T) {
+		var f Float16
+		err := Unmarshal([]byte{0x01}, &f)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"cmp"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
 	"net/url"
 	"reflect"
@@ -208,12 +209,19 @@ func deepEqualRV(rx, ry reflect.Value) bool {
 		x := rx.Addr().Interface().(*big.Int)
 		y := ry.Addr().Interface().(*big.Int)
 		return x.Cmp(y) == 0
+	case bigRatType:
+		x := rx.Addr().Interface().(*big.Rat)
+		y := ry.Addr().Interface().(*big.Rat)
+		return x.Cmp(y) == 0
 	}
 
 	switch rx.Kind() {
 	case reflect.Float32, reflect.Float64:
-		// we can't use == operator because NaN != NaN
-		return cmp.Compare(rx.Float(), ry.Float()) == 0
+		// we can't use == operator because NaN != NaN,
+		// and cmp.Compare doesn't distinguish -0.0 from 0.0.
+		// The sign of NaN is insignificant.
+		x, y := rx.Float(), ry.Float()
+		return cmp.Compare(x, y) == 0 && (math.IsNaN(x) || math.Signbit(x) == math.Signbit(y))
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return rx.Int() == ry.Int()
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
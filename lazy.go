@@ -0,0 +1,88 @@
+package cbor
+
+// Lazy is a raw encoded CBOR map that decodes its values on demand.
+// The getters walk the encoded map to find the value of the key
+// without decoding the whole map into a Go map,
+// so it is useful when only a few fields of a large map are needed.
+//
+// The getters don't modify l, and they are safe for concurrent use.
+// They check the well-formedness of the items that they walk only.
+// The values are decoded with the zero Options, regardless of SetDefaultDecodeOptions.
+type Lazy RawMessage
+
+// MarshalCBOR returns l as the CBOR encoding of l.
+func (l Lazy) MarshalCBOR() ([]byte, error) {
+	return RawMessage(l).MarshalCBOR()
+}
+
+// UnmarshalCBOR sets *l to a copy of data.
+func (l *Lazy) UnmarshalCBOR(data []byte) error {
+	return (*RawMessage)(l).UnmarshalCBOR(data)
+}
+
+// Get returns the encoded value of the text string key.
+// It reports whether the key is found.
+// It returns an error if l is not a map or is not well-formed.
+// The returned message is the part of l, and it is not copied.
+func (l Lazy) Get(key string) (RawMessage, bool, error) {
+	var s Scanner
+	s.Reset(l)
+
+	major, _, err := s.Next()
+	if err != nil {
+		return nil, false, unexpectedEnd(err)
+	}
+	if major != MajorTypeMap {
+		return nil, false, newSemanticError("cbor: unexpected type, want map")
+	}
+	ok, err := s.enterMap(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	raw, err := s.Raw()
+	if err != nil {
+		return nil, false, err
+	}
+	return raw, true, nil
+}
+
+// GetString returns the value of the text string key as a string.
+// It reports whether the key is found.
+func (l Lazy) GetString(key string) (string, bool, error) {
+	var v string
+	ok, err := l.get(key, &v)
+	return v, ok, err
+}
+
+// GetInt64 returns the value of the text string key as an int64.
+// It reports whether the key is found.
+func (l Lazy) GetInt64(key string) (int64, bool, error) {
+	var v int64
+	ok, err := l.get(key, &v)
+	return v, ok, err
+}
+
+// GetFloat64 returns the value of the text string key as a float64.
+// It reports whether the key is found.
+func (l Lazy) GetFloat64(key string) (float64, bool, error) {
+	var v float64
+	ok, err := l.get(key, &v)
+	return v, ok, err
+}
+
+// GetBool returns the value of the text string key as a bool.
+// It reports whether the key is found.
+func (l Lazy) GetBool(key string) (bool, bool, error) {
+	var v bool
+	ok, err := l.get(key, &v)
+	return v, ok, err
+}
+
+// get decodes the value of the key into v.
+func (l Lazy) get(key string, v any) (bool, error) {
+	raw, ok, err := l.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, Options{}.Unmarshal(raw, v)
+}
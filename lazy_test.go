@@ -0,0 +1,170 @@
+package cbor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	// {1: "one", "a": "foo", (_ "b", "c"): -1, "d": 1.5, "e": true}
+	l := Lazy{
+		0xa5,
+		0x01, 0x63, 0x6f, 0x6e, 0x65,
+		0x61, 0x61, 0x63, 0x66, 0x6f, 0x6f,
+		0x7f, 0x61, 0x62, 0x61, 0x63, 0xff, 0x20,
+		0x61, 0x64, 0xf9, 0x3e, 0x00,
+		0x61, 0x65, 0xf5,
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		raw, ok, err := l.Get("d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("Get() not found")
+		}
+		if string(raw) != "\xf9\x3e\x00" {
+			t.Errorf("Get() = %x, want f93e00", raw)
+		}
+	})
+
+	t.Run("GetString", func(t *testing.T) {
+		v, ok, err := l.GetString("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || v != "foo" {
+			t.Errorf("GetString() = %q, %t, want %q, true", v, ok, "foo")
+		}
+	})
+
+	t.Run("GetInt64 with indefinite-length key", func(t *testing.T) {
+		v, ok, err := l.GetInt64("bc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || v != -1 {
+			t.Errorf("GetInt64() = %d, %t, want -1, true", v, ok)
+		}
+	})
+
+	t.Run("GetFloat64", func(t *testing.T) {
+		v, ok, err := l.GetFloat64("d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || v != 1.5 {
+			t.Errorf("GetFloat64() = %v, %t, want 1.5, true", v, ok)
+		}
+	})
+
+	t.Run("GetBool", func(t *testing.T) {
+		v, ok, err := l.GetBool("e")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || !v {
+			t.Errorf("GetBool() = %t, %t, want true, true", v, ok)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		v, ok, err := l.GetString("one")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok || v != "" {
+			t.Errorf("GetString() = %q, %t, want \"\", false", v, ok)
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		_, ok, err := l.GetInt64("a")
+		if !ok {
+			t.Error("GetInt64() not found")
+		}
+		var e *UnmarshalTypeError
+		if !errors.As(err, &e) {
+			t.Errorf("GetInt64() error = %v, want UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestLazy_Indefinite(t *testing.T) {
+	// {_ "a": 1, "b": 2}
+	l := Lazy{0xbf, 0x61, 0x61, 0x01, 0x61, 0x62, 0x02, 0xff}
+	v, ok, err := l.GetInt64("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || v != 2 {
+		t.Errorf("GetInt64() = %d, %t, want 2, true", v, ok)
+	}
+
+	_, ok, err = l.GetInt64("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("GetInt64() found, want not found")
+	}
+}
+
+func TestLazy_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		data Lazy
+	}{
+		{"empty", Lazy{}},
+		{"not a map", Lazy{0x80}},
+		{"short map", Lazy{0xa2, 0x61, 0x61, 0x01}},
+		{"short indefinite-length map", Lazy{0xbf, 0x61, 0x61, 0x01}},
+		{"break in definite-length map", Lazy{0xa1, 0xff}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := tt.data.Get("b"); err == nil {
+				t.Errorf("Get() should fail")
+			}
+		})
+	}
+}
+
+func TestLazy_Unmarshal(t *testing.T) {
+	var v struct {
+		A Lazy
+	}
+	data := []byte{0xa1, 0x61, 0x41, 0xa1, 0x61, 0x61, 0x01}
+	if err := Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := v.A.GetInt64("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || got != 1 {
+		t.Errorf("GetInt64() = %d, %t, want 1, true", got, ok)
+	}
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(data) {
+		t.Errorf("Marshal() = %x, want %x", b, data)
+	}
+}
+
+func TestLazy_Allocs(t *testing.T) {
+	// {"a": "foo", "b": 1}
+	l := Lazy{0xa2, 0x61, 0x61, 0x63, 0x66, 0x6f, 0x6f, 0x61, 0x62, 0x01}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := l.Get("b"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Get() allocs = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,177 @@
+package cbor
+
+import "errors"
+
+// Limits bounds the resources used to decode untrusted CBOR data.
+// The zero value of each field means no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of arrays, maps and tags.
+	// A top-level integer has depth 0, and [1] and {} have depth 1.
+	MaxDepth int
+
+	// MaxArrayLen is the maximum number of elements in an array.
+	MaxArrayLen int
+
+	// MaxMapLen is the maximum number of key-value pairs in a map.
+	MaxMapLen int
+
+	// MaxStringLen is the maximum length of a text string in bytes.
+	// The length of an indefinite-length text string is the sum of the lengths of its chunks.
+	MaxStringLen int
+
+	// MaxBytesLen is the maximum length of a byte string in bytes.
+	// The length of an indefinite-length byte string is the sum of the lengths of its chunks.
+	MaxBytesLen int
+}
+
+var (
+	// ErrMaxDepth is returned when the nesting depth exceeds Limits.MaxDepth.
+	ErrMaxDepth = errors.New("cbor: exceeded max nesting depth")
+
+	// ErrMaxArrayLen is returned when the length of an array exceeds Limits.MaxArrayLen.
+	ErrMaxArrayLen = errors.New("cbor: exceeded max array length")
+
+	// ErrMaxMapLen is returned when the length of a map exceeds Limits.MaxMapLen.
+	ErrMaxMapLen = errors.New("cbor: exceeded max map length")
+
+	// ErrMaxStringLen is returned when the length of a text string exceeds Limits.MaxStringLen.
+	ErrMaxStringLen = errors.New("cbor: exceeded max text string length")
+
+	// ErrMaxBytesLen is returned when the length of a byte string exceeds Limits.MaxBytesLen.
+	ErrMaxBytesLen = errors.New("cbor: exceeded max byte string length")
+)
+
+// UnmarshalSafe is like Unmarshal, but it checks that data is within limits before decoding.
+// It is useful to decode CBOR data from untrusted sources.
+// Nothing is stored into v if data exceeds the limits.
+// It decodes with the zero Options, regardless of SetDefaultDecodeOptions.
+func UnmarshalSafe(data []byte, v any, limits Limits) error {
+	d := newDecodeState(data)
+	if err := d.checkWellFormed(); err != nil {
+		return err
+	}
+
+	d.init(data)
+	if err := d.checkLimitsChild(&limits, 0); err != nil {
+		return err
+	}
+	return Options{}.Unmarshal(data, v)
+}
+
+// exceeds reports whether n exceeds the limit.
+func exceeds(n uint64, limit int) bool {
+	return limit > 0 && n > uint64(limit)
+}
+
+// checkLimitsChild checks the next data item is within the limits.
+// depth is the nesting depth of the data item.
+// The data must be well-formed.
+func (d *decodeState) checkLimitsChild(limits *Limits, depth int) error {
+	typ, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	major := majorType(typ >> 5)
+	info := typ & 0x1f
+
+	if major == majorTypeOther {
+		_, err := d.readArgument(info)
+		return err
+	}
+
+	if major == majorTypeArray || major == majorTypeMap || major == majorTypeTag {
+		depth++
+		if exceeds(uint64(depth), limits.MaxDepth) {
+			return ErrMaxDepth
+		}
+	}
+
+	switch major {
+	case majorTypePositiveInt, majorTypeNegativeInt:
+		_, err := d.readArgument(info)
+		return err
+
+	case majorTypeBytes, majorTypeString:
+		limit, limitErr := limits.MaxBytesLen, ErrMaxBytesLen
+		if major == majorTypeString {
+			limit, limitErr = limits.MaxStringLen, ErrMaxStringLen
+		}
+
+		if info != 31 {
+			n, err := d.readArgument(info)
+			if err != nil {
+				return err
+			}
+			if exceeds(n, limit) {
+				return limitErr
+			}
+			d.off += int(n)
+			return nil
+		}
+
+		// indefinite-length string
+		var total uint64
+		for d.data[d.off] != 0xff {
+			typ, err := d.readByte()
+			if err != nil {
+				return err
+			}
+			n, err := d.readArgument(typ & 0x1f)
+			if err != nil {
+				return err
+			}
+			total += n
+			if exceeds(total, limit) {
+				return limitErr
+			}
+			d.off += int(n)
+		}
+		d.off++ // skip the "break" stop code
+
+	case majorTypeArray, majorTypeMap:
+		limit, limitErr := limits.MaxArrayLen, ErrMaxArrayLen
+		children := uint64(1)
+		if major == majorTypeMap {
+			limit, limitErr = limits.MaxMapLen, ErrMaxMapLen
+			children = 2
+		}
+
+		if info != 31 {
+			n, err := d.readArgument(info)
+			if err != nil {
+				return err
+			}
+			if exceeds(n, limit) {
+				return limitErr
+			}
+			for i := uint64(0); i < n*children; i++ {
+				if err := d.checkLimitsChild(limits, depth); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// indefinite-length array or map
+		var n uint64
+		for d.data[d.off] != 0xff {
+			n++
+			if exceeds(n, limit) {
+				return limitErr
+			}
+			for i := uint64(0); i < children; i++ {
+				if err := d.checkLimitsChild(limits, depth); err != nil {
+					return err
+				}
+			}
+		}
+		d.off++ // skip the "break" stop code
+
+	case majorTypeTag:
+		if _, err := d.readArgument(info); err != nil {
+			return err
+		}
+		return d.checkLimitsChild(limits, depth)
+	}
+	return nil
+}
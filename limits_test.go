@@ -0,0 +1,84 @@
+package cbor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnmarshalSafe(t *testing.T) {
+	limits := Limits{
+		MaxDepth:     2,
+		MaxArrayLen:  2,
+		MaxMapLen:    1,
+		MaxStringLen: 3,
+		MaxBytesLen:  2,
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		// {"abc": [h'0102', 1]}
+		input := []byte{0xa1, 0x63, 0x61, 0x62, 0x63, 0x82, 0x42, 0x01, 0x02, 0x01}
+		var got any
+		if err := UnmarshalSafe(input, &got, limits); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]any{
+			"abc": []any{[]byte{0x01, 0x02}, int64(1)},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("UnmarshalSafe() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("no limits", func(t *testing.T) {
+		input := []byte{0x81, 0x81, 0x81, 0x81, 0x01} // [[[[1]]]]
+		var got any
+		if err := UnmarshalSafe(input, &got, Limits{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  error
+	}{
+		{"depth", []byte{0x81, 0x81, 0x81, 0x01}, ErrMaxDepth},                           // [[[1]]]
+		{"depth with tags", []byte{0x81, 0xc1, 0xc1, 0x01}, ErrMaxDepth},                 // [1(1(1))]
+		{"depth of indefinite", []byte{0x9f, 0x9f, 0x9f, 0xff, 0xff, 0xff}, ErrMaxDepth}, // [_ [_ [_ ]]]
+		{"array", []byte{0x83, 0x01, 0x02, 0x03}, ErrMaxArrayLen},                        // [1, 2, 3]
+		{"indefinite array", []byte{0x9f, 0x01, 0x02, 0x03, 0xff}, ErrMaxArrayLen},       // [_ 1, 2, 3]
+		{"map", []byte{0xa2, 0x01, 0x02, 0x03, 0x04}, ErrMaxMapLen},                      // {1: 2, 3: 4}
+		{"indefinite map", []byte{0xbf, 0x01, 0x02, 0x03, 0x04, 0xff}, ErrMaxMapLen},     // {_ 1: 2, 3: 4}
+		{"string", []byte{0x64, 0x61, 0x62, 0x63, 0x64}, ErrMaxStringLen},                // "abcd"
+		{
+			"indefinite string",
+			[]byte{0x7f, 0x62, 0x61, 0x62, 0x62, 0x63, 0x64, 0xff}, // (_ "ab", "cd")
+			ErrMaxStringLen,
+		},
+		{"bytes", []byte{0x43, 0x01, 0x02, 0x03}, ErrMaxBytesLen},                              // h'010203'
+		{"indefinite bytes", []byte{0x5f, 0x41, 0x01, 0x42, 0x02, 0x03, 0xff}, ErrMaxBytesLen}, // (_ h'01', h'0203')
+		{"nested", []byte{0x81, 0x43, 0x01, 0x02, 0x03}, ErrMaxBytesLen},                       // [h'010203']
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			err := UnmarshalSafe(tt.input, &got, limits)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("UnmarshalSafe() error = %v, want %v", err, tt.want)
+			}
+			if got != nil {
+				t.Errorf("UnmarshalSafe() stored %v, want nothing", got)
+			}
+		})
+	}
+
+	t.Run("not well-formed", func(t *testing.T) {
+		var got any
+		err := UnmarshalSafe([]byte{0x82, 0x01}, &got, limits)
+		if err != ErrUnexpectedEnd {
+			t.Errorf("UnmarshalSafe() error = %v, want %v", err, ErrUnexpectedEnd)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrPathNotFound is returned by GetPath when the path doesn't exist in the data.
+var ErrPathNotFound = errors.New("cbor: path not found")
+
+// GetPath returns the encoded data item at the path in data without decoding the other items.
+// Each element of path selects a child of the current item:
+// a text string selects the value of the key in a map,
+// and an integer selects the value of the key in a map or the element at the index in an array.
+// It returns ErrPathNotFound if no item matches the path,
+// and a SyntaxError if data is not a single well-formed data item.
+// The returned message is the part of data, and it is not copied.
+func GetPath(data []byte, path ...any) (RawMessage, error) {
+	d := newDecodeState(data)
+	if err := d.checkWellFormed(); err != nil {
+		return nil, err
+	}
+
+	var s Scanner
+	s.Reset(data)
+
+	if _, _, err := s.Next(); err != nil {
+		return nil, unexpectedEnd(err)
+	}
+	for _, p := range path {
+		var ok bool
+		var err error
+		switch s.major {
+		case MajorTypeMap:
+			ok, err = s.enterMap(p)
+		case MajorTypeArray:
+			ok, err = s.enterArray(p)
+		default:
+			return nil, newSemanticError("cbor: unexpected type, want array or map")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+	}
+	return s.Raw()
+}
+
+// enterMap reads the pairs of the current map until the key matches,
+// and makes the value of the key the current item.
+// It reports whether the key is found.
+func (s *Scanner) enterMap(key any) (bool, error) {
+	if _, ok := key.(string); !ok {
+		if _, ok := pathInteger(key); !ok {
+			return false, errors.New("cbor: unsupported type of the path element")
+		}
+	}
+
+	indefinite := s.head.Indefinite()
+	n := s.head.Arg
+	for i := uint64(0); indefinite || i < n; i++ {
+		// read the key.
+		major, h, err := s.Next()
+		if err != nil {
+			return false, unexpectedEnd(err)
+		}
+		if indefinite && major == MajorTypeOther && h.Indefinite() {
+			// the "break" stop code.
+			break
+		}
+		match, err := s.matchKey(major, h, key)
+		if err != nil {
+			return false, err
+		}
+
+		// read the value.
+		if _, _, err := s.Next(); err != nil {
+			return false, unexpectedEnd(err)
+		}
+		if match {
+			return true, nil
+		}
+		if err := s.Skip(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// enterArray reads the elements of the current array until the index,
+// and makes the element at the index the current item.
+// It reports whether the element is found.
+func (s *Scanner) enterArray(index any) (bool, error) {
+	idx, ok := pathInteger(index)
+	if !ok {
+		return false, errors.New("cbor: unsupported type of the path element")
+	}
+	if idx.Sign {
+		return false, nil
+	}
+
+	indefinite := s.head.Indefinite()
+	n := s.head.Arg
+	for i := uint64(0); indefinite || i < n; i++ {
+		major, h, err := s.Next()
+		if err != nil {
+			return false, unexpectedEnd(err)
+		}
+		if indefinite && major == MajorTypeOther && h.Indefinite() {
+			// the "break" stop code.
+			break
+		}
+		if i == idx.Value {
+			return true, nil
+		}
+		if err := s.Skip(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// matchKey reports whether the current item is the key,
+// and skips the item.
+// key is a string or an integer accepted by pathInteger.
+func (s *Scanner) matchKey(major MajorType, h Head, key any) (bool, error) {
+	if str, ok := key.(string); ok {
+		return s.matchString(major, h, str)
+	}
+
+	i, _ := pathInteger(key)
+	switch major {
+	case MajorTypePositiveInt:
+		return !i.Sign && h.Arg == i.Value, nil
+	case MajorTypeNegativeInt:
+		return i.Sign && h.Arg == i.Value, nil
+	}
+	return false, s.Skip()
+}
+
+// matchString reports whether the current item is the text string key,
+// and skips the item.
+func (s *Scanner) matchString(major MajorType, h Head, key string) (bool, error) {
+	if major != MajorTypeString {
+		return false, s.Skip()
+	}
+	if !h.Indefinite() {
+		return string(s.Bytes()) == key, nil
+	}
+
+	// indefinite-length text string.
+	raw, err := s.Raw()
+	if err != nil {
+		return false, err
+	}
+	var str string
+	if err := (Options{}).Unmarshal(raw, &str); err != nil {
+		return false, err
+	}
+	return str == key, nil
+}
+
+// pathInteger converts the integer element of a path to Integer.
+func pathInteger(v any) (Integer, bool) {
+	var i int64
+	switch v := v.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint:
+		return Integer{Value: uint64(v)}, true
+	case uint8:
+		return Integer{Value: uint64(v)}, true
+	case uint16:
+		return Integer{Value: uint64(v)}, true
+	case uint32:
+		return Integer{Value: uint64(v)}, true
+	case uint64:
+		return Integer{Value: v}, true
+	case Integer:
+		return v, true
+	default:
+		return Integer{}, false
+	}
+	if i < 0 {
+		return Integer{Sign: true, Value: uint64(-1 - i)}, true
+	}
+	return Integer{Value: uint64(i)}, true
+}
+
+// unexpectedEnd converts io.EOF into ErrUnexpectedEnd.
+func unexpectedEnd(err error) error {
+	if err == io.EOF {
+		return ErrUnexpectedEnd
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestGetPath(t *testing.T) {
+	// {"a": [1, {2: "foo", -1: "bar"}], "b": (_ 3, 4), 1: "one"}
+	input := []byte{
+		0xa3,
+		0x61, 0x61, 0x82, 0x01, 0xa2, 0x02, 0x63, 0x66, 0x6f, 0x6f, 0x20, 0x63, 0x62, 0x61, 0x72,
+		0x61, 0x62, 0x9f, 0x03, 0x04, 0xff,
+		0x01, 0x63, 0x6f, 0x6e, 0x65,
+	}
+
+	tests := []struct {
+		name string
+		path []any
+		want RawMessage
+	}{
+		{"root", nil, input},
+		{"text string key", []any{"b"}, RawMessage{0x9f, 0x03, 0x04, 0xff}},
+		{"integer key", []any{1}, RawMessage{0x63, 0x6f, 0x6e, 0x65}},
+		{"array index", []any{"a", 0}, RawMessage{0x01}},
+		{"nested", []any{"a", 1, uint64(2)}, RawMessage{0x63, 0x66, 0x6f, 0x6f}},
+		{"negative integer key", []any{"a", 1, int8(-1)}, RawMessage{0x63, 0x62, 0x61, 0x72}},
+		{"indefinite-length array", []any{"b", 1}, RawMessage{0x04}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetPath(input, tt.path...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("GetPath() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPath_NotFound(t *testing.T) {
+	// {"a": [1, 2], "b": (_ 3)}
+	input := []byte{0xa2, 0x61, 0x61, 0x82, 0x01, 0x02, 0x61, 0x62, 0x9f, 0x03, 0xff}
+
+	tests := []struct {
+		name string
+		path []any
+	}{
+		{"missing key", []any{"c"}},
+		{"integer key for text string", []any{0}},
+		{"out of range", []any{"a", 2}},
+		{"negative index", []any{"a", -1}},
+		{"out of range of indefinite-length array", []any{"b", 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetPath(input, tt.path...)
+			if err != ErrPathNotFound {
+				t.Errorf("GetPath() error = %v, want ErrPathNotFound", err)
+			}
+		})
+	}
+}
+
+func TestGetPath_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		path  []any
+	}{
+		{"empty", []byte{}, nil},
+		{"not a container", []byte{0x01}, []any{0}},
+		{"text string index", []byte{0x81, 0x01}, []any{"a"}},
+		{"unsupported path element", []byte{0xa0}, []any{1.5}},
+		{"short map", []byte{0xa2, 0x61, 0x61, 0x01}, []any{"b"}},
+		{"short array", []byte{0x82, 0x01}, []any{1}},
+		{"ill-formed sibling", []byte{0x82, 0x1c, 0x01}, []any{1}},
+		{"ill-formed target", []byte{0x81, 0x82, 0x01}, []any{0}},
+		{"trailing data of array", []byte{0x81, 0x01, 0x02}, []any{0}},
+		{"trailing data of root", []byte{0x01, 0x02}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetPath(tt.input, tt.path...)
+			if err == nil || err == ErrPathNotFound {
+				t.Errorf("GetPath() error = %v, want an error", err)
+			}
+		})
+	}
+
+	t.Run("syntax error offset", func(t *testing.T) {
+		input := []byte{0x81, 0x01, 0x02}
+		_, err := GetPath(input, 0)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Fatalf("GetPath() error = %v, want *SyntaxError", err)
+		}
+		if se.Offset != 2 {
+			t.Errorf("unexpected Offset: got %d, want %d", se.Offset, 2)
+		}
+	})
+}
@@ -0,0 +1,275 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/shogo82148/float16"
+)
+
+// MajorType is the major type of a CBOR data item.
+// See RFC 8949 Section 3.1.
+type MajorType byte
+
+const (
+	// MajorTypePositiveInt is an unsigned integer.
+	MajorTypePositiveInt MajorType = 0
+
+	// MajorTypeNegativeInt is a negative integer -1-n.
+	MajorTypeNegativeInt MajorType = 1
+
+	// MajorTypeBytes is a byte string.
+	MajorTypeBytes MajorType = 2
+
+	// MajorTypeString is a text string.
+	MajorTypeString MajorType = 3
+
+	// MajorTypeArray is an array of data items.
+	MajorTypeArray MajorType = 4
+
+	// MajorTypeMap is a map of pairs of data items.
+	MajorTypeMap MajorType = 5
+
+	// MajorTypeTag is a tagged data item.
+	MajorTypeTag MajorType = 6
+
+	// MajorTypeOther is a simple value, a floating-point number or the "break" stop code.
+	MajorTypeOther MajorType = 7
+)
+
+// String returns the name of the major type.
+func (m MajorType) String() string {
+	switch m {
+	case MajorTypePositiveInt:
+		return "positive integer"
+	case MajorTypeNegativeInt:
+		return "negative integer"
+	case MajorTypeBytes:
+		return "byte string"
+	case MajorTypeString:
+		return "text string"
+	case MajorTypeArray:
+		return "array"
+	case MajorTypeMap:
+		return "map"
+	case MajorTypeTag:
+		return "tag"
+	case MajorTypeOther:
+		return "simple value or float"
+	}
+	return "MajorType(" + strconv.Itoa(int(m)) + ")"
+}
+
+// Head is the head of a CBOR data item.
+type Head struct {
+	// Info is the additional information, the low-order 5 bits of the initial byte.
+	Info byte
+
+	// Arg is the argument of the head:
+	// the value of unsigned integers, n of negative integers -1-n,
+	// the length of strings, the number of elements of arrays,
+	// the number of pairs of maps, the tag number,
+	// the simple value, or the bits of the floating-point number.
+	// It is zero for indefinite-length items.
+	Arg uint64
+}
+
+// Indefinite reports whether the head starts an indefinite-length item.
+// For MajorTypeOther, it reports whether the head is the "break" stop code.
+func (h Head) Indefinite() bool {
+	return h.Info == 31
+}
+
+// Float returns the value of the floating-point number of MajorTypeOther.
+// It returns zero if the head is not a floating-point number.
+func (h Head) Float() float64 {
+	switch h.Info {
+	case 25:
+		return float16.FromBits(uint16(h.Arg)).Float64()
+	case 26:
+		return float64(math.Float32frombits(uint32(h.Arg)))
+	case 27:
+		return math.Float64frombits(h.Arg)
+	}
+	return 0
+}
+
+// Scanner is a low-level cursor over CBOR data.
+// It walks the data items in the pre-order without reflection,
+// and doesn't allocate memory unless it returns an error.
+//
+// Next reads the heads one by one: the elements of arrays and maps,
+// the contents of tags and the chunks of indefinite-length strings
+// are returned by the following calls, and indefinite-length items
+// end with the "break" stop code.
+// The contents of byte strings and text strings are consumed with their heads,
+// and available by Bytes.
+//
+// Scanner checks the well-formedness of each head only.
+// Use Raw to check the whole data item.
+type Scanner struct {
+	d     decodeState
+	start int // start offset of the current item
+	major MajorType
+	head  Head
+	valid bool // whether the current item is available
+}
+
+// NewScanner returns a new scanner that reads from data.
+func NewScanner(data []byte) *Scanner {
+	s := new(Scanner)
+	s.Reset(data)
+	return s
+}
+
+// Reset resets the scanner to read from data.
+// It allows to reuse the scanner without allocation.
+func (s *Scanner) Reset(data []byte) {
+	s.d.init(data)
+	s.start = 0
+	s.major = 0
+	s.head = Head{}
+	s.valid = false
+}
+
+// Next reads the head of the next data item.
+// It returns io.EOF if there is no more data.
+func (s *Scanner) Next() (MajorType, Head, error) {
+	s.valid = false
+	if s.d.off >= len(s.d.data) {
+		return 0, Head{}, io.EOF
+	}
+
+	start := s.d.off
+	major, head, err := readHead(&s.d)
+	if err != nil {
+		return 0, Head{}, err
+	}
+
+	if (major == MajorTypeBytes || major == MajorTypeString) && !head.Indefinite() {
+		if !s.d.isAvailable(head.Arg) {
+			s.d.off = start
+			return 0, Head{}, ErrUnexpectedEnd
+		}
+		s.d.off += int(head.Arg)
+	}
+
+	s.start = start
+	s.major = major
+	s.head = head
+	s.valid = true
+	return major, head, nil
+}
+
+// readHead reads the head of the next data item from d.
+// d.off is not moved if it returns an error.
+func readHead(d *decodeState) (MajorType, Head, error) {
+	start := d.off
+	typ, err := d.readByte()
+	if err != nil {
+		return 0, Head{}, err
+	}
+	major := MajorType(typ >> 5)
+	head := Head{Info: typ & 0x1f}
+
+	if head.Indefinite() {
+		switch major {
+		case MajorTypePositiveInt, MajorTypeNegativeInt, MajorTypeTag:
+			d.off = start
+			return 0, Head{}, d.newSyntaxError("cbor: invalid additional information")
+		}
+		return major, head, nil
+	}
+
+	arg, err := d.readArgument(head.Info)
+	if err != nil {
+		d.off = start
+		return 0, Head{}, err
+	}
+	if major == MajorTypeOther && head.Info == 24 && arg < 0x20 {
+		d.off = start
+		return 0, Head{}, d.newSyntaxError("cbor: invalid simple value")
+	}
+	head.Arg = arg
+	return major, head, nil
+}
+
+// PeekHead reads the head of the first data item in data without decoding the item,
+// e.g. to dispatch the data by its major type and length before decoding it.
+// arg is the argument of the head described in Head.Arg,
+// indefinite reports whether the head starts an indefinite-length item (see Head.Indefinite),
+// and headLen is the length of the head in bytes.
+// The contents of the item are not read, so they may be missing or malformed.
+func PeekHead(data []byte) (major MajorType, arg uint64, indefinite bool, headLen int, err error) {
+	var d decodeState
+	d.init(data)
+	major, head, err := readHead(&d)
+	if err != nil {
+		return 0, 0, false, 0, err
+	}
+	return major, head.Arg, head.Indefinite(), d.off, nil
+}
+
+// MinimalUint returns the head of the major type with the argument v in the shortest form,
+// e.g. 0x18 0x64 for MajorTypePositiveInt and 100,
+// as the encoder and the deterministic encoding (RFC 8949 Section 4.2.1) require.
+// It is the counterpart of PeekHead.
+// For MajorTypeOther, v is the simple value or the bits of the floating-point number,
+// and the head is not well-formed if v is 24 through 31.
+// It panics if major is not one of the MajorType constants.
+func MinimalUint(major MajorType, v uint64) []byte {
+	if major > MajorTypeOther {
+		panic("cbor: invalid major type " + major.String())
+	}
+	var e encodeState
+	e.writeUint(major, v)
+	return e.buf.Bytes()
+}
+
+// Offset returns the offset of the current item, which is read by the last call of Next.
+func (s *Scanner) Offset() int {
+	return s.start
+}
+
+// Bytes returns the content of the current item
+// if it is a definite-length byte string or text string.
+// Otherwise, it returns nil.
+// The returned slice is the part of the data, and it is not copied.
+func (s *Scanner) Bytes() []byte {
+	if !s.valid || s.head.Indefinite() {
+		return nil
+	}
+	if s.major != MajorTypeBytes && s.major != MajorTypeString {
+		return nil
+	}
+	end := s.d.off
+	return s.d.data[end-int(s.head.Arg) : end : end]
+}
+
+// Raw returns the encoded current item, including the elements of arrays and maps,
+// the contents of tags and the chunks of indefinite-length strings,
+// and skips them so that the next call of Next reads the item following the current item.
+// The returned message is the part of the data, and it is not copied.
+// It returns an error if the current item is not well-formed,
+// or is the "break" stop code.
+func (s *Scanner) Raw() (RawMessage, error) {
+	if !s.valid {
+		return nil, errors.New("cbor: Raw called without the current item")
+	}
+	s.d.off = s.start
+	if err := s.d.checkWellFormedChild(); err != nil {
+		s.d.off = s.start
+		s.valid = false
+		return nil, err
+	}
+	s.valid = false
+	return RawMessage(s.d.data[s.start:s.d.off:s.d.off]), nil
+}
+
+// Skip is like Raw, but it doesn't return the encoded item.
+func (s *Scanner) Skip() error {
+	_, err := s.Raw()
+	return err
+}
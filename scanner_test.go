@@ -0,0 +1,297 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanner(t *testing.T) {
+	type item struct {
+		Major MajorType
+		Head  Head
+		Bytes []byte
+	}
+
+	// [1, -2, "ab", {_ h'01': 1(1.5)}, (_ "c"), true]
+	input := []byte{
+		0x86,
+		0x01,
+		0x21,
+		0x62, 0x61, 0x62,
+		0xbf, 0x41, 0x01, 0xc1, 0xf9, 0x3e, 0x00, 0xff,
+		0x7f, 0x61, 0x63, 0xff,
+		0xf5,
+	}
+	want := []item{
+		{MajorTypeArray, Head{Info: 6, Arg: 6}, nil},
+		{MajorTypePositiveInt, Head{Info: 1, Arg: 1}, nil},
+		{MajorTypeNegativeInt, Head{Info: 1, Arg: 1}, nil},
+		{MajorTypeString, Head{Info: 2, Arg: 2}, []byte("ab")},
+		{MajorTypeMap, Head{Info: 31}, nil},
+		{MajorTypeBytes, Head{Info: 1, Arg: 1}, []byte{0x01}},
+		{MajorTypeTag, Head{Info: 1, Arg: 1}, nil},
+		{MajorTypeOther, Head{Info: 25, Arg: 0x3e00}, nil},
+		{MajorTypeOther, Head{Info: 31}, nil},
+		{MajorTypeString, Head{Info: 31}, nil},
+		{MajorTypeString, Head{Info: 1, Arg: 1}, []byte("c")},
+		{MajorTypeOther, Head{Info: 31}, nil},
+		{MajorTypeOther, Head{Info: 21, Arg: 21}, nil},
+	}
+
+	s := NewScanner(input)
+	var got []item
+	for {
+		major, head, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, item{major, head, s.Bytes()})
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Next() mismatch (-want +got):\n%s", diff)
+	}
+
+	if f := want[7].Head.Float(); f != 1.5 {
+		t.Errorf("Float() = %v, want 1.5", f)
+	}
+}
+
+func TestScanner_Raw(t *testing.T) {
+	// {"a": [1, 2], "b": 3}
+	input := []byte{0xa2, 0x61, 0x61, 0x82, 0x01, 0x02, 0x61, 0x62, 0x03}
+	s := NewScanner(input)
+
+	if _, _, err := s.Next(); err != nil { // the map
+		t.Fatal(err)
+	}
+	if _, _, err := s.Next(); err != nil { // "a"
+		t.Fatal(err)
+	}
+	if _, _, err := s.Next(); err != nil { // [1, 2]
+		t.Fatal(err)
+	}
+	if s.Offset() != 3 {
+		t.Errorf("Offset() = %d, want 3", s.Offset())
+	}
+	raw, err := s.Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(RawMessage{0x82, 0x01, 0x02}, raw); diff != "" {
+		t.Errorf("Raw() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the next item is "b".
+	major, _, err := s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if major != MajorTypeString || string(s.Bytes()) != "b" {
+		t.Errorf("Next() = %d %q, want the text string \"b\"", major, s.Bytes())
+	}
+	if _, _, err := s.Next(); err != nil { // 3
+		t.Fatal(err)
+	}
+	if err := s.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Next(); err != io.EOF {
+		t.Errorf("Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestScanner_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  error
+	}{
+		{"unexpected end of argument", []byte{0x19, 0x01}, ErrUnexpectedEnd},
+		{"unexpected end of string", []byte{0x62, 0x61}, ErrUnexpectedEnd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := NewScanner(tt.input).Next()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Next() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	for _, input := range [][]byte{
+		{0x1c},       // reserved additional information
+		{0x1f},       // indefinite-length integer
+		{0xdf},       // indefinite-length tag
+		{0xf8, 0x01}, // invalid simple value
+	} {
+		_, _, err := NewScanner(input).Next()
+		if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Next(%x) error = %v, want *SyntaxError", input, err)
+		}
+	}
+
+	t.Run("raw of not well-formed item", func(t *testing.T) {
+		s := NewScanner([]byte{0x82, 0x01})
+		if _, _, err := s.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Raw(); err != ErrUnexpectedEnd {
+			t.Errorf("Raw() error = %v, want %v", err, ErrUnexpectedEnd)
+		}
+	})
+
+	t.Run("raw of break", func(t *testing.T) {
+		s := NewScanner([]byte{0xff})
+		if _, _, err := s.Next(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Raw(); err == nil {
+			t.Error("Raw() error = nil, want error")
+		}
+	})
+}
+
+func TestScanner_Allocs(t *testing.T) {
+	input := []byte{0x82, 0x62, 0x61, 0x62, 0xa1, 0x01, 0x82, 0x01, 0x02}
+	var s Scanner
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Reset(input)
+		for {
+			major, _, err := s.Next()
+			if err != nil {
+				break
+			}
+			if major == MajorTypeMap {
+				if _, err := s.Raw(); err != nil {
+					break
+				}
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Scanner allocates %v times, want 0", allocs)
+	}
+}
+
+func TestPeekHead(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		major      MajorType
+		arg        uint64
+		indefinite bool
+		headLen    int
+	}{
+		{"small integer", []byte{0x01}, MajorTypePositiveInt, 1, false, 1},
+		{"negative integer", []byte{0x38, 0x63}, MajorTypeNegativeInt, 99, false, 2},
+		{"64-bit integer", []byte{0x1b, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, MajorTypePositiveInt, 0x0102030405060708, false, 9},
+		{"text string without content", []byte{0x79, 0x01, 0x00}, MajorTypeString, 256, false, 3},
+		{"array", []byte{0x83, 0x01, 0x02, 0x03}, MajorTypeArray, 3, false, 1},
+		{"indefinite-length map", []byte{0xbf, 0xff}, MajorTypeMap, 0, true, 1},
+		{"tag", []byte{0xd8, 0x20, 0x61, 0x61}, MajorTypeTag, 32, false, 2},
+		{"float", []byte{0xf9, 0x3c, 0x00}, MajorTypeOther, 0x3c00, false, 3},
+		{"break", []byte{0xff}, MajorTypeOther, 0, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			major, arg, indefinite, headLen, err := PeekHead(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if major != tt.major || arg != tt.arg || indefinite != tt.indefinite || headLen != tt.headLen {
+				t.Errorf("PeekHead() = %v, %d, %v, %d, want %v, %d, %v, %d",
+					major, arg, indefinite, headLen, tt.major, tt.arg, tt.indefinite, tt.headLen)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"reserved additional information", []byte{0x1c}},
+		{"indefinite-length integer", []byte{0x1f}},
+		{"invalid simple value", []byte{0xf8, 0x01}},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, _, err := PeekHead(tt.data); err == nil {
+				t.Error("PeekHead() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMinimalUint(t *testing.T) {
+	tests := []struct {
+		major MajorType
+		v     uint64
+		want  []byte
+	}{
+		{MajorTypePositiveInt, 0, []byte{0x00}},
+		{MajorTypePositiveInt, 23, []byte{0x17}},
+		{MajorTypePositiveInt, 24, []byte{0x18, 0x18}},
+		{MajorTypePositiveInt, 0xff, []byte{0x18, 0xff}},
+		{MajorTypeNegativeInt, 0x100, []byte{0x39, 0x01, 0x00}},
+		{MajorTypeBytes, 0xffff, []byte{0x59, 0xff, 0xff}},
+		{MajorTypeString, 0x10000, []byte{0x7a, 0x00, 0x01, 0x00, 0x00}},
+		{MajorTypeArray, 0xffffffff, []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{MajorTypeMap, 0x100000000, []byte{0xbb, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{MajorTypeTag, 55799, []byte{0xd9, 0xd9, 0xf7}},
+		{MajorTypeOther, 22, []byte{0xf6}},
+	}
+	for _, tt := range tests {
+		got := MinimalUint(tt.major, tt.v)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("MinimalUint(%v, %d) mismatch (-want +got):\n%s", tt.major, tt.v, diff)
+		}
+
+		// PeekHead reads it back.
+		major, arg, _, headLen, err := PeekHead(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if major != tt.major || arg != tt.v || headLen != len(got) {
+			t.Errorf("PeekHead(%x) = %v, %d, %d, want %v, %d, %d", got, major, arg, headLen, tt.major, tt.v, len(got))
+		}
+	}
+
+	t.Run("invalid major type", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("MinimalUint() should panic")
+			}
+		}()
+		MinimalUint(MajorType(8), 0)
+	})
+}
+
+func TestMajorType_String(t *testing.T) {
+	tests := []struct {
+		major MajorType
+		want  string
+	}{
+		{MajorTypePositiveInt, "positive integer"},
+		{MajorTypeNegativeInt, "negative integer"},
+		{MajorTypeBytes, "byte string"},
+		{MajorTypeString, "text string"},
+		{MajorTypeArray, "array"},
+		{MajorTypeMap, "map"},
+		{MajorTypeTag, "tag"},
+		{MajorTypeOther, "simple value or float"},
+		{MajorType(8), "MajorType(8)"},
+	}
+	for _, tt := range tests {
+		if got := tt.major.String(); got != tt.want {
+			t.Errorf("MajorType(%d).String() = %q, want %q", byte(tt.major), got, tt.want)
+		}
+	}
+}
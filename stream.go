@@ -1,8 +1,13 @@
 package cbor
 
 import (
+	"bytes"
+	"encoding/binary"
 	"io"
+	"maps"
+	"reflect"
 	"slices"
+	"time"
 )
 
 // A Decoder reads and decodes CBOR values from an input stream.
@@ -21,6 +26,8 @@ func NewDecoder(r io.Reader) *Decoder {
 
 // Decode reads the next CBOR-encoded value from its input and stores it in the
 // value pointed to by v.
+// Like Unmarshal, it returns an UnmarshalTypeError if a CBOR value is not appropriate
+// for the Go type; the value is consumed, so the next call decodes the next value.
 func (dec *Decoder) Decode(v any) error {
 	if dec.err != nil {
 		return dec.err
@@ -33,10 +40,103 @@ func (dec *Decoder) Decode(v any) error {
 	dec.d.init(dec.buf[dec.scanp : dec.scanp+n])
 	dec.scanp += n
 
-	return dec.d.decode(v)
+	if err := dec.d.decode(v); err != nil {
+		return err
+	}
+	return dec.d.savedError
+}
+
+// DecodeArrayFunc reads the head of the next CBOR array from its input and
+// calls fn once for each element of the array.
+// fn must read exactly one value from dec, e.g. by calling dec.Decode.
+// Both definite-length and indefinite-length arrays are supported.
+// It is useful to process a large array without reading all elements into memory.
+func (dec *Decoder) DecodeArrayFunc(fn func(dec *Decoder) error) error {
+	if dec.err != nil {
+		return dec.err
+	}
+
+	if err := dec.fill(1); err != nil {
+		return err
+	}
+	typ := dec.buf[dec.scanp]
+	if majorType(typ>>5) != majorTypeArray {
+		return newSemanticError("cbor: unexpected type, want array")
+	}
+
+	// indefinite-length array
+	if typ == 0x9f {
+		dec.scanp++
+		for {
+			if err := dec.fill(1); err != nil {
+				return noEOF(err)
+			}
+			if dec.buf[dec.scanp] == 0xff {
+				dec.scanp++
+				return nil
+			}
+			if err := fn(dec); err != nil {
+				return err
+			}
+		}
+	}
+
+	// definite-length array
+	var n uint64
+	switch info := typ & 0x1f; {
+	case info < 24:
+		dec.scanp++
+		n = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if err := dec.fill(1 + size); err != nil {
+			return noEOF(err)
+		}
+		d := newDecodeState(dec.buf[dec.scanp+1 : dec.scanp+1+size])
+		w, err := d.readArgument(info)
+		if err != nil {
+			return err
+		}
+		dec.scanp += 1 + size
+		n = w
+	default:
+		return &SyntaxError{msg: "cbor: invalid additional information"}
+	}
+	for i := uint64(0); i < n; i++ {
+		if err := fn(dec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fill reads data until n bytes of unread data are available in the buffer.
+func (dec *Decoder) fill(n int) error {
+	for len(dec.buf)-dec.scanp < n {
+		err := dec.refill()
+		if len(dec.buf)-dec.scanp >= n {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF && len(dec.buf)-dec.scanp > 0 {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// noEOF converts io.EOF into io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
 }
 
 // UseAnyKey allows decoding maps to map[any]any instead of map[string]any.
+// See Options.UseAnyKey for the semantics of map keys.
 func (dec *Decoder) UseAnyKey() {
 	dec.d.useAnyKey = true
 }
@@ -46,18 +146,150 @@ func (dec *Decoder) UseInteger() {
 	dec.d.useInteger = true
 }
 
+// PreserveTags allows decoding tags to Tag instead of interpreting them.
+// See Options.PreserveTags.
+func (dec *Decoder) PreserveTags() {
+	dec.d.preserveTags = true
+}
+
+// RequireShortestInts causes the Decoder to return an error
+// when integers, lengths or tag numbers are not encoded in the shortest form.
+func (dec *Decoder) RequireShortestInts() {
+	dec.d.requireShortestInts = true
+}
+
+// StripBOM causes the Decoder to remove a leading byte order mark from decoded text strings.
+// See Options.StripBOM.
+func (dec *Decoder) StripBOM() {
+	dec.d.stripBOM = true
+}
+
+// NormalizeText causes the Decoder to normalize decoded text strings with n.
+// See Options.NormalizeText.
+func (dec *Decoder) NormalizeText(n TextNormalizer) {
+	dec.d.normalizeText = n
+}
+
+// AllowIntegralFloatToInt allows decoding floats that have no fractional part into integer types.
+// See Options.AllowIntegralFloatToInt.
+func (dec *Decoder) AllowIntegralFloatToInt() {
+	dec.d.allowIntegralFloatToInt = true
+}
+
+// AllowIntToFloat allows decoding integers into float types.
+// See Options.AllowIntToFloat for the precision.
+func (dec *Decoder) AllowIntToFloat() {
+	dec.d.allowIntToFloat = true
+}
+
+// AllowDatetimeToEpoch allows decoding date/time strings into integer and float types.
+// See Options.AllowDatetimeToEpoch.
+func (dec *Decoder) AllowDatetimeToEpoch() {
+	dec.d.allowDatetimeToEpoch = true
+}
+
+// AllowNullToZero allows decoding null into types that can't be nil as their zero values.
+// See Options.AllowNullToZero.
+func (dec *Decoder) AllowNullToZero() {
+	dec.d.allowNullToZero = true
+}
+
+// AllowBoolCoercion allows decoding booleans into integer and string types.
+// See Options.AllowBoolCoercion.
+func (dec *Decoder) AllowBoolCoercion() {
+	dec.d.allowBoolCoercion = true
+}
+
+// InternKeys causes the Decoder to share the strings of the same text string map keys.
+// See Options.InternKeys.
+func (dec *Decoder) InternKeys() {
+	dec.d.internKeys = true
+}
+
+// SetFieldResolver sets the function that resolves the fields of structs for the decoded map keys.
+// See Options.FieldResolver.
+func (dec *Decoder) SetFieldResolver(fn FieldResolver) {
+	dec.d.fieldResolver = fn
+}
+
+// AllowLeapSeconds allows the leap second "60" in date/time strings.
+// See Options.AllowLeapSeconds.
+func (dec *Decoder) AllowLeapSeconds() {
+	dec.d.allowLeapSeconds = true
+}
+
+// ReplaceInvalidUTF8 causes the Decoder to replace invalid UTF-8 sequences in decoded text strings with U+FFFD.
+// See Options.ReplaceInvalidUTF8.
+func (dec *Decoder) ReplaceInvalidUTF8() {
+	dec.d.replaceInvalidUTF8 = true
+}
+
+// SetHook sets the function that inspects or replaces each value decoded into an interface type.
+// See DecodeHook.
+func (dec *Decoder) SetHook(fn DecodeHook) {
+	dec.d.hook = fn
+}
+
+// RequireExactArrayLength rejects the arrays decoded into Go arrays that have a different length.
+// See Options.RequireExactArrayLength.
+func (dec *Decoder) RequireExactArrayLength() {
+	dec.d.requireExactArrayLength = true
+}
+
+// CaptureRawFields causes the Decoder to record the raw CBOR encodings of the struct fields in m,
+// e.g. for audits, when it decodes a top-level map into a struct.
+// The keys of m are the Go names of the matched fields, and the values are copies of
+// the encodings of the field values, including the tags of the expected conversions.
+// m is cleared at the beginning of each call of Decode.
+// Pass nil to stop recording.
+func (dec *Decoder) CaptureRawFields(m map[string]RawMessage) {
+	dec.d.rawFields = m
+}
+
+// UseDefaultOptions replaces the options of the Decoder with the options set by SetDefaultDecodeOptions.
+// The options set to the Decoder before the call are discarded.
+func (dec *Decoder) UseDefaultOptions() {
+	DefaultDecodeOptions().set(&dec.d)
+
+	// RegisterTagType must not modify the map shared with the default options.
+	dec.d.tagTypes = maps.Clone(dec.d.tagTypes)
+}
+
+// RegisterTagType registers the type of v as the concrete type of the tag number n.
+// See Options.TagTypes.
+func (dec *Decoder) RegisterTagType(n TagNumber, v any) {
+	if dec.d.tagTypes == nil {
+		dec.d.tagTypes = make(map[TagNumber]reflect.Type)
+	}
+	dec.d.tagTypes[n] = reflect.TypeOf(v)
+}
+
+// readValue reads the next CBOR value into the buffer and returns its length.
+// It returns io.EOF only if the input ends at a value boundary,
+// and io.ErrUnexpectedEOF if the input ends in the middle of a value.
 func (dec *Decoder) readValue() (n int, err error) {
 	for {
 		dec.d.init(dec.buf[dec.scanp:])
-		err := dec.d.checkWellFormedChild()
-		if err == nil {
+		scanErr := dec.d.checkWellFormedChild()
+		if scanErr == nil {
 			return dec.d.off, nil
 		}
+		if _, ok := scanErr.(*SyntaxError); ok {
+			// More data doesn't fix syntax errors.
+			return 0, scanErr
+		}
 
-		// More data is needed and there was no read error.
-		if err := dec.refill(); err != nil {
+		// Did the last read have an error?
+		// Delayed until now to allow buffer scan.
+		if err != nil {
+			if err == io.EOF && len(dec.buf) > dec.scanp {
+				return 0, io.ErrUnexpectedEOF
+			}
 			return 0, err
 		}
+
+		// More data is needed.
+		err = dec.refill()
 	}
 }
 
@@ -80,10 +312,47 @@ func (dec *Decoder) refill() error {
 	return err
 }
 
+// A FramedDecoder reads and decodes length-prefixed CBOR values from an input stream.
+// Each value is prefixed by its length in bytes as a 4-byte big-endian unsigned integer.
+type FramedDecoder struct {
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+// NewFramedDecoder returns a new decoder that reads length-prefixed CBOR values from r.
+func NewFramedDecoder(r io.Reader) *FramedDecoder {
+	return &FramedDecoder{r: r}
+}
+
+// Decode reads the next length-prefixed CBOR value from its input and stores it in the
+// value pointed to by v.
+// It returns io.EOF if there are no more frames,
+// and io.ErrUnexpectedEOF if the input ends in the middle of a frame.
+func (dec *FramedDecoder) Decode(v any) error {
+	var head [4]byte
+	if _, err := io.ReadFull(dec.r, head[:]); err != nil {
+		return err
+	}
+	n := int64(binary.BigEndian.Uint32(head[:]))
+
+	// don't allocate the buffer by the length in the prefix,
+	// because it may be very large.
+	dec.buf.Reset()
+	m, err := dec.buf.ReadFrom(io.LimitReader(dec.r, n))
+	if err != nil {
+		return err
+	}
+	if m != n {
+		return io.ErrUnexpectedEOF
+	}
+	return Unmarshal(dec.buf.Bytes(), v)
+}
+
 // An Encoder writes CBOR to an output stream.
 type Encoder struct {
-	w   io.Writer
-	err error
+	w    io.Writer
+	err  error
+	opts Options
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -97,7 +366,7 @@ func (enc *Encoder) Encode(v any) error {
 		return enc.err
 	}
 
-	data, err := Marshal(v)
+	data, err := enc.opts.Marshal(v)
 	if err != nil {
 		enc.err = err
 		return err
@@ -106,3 +375,74 @@ func (enc *Encoder) Encode(v any) error {
 	_, err = enc.w.Write(data)
 	return err
 }
+
+// SetFloatMode specifies how to encode floating-point numbers.
+func (enc *Encoder) SetFloatMode(mode FloatMode) {
+	enc.opts.FloatMode = mode
+}
+
+// SetMapKeySort specifies how to sort the keys of maps.
+// See Options.MapKeySort.
+func (enc *Encoder) SetMapKeySort(sort MapKeySort) {
+	enc.opts.MapKeySort = sort
+}
+
+// SetEnumAsString specifies whether to encode integer types implementing fmt.Stringer as text strings.
+// See Options.EnumAsString.
+func (enc *Encoder) SetEnumAsString(on bool) {
+	enc.opts.EnumAsString = on
+}
+
+// SetStructMode specifies how to encode structs.
+// See Options.StructMode.
+func (enc *Encoder) SetStructMode(mode StructMode) {
+	enc.opts.StructMode = mode
+}
+
+// SetComplexMode specifies how to encode complex numbers.
+// See Options.ComplexMode.
+func (enc *Encoder) SetComplexMode(mode ComplexMode) {
+	enc.opts.ComplexMode = mode
+}
+
+// SetDurationMode specifies how to encode time.Duration values.
+// See Options.DurationMode.
+func (enc *Encoder) SetDurationMode(mode DurationMode) {
+	enc.opts.DurationMode = mode
+}
+
+// SetTimeMode specifies how to encode time.Time values.
+// See Options.TimeMode.
+func (enc *Encoder) SetTimeMode(mode TimeMode) {
+	enc.opts.TimeMode = mode
+}
+
+// SetTimeLocation specifies the location of time.Time values encoded in TimeModeRFC3339.
+// See Options.TimeLocation.
+func (enc *Encoder) SetTimeLocation(loc *time.Location) {
+	enc.opts.TimeLocation = loc
+}
+
+// SetTimePrecision specifies the precision of time.Time values encoded in TimeModeRFC3339.
+// See Options.TimePrecision.
+func (enc *Encoder) SetTimePrecision(d time.Duration) {
+	enc.opts.TimePrecision = d
+}
+
+// SetHomogeneousArrayTag specifies whether to encode Go slices and arrays as homogeneous arrays (tag number 41).
+// See Options.HomogeneousArrayTag.
+func (enc *Encoder) SetHomogeneousArrayTag(on bool) {
+	enc.opts.HomogeneousArrayTag = on
+}
+
+// SetStringerAsText specifies whether to encode the values implementing error or fmt.Stringer as text strings.
+// See Options.StringerAsText.
+func (enc *Encoder) SetStringerAsText(on bool) {
+	enc.opts.StringerAsText = on
+}
+
+// SetValidateMarshalerOutput specifies whether to check that the outputs of MarshalCBOR are well-formed.
+// See Options.ValidateMarshalerOutput.
+func (enc *Encoder) SetValidateMarshalerOutput(on bool) {
+	enc.opts.ValidateMarshalerOutput = on
+}
@@ -3,7 +3,14 @@ package cbor
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -43,6 +50,86 @@ func TestEncoder(t *testing.T) {
 	}
 }
 
+func TestEncoder_SetFloatMode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetFloatMode(FloatModeFloat64Only)
+	if err := enc.Encode(1.0); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xfb, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_SetMapKeySort(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetMapKeySort(MapKeySortByValue)
+	if err := enc.Encode(map[int]int{-1: 0, 10: 0, 100: 0}); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xa3, 0x20, 0x00, 0x0a, 0x00, 0x18, 0x64, 0x00}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_SetStructMode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetStructMode(StructModeArray)
+	if err := enc.Encode(struct{ B, A int }{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x82, 0x01, 0x02}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_SetComplexMode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetComplexMode(ComplexModeArray)
+	if err := enc.Encode(complex(1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x82, 0xf9, 0x3c, 0x00, 0xf9, 0x40, 0x00}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_SetDurationMode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetDurationMode(DurationModeSeconds)
+	if err := enc.Encode(1500 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xf9, 0x3e, 0x00}
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncoder_SetTimeMode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetTimeMode(TimeModeRFC3339)
+	enc.SetTimeLocation(time.FixedZone("Asia/Tokyo", 9*60*60))
+	enc.SetTimePrecision(time.Second)
+	if err := enc.Encode(time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{0xc0, 0x78, 0x19}, "2013-03-22T05:04:00+09:00"...)
+	if diff := cmp.Diff(want, buf.Bytes()); diff != "" {
+		t.Errorf("Encode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestDecoder(t *testing.T) {
 	for i := 0; i < len(streamEncoded); i++ {
 		r := bytes.NewReader(streamEncoded[i])
@@ -62,6 +149,84 @@ func TestDecoder(t *testing.T) {
 	}
 }
 
+func TestDecoder_TypeError(t *testing.T) {
+	// ("a", 1)
+	input := []byte{0x61, 0x61, 0x01}
+	dec := NewDecoder(bytes.NewReader(input))
+
+	// the type error is reported, and the value is consumed.
+	var v int
+	err := dec.Decode(&v)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+	}
+
+	// the decoder continues with the next value.
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Errorf("Decode() = %d, want 1", v)
+	}
+	if err := dec.Decode(&v); err != io.EOF {
+		t.Errorf("Decode() error = %v, want io.EOF", err)
+	}
+}
+
+func TestDecoder_EOF(t *testing.T) {
+	readers := []struct {
+		name string
+		r    func([]byte) io.Reader
+	}{
+		{"bytes.Reader", func(b []byte) io.Reader { return bytes.NewReader(b) }},
+		{"OneByteReader", func(b []byte) io.Reader { return iotest.OneByteReader(bytes.NewReader(b)) }},
+		{"DataErrReader", func(b []byte) io.Reader { return iotest.DataErrReader(bytes.NewReader(b)) }},
+	}
+
+	for _, rr := range readers {
+		t.Run(rr.name, func(t *testing.T) {
+			t.Run("empty", func(t *testing.T) {
+				dec := NewDecoder(rr.r([]byte{}))
+				var v any
+				if err := dec.Decode(&v); err != io.EOF {
+					t.Errorf("Decode() error = %v, want io.EOF", err)
+				}
+			})
+
+			t.Run("value boundary", func(t *testing.T) {
+				dec := NewDecoder(rr.r([]byte{0x01, 0x82, 0x02, 0x03}))
+				var got []any
+				for {
+					var v any
+					err := dec.Decode(&v)
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, v)
+				}
+				want := []any{int64(1), []any{int64(2), int64(3)}}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+				}
+			})
+
+			t.Run("truncated", func(t *testing.T) {
+				dec := NewDecoder(rr.r([]byte{0x01, 0x82, 0x02}))
+				var v any
+				if err := dec.Decode(&v); err != nil {
+					t.Fatal(err)
+				}
+				if err := dec.Decode(&v); err != io.ErrUnexpectedEOF {
+					t.Errorf("Decode() error = %v, want io.ErrUnexpectedEOF", err)
+				}
+			})
+		})
+	}
+}
+
 func TestDecoder_UseAnyKey(t *testing.T) {
 	t.Run("number key", func(t *testing.T) {
 		input := []byte{0xa2, 0x01, 0x02, 0x03, 0x04}
@@ -116,6 +281,30 @@ func TestDecoder_UseAnyKey(t *testing.T) {
 		}
 	})
 
+	t.Run("integer and float keys", func(t *testing.T) {
+		input := []byte{
+			0xa2,             // two elements map
+			0x01, 0x61, 0x61, // 1: "a"
+			0xf9, 0x3c, 0x00, 0x61, 0x62, // 1.0: "b"
+		}
+		want := map[any]any{
+			int64(1):     "a",
+			float64(1.0): "b",
+		}
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.UseAnyKey()
+		var got any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("byte strings keys", func(t *testing.T) {
 		input := []byte{
 			0xa1,                         // one element map
@@ -180,6 +369,895 @@ func TestDecoder_UseInteger(t *testing.T) {
 			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("typed destinations", func(t *testing.T) {
+		// {"A": -1, "B": -18446744073709551616}
+		input := []byte{0xa2, 0x61, 0x41, 0x20, 0x61, 0x42, 0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		type foo struct {
+			A int64
+			B Integer
+		}
+		want := foo{A: -1, B: Integer{Sign: true, Value: 18446744073709551615}}
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.UseInteger()
+		var got foo
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("keyasint", func(t *testing.T) {
+		input := []byte{0xa3, 0x01, 0x01, 0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02, 0x20, 0x03}
+		want := FooJ{A: 1, B: 2, C: 3}
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.UseInteger()
+		var got FooJ
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestDecoder_PreserveTags(t *testing.T) {
+	t.Run("known tag", func(t *testing.T) {
+		input := []byte{
+			0xc1,                         // tag 1: epoch-based date/time
+			0x1a, 0x51, 0x4b, 0x67, 0xb0, // 1363896240
+		}
+		want := Tag{Number: 1, Content: int64(1363896240)}
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.PreserveTags()
+		var got any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("nested tags", func(t *testing.T) {
+		input := []byte{
+			0xd9, 0xd9, 0xf7, // tag 55799: self-described CBOR
+			0x82,       // array of length 2
+			0xc2,       // tag 2: positive bignum
+			0x41, 0x01, // h'01'
+			0xd8, 0x20, // tag 32: URI
+			0x61, 0x61, // "a"
+		}
+		want := Tag{
+			Number: 55799,
+			Content: []any{
+				Tag{Number: 2, Content: []byte{0x01}},
+				Tag{Number: 32, Content: "a"},
+			},
+		}
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.PreserveTags()
+		var got any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("typed target", func(t *testing.T) {
+		input := []byte{
+			0xc1,                         // tag 1: epoch-based date/time
+			0x1a, 0x51, 0x4b, 0x67, 0xb0, // 1363896240
+		}
+		want := time.Unix(1363896240, 0)
+
+		r := bytes.NewReader(input)
+		dec := NewDecoder(r)
+		dec.PreserveTags()
+		var got time.Time
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		if !got.Equal(want) {
+			t.Errorf("Decode() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestDecoder_RequireShortestInts(t *testing.T) {
+	t.Run("shortest", func(t *testing.T) {
+		input := []byte{
+			0x83,       // array of length 3
+			0x18, 0x18, // 24
+			0x38, 0xff, // -256
+			0x78, 0x18, // text string of length 24
+			'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a',
+			'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a', 'a',
+		}
+		want := []any{int64(24), int64(-256), "aaaaaaaaaaaaaaaaaaaaaaaa"}
+
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.RequireShortestInts()
+		var got any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		input  []byte
+		offset int64
+	}{
+		{"positive integer", []byte{0x18, 0x0a}, 0},
+		{"negative integer", []byte{0x39, 0x00, 0xff}, 0},
+		{"uint32", []byte{0x1a, 0x00, 0x00, 0xff, 0xff}, 0},
+		{"uint64", []byte{0x1b, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}, 0},
+		{"byte string length", []byte{0x58, 0x01, 0x00}, 0},
+		{"array length", []byte{0x82, 0x00, 0x98, 0x00}, 2},
+		{"map length", []byte{0xb8, 0x00}, 0},
+		{"tag number", []byte{0xd8, 0x01, 0x00}, 0},
+		{"indefinite-length chunk", []byte{0x7f, 0x78, 0x01, 'a', 0xff}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.RequireShortestInts()
+			var got any
+			err := dec.Decode(&got)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Decode() should return SyntaxError, got %v", err)
+			}
+			if se.Offset != tt.offset {
+				t.Errorf("unexpected offset: got %d, want %d", se.Offset, tt.offset)
+			}
+
+			// it is valid without RequireShortestInts.
+			if err := Unmarshal(tt.input, &got); err != nil {
+				t.Errorf("Unmarshal() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_AllowIntegralFloatToInt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		ptr   any
+		want  any
+	}{
+		{"float16 to int64", []byte{0xf9, 0x49, 0x00}, new(int64), ptr(int64(10))},
+		{"float32 to int", []byte{0xfa, 0xc7, 0x00, 0x00, 0x00}, new(int), ptr(-32768)},
+		{"float64 to uint8", []byte{0xfb, 0x40, 0x6f, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00}, new(uint8), ptr(uint8(255))},
+		{"negative zero to uint", []byte{0xf9, 0x80, 0x00}, new(uint), ptr(uint(0))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.AllowIntegralFloatToInt()
+			if err := dec.Decode(tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.ptr); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without AllowIntegralFloatToInt.
+			err := Unmarshal(tt.input, tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name  string
+		input []byte
+		ptr   any
+	}{
+		{"fractional", []byte{0xf9, 0x3e, 0x00}, new(int)},                                      // 1.5
+		{"overflow int8", []byte{0xf9, 0x58, 0x00}, new(int8)},                                  // 128.0
+		{"negative to uint", []byte{0xf9, 0xbc, 0x00}, new(uint)},                               // -1.0
+		{"too large", []byte{0xfb, 0x43, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, new(int64)}, // 2^63
+		{"NaN", []byte{0xf9, 0x7e, 0x00}, new(int)},
+		{"infinity", []byte{0xf9, 0x7c, 0x00}, new(int)},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.AllowIntegralFloatToInt()
+			err := dec.Decode(tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_AllowIntToFloat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		ptr   any
+		want  any
+	}{
+		{"positive to float64", []byte{0x0a}, new(float64), ptr(10.0)},
+		{"negative to float32", []byte{0x39, 0x01, 0xf3}, new(float32), ptr(float32(-500))},
+		{"2^53+1 is rounded", []byte{0x1b, 0x00, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, new(float64), ptr(9007199254740992.0)},
+		{"min negative", []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, new(float64), ptr(-18446744073709551616.0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.AllowIntToFloat()
+			if err := dec.Decode(tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.ptr); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without AllowIntToFloat.
+			err := Unmarshal(tt.input, tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_AllowDatetimeToEpoch(t *testing.T) {
+	input := append([]byte{0xc0, 0x76}, "2013-03-21T20:04:00.5Z"...)
+	tests := []struct {
+		name string
+		ptr  any
+		want any
+	}{
+		{"int64", new(int64), ptr(int64(1363896240))},
+		{"uint32", new(uint32), ptr(uint32(1363896240))},
+		{"float64", new(float64), ptr(1363896240.5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(input))
+			dec.AllowDatetimeToEpoch()
+			if err := dec.Decode(tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.ptr); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without AllowDatetimeToEpoch.
+			err := Unmarshal(input, tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.AllowDatetimeToEpoch()
+		var got int16
+		err := dec.Decode(&got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Decode() error = %v, want *SemanticError", err)
+		}
+	})
+}
+
+func TestDecoder_AllowNullToZero(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		ptr   any
+		want  any
+	}{
+		{"int", []byte{0xf6}, ptr(42), ptr(0)},
+		{"string", []byte{0xf6}, ptr("foo"), ptr("")},
+		{"bool", []byte{0xf6}, ptr(true), ptr(false)},
+		{
+			"struct fields",
+			[]byte{0xa2, 0x61, 0x41, 0xf6, 0x61, 0x42, 0xf6},
+			&FooA{A: 42, B: "foo"},
+			&FooA{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.AllowNullToZero()
+			if err := dec.Decode(tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.ptr); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without AllowNullToZero.
+			err := Unmarshal(tt.input, tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+
+	t.Run("undefined", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0xf7}))
+		dec.AllowNullToZero()
+		var got int
+		err := dec.Decode(&got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestDecoder_AllowBoolCoercion(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		ptr   any
+		want  any
+	}{
+		{"true to int", []byte{0xf5}, new(int), ptr(1)},
+		{"false to int", []byte{0xf4}, ptr(42), ptr(0)},
+		{"true to uint8", []byte{0xf5}, new(uint8), ptr(uint8(1))},
+		{"false to uint64", []byte{0xf4}, ptr(uint64(42)), ptr(uint64(0))},
+		{"true to string", []byte{0xf5}, new(string), ptr("true")},
+		{"false to string", []byte{0xf4}, new(string), ptr("false")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.AllowBoolCoercion()
+			if err := dec.Decode(tt.ptr); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, tt.ptr); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without AllowBoolCoercion.
+			err := Unmarshal(tt.input, tt.ptr)
+			if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+			}
+		})
+	}
+
+	t.Run("float", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0xf5}))
+		dec.AllowBoolCoercion()
+		var got float64
+		err := dec.Decode(&got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestDecoder_InternKeys(t *testing.T) {
+	// [{"abc": 1}, {"abc": 2}]
+	input := []byte{0x82, 0xa1, 0x63, 0x61, 0x62, 0x63, 0x01, 0xa1, 0x63, 0x61, 0x62, 0x63, 0x02}
+
+	keyData := func(m map[string]any) *byte {
+		for k := range m {
+			return unsafe.StringData(k)
+		}
+		return nil
+	}
+
+	t.Run("interned", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.InternKeys()
+		var got []map[string]any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := []map[string]any{{"abc": int64(1)}, {"abc": int64(2)}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+		if keyData(got[0]) != keyData(got[1]) {
+			t.Error("the keys should share the same string")
+		}
+	})
+
+	t.Run("normalized", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.InternKeys()
+		dec.NormalizeText(upperNormalizer{})
+		var got []map[string]any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		want := []map[string]any{{"ABC": int64(1)}, {"ABC": int64(2)}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+		if keyData(got[0]) != keyData(got[1]) {
+			t.Error("the keys should share the same string")
+		}
+	})
+
+	t.Run("not interned", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(input))
+		var got []map[string]any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if keyData(got[0]) == keyData(got[1]) {
+			t.Error("the keys should not share the same string without InternKeys")
+		}
+	})
+}
+
+func TestDecoder_SetFieldResolver(t *testing.T) {
+	// {"alg": 1, "kit": h'01', 2: 3}
+	input := []byte{0xa3, 0x63, 0x61, 0x6c, 0x67, 0x01, 0x63, 0x6b, 0x69, 0x74, 0x41, 0x01, 0x02, 0x03}
+
+	var keys []any
+	resolver := func(typ reflect.Type, key any) (string, bool) {
+		if typ != reflect.TypeOf(FooB{}) {
+			t.Errorf("unexpected type: %v", typ)
+		}
+		keys = append(keys, key)
+		switch key {
+		case "alg":
+			return "Alg", true
+		case "kit":
+			return "Unknown", true
+		}
+		return "", false
+	}
+
+	dec := NewDecoder(bytes.NewReader(input))
+	dec.SetFieldResolver(resolver)
+	var got FooB
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	// "kit" is resolved to no field, and 2 falls back to the keys of the fields.
+	want := FooB{Alg: 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]any{"alg", "kit", int64(2)}, keys); diff != "" {
+		t.Errorf("keys mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("indefinite-length map", func(t *testing.T) {
+		// {_ "alg": 1}
+		input := []byte{0xbf, 0x63, 0x61, 0x6c, 0x67, 0x01, 0xff}
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.SetFieldResolver(resolver)
+		var got FooB
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(FooB{Alg: 1}, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestDecoder_SetHook(t *testing.T) {
+	errTooLong := errors.New("too long")
+	hook := func(v any) (any, error) {
+		switch v := v.(type) {
+		case string:
+			if len(v) > 3 {
+				return nil, errTooLong
+			}
+			return strings.ToUpper(v), nil
+		case Tag:
+			if v.Number == 100 {
+				return "tagged", nil
+			}
+		}
+		return v, nil
+	}
+
+	t.Run("transform", func(t *testing.T) {
+		// {"a": ["b", 1, 100("c")], "x": ["y"]}
+		input := []byte{
+			0xa2,
+			0x61, 0x61, 0x83, 0x61, 0x62, 0x01, 0xd8, 0x64, 0x61, 0x63,
+			0x61, 0x78, 0x81, 0x61, 0x79,
+		}
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.PreserveTags()
+		dec.SetHook(hook)
+		var got any
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		// the keys are not passed to the hook.
+		want := map[string]any{
+			"a": []any{"B", int64(1), "tagged"},
+			"x": []any{"Y"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("struct field", func(t *testing.T) {
+		type S struct {
+			A any
+			B string
+		}
+		// {"A": "a", "B": "b"}
+		input := []byte{0xa2, 0x61, 0x41, 0x61, 0x61, 0x61, 0x42, 0x61, 0x62}
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.SetHook(hook)
+		var got S
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		// the hook is called only for the values decoded into interfaces.
+		if diff := cmp.Diff(S{A: "A", B: "b"}, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		// ["a", "long"]
+		input := []byte{0x82, 0x61, 0x61, 0x64, 0x6c, 0x6f, 0x6e, 0x67}
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.SetHook(hook)
+		var got any
+		if err := dec.Decode(&got); !errors.Is(err, errTooLong) {
+			t.Errorf("Decode() error = %v, want %v", err, errTooLong)
+		}
+	})
+
+	t.Run("not assignable", func(t *testing.T) {
+		// "a"
+		input := []byte{0x61, 0x61}
+		dec := NewDecoder(bytes.NewReader(input))
+		dec.SetHook(func(v any) (any, error) { return 1, nil })
+		var got fmt.Stringer
+		err := dec.Decode(&got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestDecoder_AllowLeapSeconds(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want time.Time
+	}{
+		{
+			"leap second",
+			"2016-12-31T23:59:60Z",
+			time.Date(2016, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		},
+		{
+			"leap second with fraction",
+			"2016-12-31T23:59:60.5Z",
+			time.Date(2016, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		},
+		{
+			"leap second with offset",
+			"2017-01-01T08:59:60+09:00",
+			time.Date(2016, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := Marshal(Tag{Number: 0, Content: tt.s})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dec := NewDecoder(bytes.NewReader(input))
+			dec.AllowLeapSeconds()
+			var got time.Time
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+
+			// it is an error without AllowLeapSeconds.
+			err = Unmarshal(input, &got)
+			if _, ok := err.(*SemanticError); !ok {
+				t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name string
+		s    string
+	}{
+		{"invalid second", "2016-12-31T23:59:61Z"},
+		{"not at the end of a minute", "2016-12-31T23:58:60Z"},
+		{"not at the end of a UTC day", "2016-12-31T23:59:60+09:00"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := Marshal(Tag{Number: 0, Content: tt.s})
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := NewDecoder(bytes.NewReader(input))
+			dec.AllowLeapSeconds()
+			var got time.Time
+			err = dec.Decode(&got)
+			if _, ok := err.(*SemanticError); !ok {
+				t.Errorf("Decode() error = %v, want *SemanticError", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_ReplaceInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  any
+	}{
+		{"invalid byte", []byte{0x63, 0x61, 0xff, 0x62}, "a\ufffdb"},
+		{"truncated sequence", []byte{0x63, 0x61, 0xe3, 0x81}, "a\ufffd"},
+		{"indefinite-length string", []byte{0x7f, 0x61, 0x61, 0x61, 0xff, 0xff}, "a\ufffd"},
+		{"map key", []byte{0xa1, 0x61, 0xff, 0x01}, map[string]any{"\ufffd": int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.ReplaceInvalidUTF8()
+			var got any
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			// it is an error without ReplaceInvalidUTF8.
+			if err := Unmarshal(tt.input, &got); err == nil {
+				t.Error("Unmarshal() error = nil, want error")
+			}
+		})
+	}
+
+	t.Run("valid string", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x63, 0xe3, 0x81, 0x82}))
+		dec.ReplaceInvalidUTF8()
+		var got string
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != "\u3042" {
+			t.Errorf("Decode() = %q, want %q", got, "\u3042")
+		}
+	})
+}
+
+func TestDecoder_RequireExactArrayLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  [3]int
+		ok    bool
+	}{
+		{"exact", []byte{0x83, 0x01, 0x02, 0x03}, [3]int{1, 2, 3}, true},
+		{"short", []byte{0x82, 0x01, 0x02}, [3]int{1, 2, 0}, false},
+		{"long", []byte{0x84, 0x01, 0x02, 0x03, 0x04}, [3]int{1, 2, 3}, false},
+		{"indefinite-length exact", []byte{0x9f, 0x01, 0x02, 0x03, 0xff}, [3]int{1, 2, 3}, true},
+		{"indefinite-length short", []byte{0x9f, 0x01, 0xff}, [3]int{1, 0, 0}, false},
+		{"indefinite-length long", []byte{0x9f, 0x01, 0x02, 0x03, 0x04, 0xff}, [3]int{1, 2, 3}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tt.input))
+			dec.RequireExactArrayLength()
+			var got [3]int
+			err := dec.Decode(&got)
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if _, ok := err.(*UnmarshalTypeError); !ok {
+				t.Errorf("Decode() error = %v, want *UnmarshalTypeError", err)
+			}
+			// the elements are decoded even if the length is different.
+			if got != tt.want {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+
+			// any length is accepted without RequireExactArrayLength.
+			if err := Unmarshal(tt.input, &got); err != nil {
+				t.Errorf("Unmarshal() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_CaptureRawFields(t *testing.T) {
+	type S struct {
+		A int
+		B FooA
+		C []byte `cbor:",base64"`
+	}
+	// {"A": 1, "B": {"A": 2}, "C": 22(h'01'), "D": 3}
+	input := []byte{
+		0xa4,
+		0x61, 0x41, 0x01,
+		0x61, 0x42, 0xa1, 0x61, 0x41, 0x02,
+		0x61, 0x43, 0xd6, 0x41, 0x01,
+		0x61, 0x44, 0x03,
+	}
+
+	raw := map[string]RawMessage{"Z": {0x00}}
+	dec := NewDecoder(bytes.NewReader(append(input, 0x9f, 0x01, 0xff)))
+	dec.CaptureRawFields(raw)
+	var got S
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(S{A: 1, B: FooA{A: 2}, C: []byte{0x01}}, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the fields of the nested struct and the unknown keys are not recorded.
+	want := map[string]RawMessage{
+		"A": {0x01},
+		"B": {0xa1, 0x61, 0x41, 0x02},
+		"C": {0xd6, 0x41, 0x01},
+	}
+	if diff := cmp.Diff(want, raw); diff != "" {
+		t.Errorf("raw fields mismatch (-want +got):\n%s", diff)
+	}
+
+	// the map is cleared for the next value.
+	var a []int
+	if err := dec.Decode(&a); err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 0 {
+		t.Errorf("raw fields = %v, want empty", raw)
+	}
+}
+
+func TestDecoder_UseDefaultOptions(t *testing.T) {
+	input := []byte{0xa1, 0x01, 0x02} // {1: 2}
+
+	SetDefaultDecodeOptions(Options{
+		UseAnyKey: true,
+		TagTypes:  map[TagNumber]reflect.Type{},
+	})
+	t.Cleanup(func() {
+		SetDefaultDecodeOptions(Options{})
+	})
+
+	// Decoder doesn't use the default options unless it opts in.
+	dec := NewDecoder(bytes.NewReader(input))
+	var got any
+	if err := dec.Decode(&got); err == nil {
+		t.Errorf("Decode() error = nil, want error")
+	}
+
+	dec = NewDecoder(bytes.NewReader(input))
+	dec.UseDefaultOptions()
+	dec.RegisterTagType(1000, circle{})
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[any]any{int64(1): int64(2)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	// RegisterTagType doesn't modify the default options.
+	if n := len(DefaultDecodeOptions().TagTypes); n != 0 {
+		t.Errorf("len(DefaultDecodeOptions().TagTypes) = %d, want 0", n)
+	}
+}
+
+type shape interface {
+	Area() float64
+}
+
+type square struct {
+	Side float64
+}
+
+func (s square) Area() float64 { return s.Side * s.Side }
+
+type circle struct {
+	R float64
+}
+
+func (c *circle) Area() float64 { return 3 * c.R * c.R }
+
+func TestDecoder_RegisterTagType(t *testing.T) {
+	input, err := Marshal([]Tag{
+		{Number: 40000, Content: square{Side: 2}},
+		{Number: 40001, Content: circle{R: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(input))
+	dec.RegisterTagType(40000, square{})
+	dec.RegisterTagType(40001, (*circle)(nil))
+	var got []shape
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []shape{square{Side: 2}, &circle{R: 1}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("not registered", func(t *testing.T) {
+		var got []shape
+		if err := Unmarshal(input, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+
+	t.Run("not implemented", func(t *testing.T) {
+		opts := Options{TagTypes: map[TagNumber]reflect.Type{
+			40000: reflect.TypeOf(square{}),
+			40001: reflect.TypeOf(circle{}), // *circle implements shape, but circle doesn't.
+		}}
+		var got []shape
+		err := opts.Unmarshal(input, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestDecoder_StripBOM(t *testing.T) {
+	// (_ "\ufeff", "abc")
+	input := []byte{0x7f, 0x63, 0xef, 0xbb, 0xbf, 0x63, 0x61, 0x62, 0x63, 0xff}
+
+	dec := NewDecoder(bytes.NewReader(input))
+	dec.StripBOM()
+	dec.NormalizeText(upperNormalizer{})
+	var got string
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != "ABC" {
+		t.Errorf("Decode() = %q, want %q", got, "ABC")
+	}
 }
 
 func TestDecoder_SemanticError(t *testing.T) {
@@ -221,6 +1299,64 @@ func TestDecoder_SemanticError(t *testing.T) {
 		}
 	})
 
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{
+		{
+			"NaN keys",
+			[]byte{
+				0xa2,                   // map of length 2
+				0xf9, 0x7e, 0x00, 0x01, // NaN: 1
+				0xf9, 0x7e, 0x00, 0x02, // NaN: 2
+			},
+		},
+		{
+			"NaN keys with different payloads",
+			[]byte{
+				0xa2,                   // map of length 2
+				0xf9, 0x7e, 0x00, 0x01, // NaN: 1
+				0xfa, 0x7f, 0xc0, 0x00, 0x01, 0x02, // NaN: 2
+			},
+		},
+		{
+			"NaN in array key",
+			[]byte{
+				0xa1,                         // map of length 1
+				0x81, 0xf9, 0x7e, 0x00, 0x01, // [NaN]: 1
+			},
+		},
+		{
+			"duplicated float keys",
+			[]byte{
+				0xa2,                   // map of length 2
+				0xf9, 0x3c, 0x00, 0x01, // 1.0: 1
+				0xfa, 0x3f, 0x80, 0x00, 0x00, 0x02, // 1.0: 2
+			},
+		},
+		{
+			"zero and negative zero keys",
+			[]byte{
+				0xa2,                   // map of length 2
+				0xf9, 0x00, 0x00, 0x01, // 0.0: 1
+				0xf9, 0x80, 0x00, 0x02, // -0.0: 2
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.data)
+
+			var v any
+			dec := NewDecoder(r)
+			dec.UseAnyKey()
+			err := dec.Decode(&v)
+			_, ok := err.(*SemanticError)
+			if !ok {
+				t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+			}
+		})
+	}
+
 	t.Run("duplicated indefinite-length map key decoded to any", func(t *testing.T) {
 		data := []byte{
 			0xbf,             // indefinite-length
@@ -240,3 +1376,161 @@ func TestDecoder_SemanticError(t *testing.T) {
 		}
 	})
 }
+
+func TestDecoder_DecodeArrayFunc(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  []any
+	}{
+		{
+			"empty array",
+			[]byte{0x80},
+			[]any{},
+		},
+		{
+			"array",
+			[]byte{0x83, 0x01, 0x61, 0x61, 0xf5},
+			[]any{int64(1), "a", true},
+		},
+		{
+			"array that have 25 elements",
+			[]byte{
+				0x98, 0x19, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a,
+				0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16,
+				0x17, 0x18, 0x18, 0x18, 0x19,
+			},
+			[]any{
+				int64(1), int64(2), int64(3), int64(4), int64(5), int64(6), int64(7), int64(8), int64(9), int64(10),
+				int64(11), int64(12), int64(13), int64(14), int64(15), int64(16), int64(17), int64(18), int64(19), int64(20),
+				int64(21), int64(22), int64(23), int64(24), int64(25),
+			},
+		},
+		{
+			"indefinite-length array",
+			[]byte{0x9f, 0x01, 0x82, 0x02, 0x03, 0xff},
+			[]any{int64(1), []any{int64(2), int64(3)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// read one byte at a time to test refilling the buffer.
+			r := iotest.OneByteReader(bytes.NewReader(tt.input))
+			dec := NewDecoder(r)
+			got := []any{}
+			err := dec.DecodeArrayFunc(func(dec *Decoder) error {
+				var v any
+				if err := dec.Decode(&v); err != nil {
+					return err
+				}
+				got = append(got, v)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DecodeArrayFunc() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeArrayFunc_Error(t *testing.T) {
+	t.Run("not an array", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0xa0}))
+		err := dec.DecodeArrayFunc(func(dec *Decoder) error {
+			return nil
+		})
+		var se *SemanticError
+		if !errors.As(err, &se) {
+			t.Errorf("DecodeArrayFunc() should return SemanticError, got %T", err)
+		}
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{}))
+		err := dec.DecodeArrayFunc(func(dec *Decoder) error {
+			return nil
+		})
+		if err != io.EOF {
+			t.Errorf("DecodeArrayFunc() should return io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("unexpected end", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x9f, 0x01}))
+		err := dec.DecodeArrayFunc(func(dec *Decoder) error {
+			var v any
+			return dec.Decode(&v)
+		})
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("DecodeArrayFunc() should return io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		want := errors.New("some error")
+		dec := NewDecoder(bytes.NewReader([]byte{0x81, 0x01}))
+		err := dec.DecodeArrayFunc(func(dec *Decoder) error {
+			return want
+		})
+		if err != want {
+			t.Errorf("DecodeArrayFunc() should return %v, got %v", want, err)
+		}
+	})
+}
+
+func TestFramedDecoder(t *testing.T) {
+	input := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x01, // 1
+		0x00, 0x00, 0x00, 0x06, 0x65, 0x68, 0x65, 0x6c, 0x6c, 0x6f, // "hello"
+		0x00, 0x00, 0x00, 0x04, 0x83, 0x01, 0x02, 0x03, // [1, 2, 3]
+	}
+	want := []any{int64(1), "hello", []any{int64(1), int64(2), int64(3)}}
+
+	dec := NewFramedDecoder(bytes.NewReader(input))
+	got := []any{}
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFramedDecoder_Error(t *testing.T) {
+	t.Run("truncated length", func(t *testing.T) {
+		dec := NewFramedDecoder(bytes.NewReader([]byte{0x00, 0x00}))
+		var v any
+		if err := dec.Decode(&v); err != io.ErrUnexpectedEOF {
+			t.Errorf("Decode() should return io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("truncated value", func(t *testing.T) {
+		dec := NewFramedDecoder(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x01}))
+		var v any
+		if err := dec.Decode(&v); err != io.ErrUnexpectedEOF {
+			t.Errorf("Decode() should return io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+
+	t.Run("extra data in the frame", func(t *testing.T) {
+		dec := NewFramedDecoder(bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x02, 0x01, 0x02}))
+		var v any
+		var se *SyntaxError
+		if err := dec.Decode(&v); !errors.As(err, &se) {
+			t.Errorf("Decode() should return SyntaxError, got %v", err)
+		}
+	})
+}
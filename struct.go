@@ -24,6 +24,22 @@ type structType struct {
 	toArray bool
 	fields  []field
 	maps    map[any]*field
+
+	// stringMaps is the fields that have text string keys.
+	// It allows to look up the fields without boxing the keys into any.
+	stringMaps map[string]*field
+
+	// names is the fields by their Go names.
+	// It is used to look up the fields resolved by FieldResolver.
+	names map[string]*field
+
+	// arrayFields is the fields in the declaration order.
+	// It is used to encode and decode the struct as an array.
+	arrayFields []field
+
+	// inline is the field tagged with "inline".
+	// It receives all keys not matched to other fields.
+	inline *field
 }
 
 type field struct {
@@ -32,6 +48,67 @@ type field struct {
 	encodedKey []byte
 	omitempty  bool
 	index      []int
+
+	// expected is the tag number of the expected conversion specified by
+	// "base64url", "base64" or "base16" option, or zero if not specified.
+	expected TagNumber
+
+	// timeMode is the encoding of time.Time values specified by "tag=0" or "tag=1" option,
+	// and hasTimeMode reports whether it is specified.
+	// It overrides Options.TimeMode.
+	timeMode    TimeMode
+	hasTimeMode bool
+}
+
+// lookup returns the field for the key.
+func (st *structType) lookup(key structKey) (*field, bool) {
+	if key.isString {
+		f, ok := st.stringMaps[key.str]
+		return f, ok
+	}
+	f, ok := st.maps[key.other]
+	return f, ok
+}
+
+// structKey is a map key decoded for the fields of a struct.
+// Text string keys are held in str without boxing into any.
+type structKey struct {
+	str      string
+	isString bool
+	other    any
+}
+
+// value returns the key as any.
+func (k structKey) value() any {
+	if k.isString {
+		return k.str
+	}
+	return k.other
+}
+
+// structKeySet is a set of structKey to detect duplicate keys.
+// The maps must be initialized by the caller,
+// so that they can be allocated on the stack.
+type structKeySet struct {
+	strings map[string]struct{}
+	others  map[any]struct{}
+}
+
+// add adds the key to the set, and reports whether the key is not in the set yet.
+func (s *structKeySet) add(key structKey) bool {
+	if key.isString {
+		if _, ok := s.strings[key.str]; ok {
+			return false
+		}
+		s.strings[key.str] = struct{}{}
+		return true
+	}
+
+	if _, ok := s.others[key.other]; ok {
+		return false
+	}
+	s.others[key.other] = struct{}{}
+	return true
 }
 
 func cmpFieldKey(a, b field) int {
@@ -40,17 +117,24 @@ func cmpFieldKey(a, b field) int {
 
 func newStructType(t reflect.Type) *structType {
 	var toArray bool
+	var inline *field
 	fields := make([]field, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		tag := f.Tag.Get("cbor")
 		if tag == "-" {
+			// skip the field.
+			// Note that `cbor:"-,"` uses "-" as the key.
 			continue
 		}
 
 		// parse tag
 		var omitempty bool
 		var keyasint bool
+		var isInline bool
+		var expected TagNumber
+		var timeMode TimeMode
+		var hasTimeMode bool
 		name, tag, _ := strings.Cut(tag, ",")
 		for tag != "" {
 			var opt string
@@ -60,6 +144,18 @@ func newStructType(t reflect.Type) *structType {
 				omitempty = true
 			case "keyasint":
 				keyasint = true
+			case "inline":
+				isInline = true
+			case "base64url":
+				expected = tagNumberExpectedBase64URL
+			case "base64":
+				expected = tagNumberExpectedBase64
+			case "base16":
+				expected = tagNumberExpectedBase16
+			case "tag=0":
+				timeMode, hasTimeMode = TimeModeRFC3339, true
+			case "tag=1":
+				timeMode, hasTimeMode = TimeModeEpoch, true
 			case "toarray":
 				if f.Name == "_" {
 					toArray = true
@@ -71,11 +167,23 @@ func newStructType(t reflect.Type) *structType {
 			continue
 		}
 
+		if isInline && f.Type.Kind() == reflect.Map {
+			inline = &field{
+				name:  f.Name,
+				index: f.Index,
+			}
+			continue
+		}
+
 		var key any
 		var encodedKey []byte
 		if keyasint {
 			var err error
 			key, err = strconv.ParseInt(name, 10, 64)
+			if err != nil {
+				// the key may be an unsigned integer beyond int64.
+				key, err = strconv.ParseUint(name, 10, 64)
+			}
 			if err != nil {
 				// TODO: return error
 				panic(err)
@@ -99,28 +207,44 @@ func newStructType(t reflect.Type) *structType {
 		}
 
 		fields = append(fields, field{
-			name:       f.Name,
-			key:        key,
-			encodedKey: encodedKey,
-			omitempty:  omitempty,
-			index:      f.Index,
+			name:        f.Name,
+			key:         key,
+			encodedKey:  encodedKey,
+			omitempty:   omitempty,
+			index:       f.Index,
+			expected:    expected,
+			timeMode:    timeMode,
+			hasTimeMode: hasTimeMode,
 		})
 	}
 
 	// sort fields by encodedKey
+	arrayFields := fields
 	if !toArray {
+		arrayFields = slices.Clone(fields)
 		slices.SortStableFunc(fields, cmpFieldKey)
 	}
 
 	// build maps
 	maps := make(map[any]*field)
+	stringMaps := make(map[string]*field)
+	names := make(map[string]*field)
 	for i := range fields {
-		maps[fields[i].key] = &fields[i]
+		names[fields[i].name] = &fields[i]
+		if key, ok := fields[i].key.(string); ok {
+			stringMaps[key] = &fields[i]
+		} else {
+			maps[fields[i].key] = &fields[i]
+		}
 	}
 
 	return &structType{
-		toArray: toArray,
-		fields:  fields,
-		maps:    maps,
+		toArray:     toArray,
+		fields:      fields,
+		maps:        maps,
+		stringMaps:  stringMaps,
+		names:       names,
+		arrayFields: arrayFields,
+		inline:      inline,
 	}
 }
@@ -15,3 +15,44 @@ type FooC struct {
 	A int
 	B string
 }
+
+type FooD struct {
+	A     int
+	Extra map[string]RawMessage `cbor:",inline"`
+}
+
+type FooE struct {
+	A     int
+	Extra map[any]any `cbor:",inline"`
+}
+
+type FooF struct {
+	A int `cbor:"-"`
+	B int `cbor:"-,"`
+}
+
+// FooH has omitempty fields of Integer and Simple.
+type FooH struct {
+	A Integer `cbor:",omitempty"`
+	B Simple  `cbor:",omitempty"`
+}
+
+// FooI has fields with the expected conversions.
+type FooI struct {
+	A []byte `cbor:",base64"`
+	B []byte `cbor:",base64url"`
+	C []byte `cbor:",base16,omitempty"`
+}
+
+// FooJ has keyasint fields beyond the range of int64.
+type FooJ struct {
+	A int `cbor:"1,keyasint"`
+	B int `cbor:"18446744073709551615,keyasint"`
+	C int `cbor:"-1,keyasint"`
+}
+
+// FooG has fields that may hold undefined.
+type FooG struct {
+	A Simple
+	B any
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"math/big"
+	"net"
 	"net/url"
 	"reflect"
 	"slices"
@@ -25,24 +26,145 @@ const (
 	tagNumberExpectedBase64    TagNumber = 22
 	tagNumberExpectedBase16    TagNumber = 23
 	tagNumberEncodedData       TagNumber = 24
+	tagNumberRational          TagNumber = 30
 
-	tagNumberURI          TagNumber = 32
-	tagNumberBase64URL    TagNumber = 33
-	tagNumberBase64       TagNumber = 34
-	tagNumberSelfDescribe TagNumber = 55799
+	tagNumberURI                      TagNumber = 32
+	tagNumberBase64URL                TagNumber = 33
+	tagNumberBase64                   TagNumber = 34
+	tagNumberMultiDimArray            TagNumber = 40
+	tagNumberHomogeneousArray         TagNumber = 41
+	tagNumberIPv4                     TagNumber = 52
+	tagNumberIPv6                     TagNumber = 54
+	tagNumberEpochDays                TagNumber = 100
+	tagNumberFullDate                 TagNumber = 1004
+	tagNumberMultiDimArrayColumnMajor TagNumber = 1040
+	tagNumberSelfDescribe             TagNumber = 55799
 )
 
 // Tag is a CBOR tag.
+//
+// nil Content is encoded as null, and null content is decoded into a Tag as nil Content.
+// Note that the zero Tag is encoded as tag number 0 with null,
+// which is not a valid date/time string.
 type Tag struct {
 	Number  TagNumber
 	Content any
 }
 
+// NewTag returns the Tag of the tag number and the content,
+// after normalizing the content into the representation of the tag number and validating it.
+// The following contents are normalized:
+//
+//   - tag number 0: time.Time is converted into the date/time string in UTC.
+//   - tag number 1: time.Time is converted into the epoch,
+//     which is an integer, or a float if it has fractional seconds.
+//   - tag number 2 and 3: *big.Int is converted into the byte string of the bignum.
+//   - tag number 24: the content other than []byte and EncodedData is encoded into the byte string.
+//   - tag number 30: *big.Rat is converted into the array of the numerator and the denominator.
+//   - tag number 32: *url.URL is converted into the URI string.
+//   - tag number 100: time.Time is converted into the days since 1970-01-01 in UTC.
+//   - tag number 1004: time.Time is converted into the full-date string in its location.
+//
+// The content of the tags supported by Tag.Decode must be valid for the tags,
+// e.g. NewTag returns a SemanticError for tag number 0 with an invalid date/time string.
+// The struct literal of Tag is still available to construct a tag without the validation.
+func NewTag(number TagNumber, content any) (Tag, error) {
+	content, err := normalizeTagContent(number, content)
+	if err != nil {
+		return Tag{}, err
+	}
+	if number == tagNumberEncodedData && !WellFormed(content.([]byte)) {
+		return Tag{}, newSemanticError("cbor: invalid encoded data")
+	}
+	tag := Tag{Number: number, Content: content}
+
+	// validate the content by decoding it.
+	raw, err := tag.Raw()
+	if err != nil {
+		return Tag{}, err
+	}
+	var v any
+	if err := raw.Decode(&v, Options{}); err != nil {
+		return Tag{}, err
+	}
+	return tag, nil
+}
+
+// normalizeTagContent converts the content into the representation of the tag number.
+// See NewTag for the conversions.
+func normalizeTagContent(number TagNumber, content any) (any, error) {
+	switch number {
+	case tagNumberDatetimeString:
+		if t, ok := content.(time.Time); ok {
+			return t.UTC().Format(time.RFC3339Nano), nil
+		}
+	case tagNumberEpochDatetime:
+		if t, ok := content.(time.Time); ok {
+			if t.Nanosecond() == 0 {
+				return t.Unix(), nil
+			}
+			return float64(t.Unix()) + float64(t.Nanosecond())/1e9, nil
+		}
+	case tagNumberPositiveBignum:
+		if i, ok := content.(*big.Int); ok {
+			if i.Sign() < 0 {
+				return nil, newSemanticError("cbor: negative integer for positive bignum")
+			}
+			return i.Bytes(), nil
+		}
+	case tagNumberNegativeBignum:
+		if i, ok := content.(*big.Int); ok {
+			if i.Sign() >= 0 {
+				return nil, newSemanticError("cbor: non-negative integer for negative bignum")
+			}
+			return new(big.Int).Sub(minusOne, i).Bytes(), nil
+		}
+	case tagNumberEncodedData:
+		switch c := content.(type) {
+		case []byte:
+			return c, nil
+		case EncodedData:
+			return []byte(c), nil
+		}
+		data, err := Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		return data, nil
+	case tagNumberRational:
+		if r, ok := content.(*big.Rat); ok {
+			return []any{new(big.Int).Set(r.Num()), new(big.Int).Set(r.Denom())}, nil
+		}
+	case tagNumberURI:
+		if u, ok := content.(*url.URL); ok {
+			return u.String(), nil
+		}
+	case tagNumberEpochDays:
+		if t, ok := content.(time.Time); ok {
+			days := t.Unix() / secondsPerDay
+			if t.Unix()%secondsPerDay < 0 {
+				days-- // round toward negative infinity
+			}
+			return days, nil
+		}
+	case tagNumberFullDate:
+		if t, ok := content.(time.Time); ok {
+			return t.Format(time.DateOnly), nil
+		}
+	}
+	return content, nil
+}
+
 // Decode decodes the tag content.
 // The following tags are supported:
 //
 //   - tag number 0: date/time string is decoded as time.Time.
+//     It is also decoded as Unix seconds into integers and floats if opts.AllowDatetimeToEpoch is set.
+//     The leap second is accepted if opts.AllowLeapSeconds is set.
 //   - tag number 1: epoch-based date/time is decoded as time.Time.
+//     Integer epochs and epochs in decimal fractions (tag number 4) are decoded exactly.
+//     Floating-point epochs are limited to the precision of float64,
+//     i.e. about a microsecond for the present-day dates.
 //   - tag number 2: positive bignum is decoded as *big.Int.
 //   - tag number 3: negative bignum is decoded as *big.Int.
 //   - tag number 4: decimal fraction is not implemented.
@@ -51,21 +173,46 @@ type Tag struct {
 //   - tag number 22: expected conversion to base64 is decoded as ExpectedBase64.
 //   - tag number 23: expected conversion to base16 is decoded as ExpectedBase16.
 //   - tag number 24: encoded CBOR data item is decoded as EncodedData.
+//   - tag number 30: rational number is decoded as *big.Rat.
 //   - tag number 32: URI is decoded as *url.URL.
 //   - tag number 33: base64url is decoded as Base64URLString.
 //   - tag number 34: base64 is decoded as Base64String.
+//   - tag number 40: multi-dimensional array in row-major order is decoded as nested []any,
+//     e.g. []any of []any for two dimensions, or as MultiDimArray.
+//   - tag number 41: homogeneous array is decoded as an array, e.g. into []int64 or []any.
+//   - tag number 52: IPv4 address is decoded as net.IP, and IPv4 prefix is decoded as *net.IPNet.
+//   - tag number 54: IPv6 address is decoded as net.IP, and IPv6 prefix is decoded as *net.IPNet.
+//   - tag number 100: days since 1970-01-01 is decoded as time.Time at midnight UTC, or as an integer.
+//   - tag number 1004: full-date string is decoded as time.Time at midnight UTC, or as a string.
+//   - tag number 1040: multi-dimensional array in column-major order is decoded in the same way as tag number 40.
 //   - tag number 55799: Self-Described CBOR return the content as is.
 //
 // Other tags returns tag itself.
 func (tag Tag) Decode(v any, opts Options) error {
-	data, err := Marshal(tag.Content)
+	t, err := tag.Raw()
 	if err != nil {
 		return err
 	}
-	t := RawTag{Number: tag.Number, Content: data}
 	return t.Decode(v, opts)
 }
 
+// Raw returns the RawTag that has the encoded content of tag.
+func (tag Tag) Raw() (RawTag, error) {
+	data, err := Marshal(tag.Content)
+	if err != nil {
+		return RawTag{}, err
+	}
+	return RawTag{Number: tag.Number, Content: data}, nil
+}
+
+// RawTag is a CBOR tag with the raw encoded content.
+// It is decoded from tags that are not supported by this package.
+//
+// When marshaling, the tag head is followed by Content verbatim.
+// It is the efficient way to wrap pre-encoded content in a tag,
+// because Content is neither decoded nor re-encoded.
+// Content is not validated, so it must be a well-formed CBOR data item.
+// nil Content is encoded as null, the same as nil RawMessage.
 type RawTag struct {
 	Number  TagNumber
 	Content RawMessage
@@ -77,10 +224,59 @@ func (tag RawTag) Decode(v any, opts Options) error {
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
-	return tag.decodeReflectValue(rv.Elem(), opts)
+	return tag.decodeReflectValue(0, rv.Elem(), opts)
+}
+
+// Tag returns the Tag that has the content of tag decoded into any with the options.
+func (tag RawTag) Tag(opts Options) (Tag, error) {
+	var content any
+	if err := opts.Unmarshal(tag.Content, &content); err != nil {
+		return Tag{}, err
+	}
+	return Tag{Number: tag.Number, Content: content}, nil
 }
 
-func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
+// checkHomogeneousArray checks that all elements of the array have the same major type,
+// and the same tag number if they are tagged.
+// Unsigned and negative integers are regarded as the same type.
+func checkHomogeneousArray(data RawMessage) error {
+	var s Scanner
+	s.Reset(data)
+	_, h, err := s.Next()
+	if err != nil {
+		return unexpectedEnd(err)
+	}
+
+	var first MajorType
+	var firstTag uint64
+	indefinite := h.Indefinite()
+	for i := uint64(0); indefinite || i < h.Arg; i++ {
+		major, eh, err := s.Next()
+		if err != nil {
+			return unexpectedEnd(err)
+		}
+		if indefinite && major == MajorTypeOther && eh.Indefinite() {
+			// the "break" stop code.
+			break
+		}
+		if major == MajorTypeNegativeInt {
+			major = MajorTypePositiveInt
+		}
+		if i == 0 {
+			first, firstTag = major, eh.Arg
+		} else if major != first || (major == MajorTypeTag && eh.Arg != firstTag) {
+			return newSemanticError("cbor: elements of homogeneous array have different types")
+		}
+		if err := s.Skip(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeReflectValue decodes the tag into rv.
+// start is the offset of the tag head in the input, which is reported by UnmarshalTypeError.
+func (tag RawTag) decodeReflectValue(start int, rv reflect.Value, opts Options) error {
 	firstByte := tag.Content[0]
 	mt := majorType(firstByte >> 5)
 	d := newDecodeState(tag.Content)
@@ -91,6 +287,34 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		return u.UnmarshalCBOR([]byte(tag.Content))
 	}
 
+	if opts.PreserveTags && rv.Kind() == reflect.Interface && tagType.Implements(rv.Type()) {
+		opts.set(d)
+		var content any
+		if err := d.decode(&content); err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(Tag{Number: tag.Number, Content: content}))
+		return nil
+	}
+
+	if rv.Kind() == reflect.Interface && rv.NumMethod() > 0 {
+		if t, ok := opts.TagTypes[tag.Number]; ok {
+			if !t.Implements(rv.Type()) {
+				return &UnmarshalTypeError{Value: "tag", Type: rv.Type(), Offset: int64(start)}
+			}
+			opts.set(d)
+			v := reflect.New(t).Elem()
+			if err := d.decodeReflectValue(v); err != nil {
+				return err
+			}
+			if d.savedError != nil {
+				return d.savedError
+			}
+			rv.Set(v)
+			return nil
+		}
+	}
+
 	switch tag.Number {
 
 	// tag number 0: date/time string
@@ -99,7 +323,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		if err := d.decode(&s); err != nil {
 			return wrapSemanticError("cbor: invalid datetime string", err)
 		}
-		t, err := time.Parse(time.RFC3339Nano, s)
+		t, err := parseDatetime(s, opts.AllowLeapSeconds)
 		if err != nil {
 			return wrapSemanticError("cbor: invalid datetime string", err)
 		}
@@ -116,7 +340,26 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			rv.Set(reflect.ValueOf(t))
 			return nil
 		}
-		return &UnmarshalTypeError{Value: "datetime", Type: rv.Type()}
+		if opts.AllowDatetimeToEpoch {
+			switch rt.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if rv.OverflowInt(t.Unix()) {
+					return newSemanticError("cbor: integer overflow")
+				}
+				rv.SetInt(t.Unix())
+				return nil
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if t.Unix() < 0 || rv.OverflowUint(uint64(t.Unix())) {
+					return newSemanticError("cbor: integer overflow")
+				}
+				rv.SetUint(uint64(t.Unix()))
+				return nil
+			case reflect.Float32, reflect.Float64:
+				rv.SetFloat(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+				return nil
+			}
+		}
+		return &UnmarshalTypeError{Value: "datetime", Type: rv.Type(), Offset: int64(start)}
 
 	// tag number 1: epoch-based date/time
 	case tagNumberEpochDatetime:
@@ -144,6 +387,19 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 				i, f := math.Modf(epoch)
 				t = time.Unix(int64(i), int64(math.RoundToEven(f*1e9)))
 			}
+		case majorTypeTag:
+			var frac RawTag
+			if err := d.decode(&frac); err != nil {
+				return wrapSemanticError("cbor: invalid epoch-based datetime", err)
+			}
+			if frac.Number != tagNumberDecimalFraction {
+				return newSemanticError("cbor: invalid epoch-based datetime")
+			}
+			var err error
+			t, err = decodeEpochDecimalFraction(frac.Content)
+			if err != nil {
+				return err
+			}
 		default:
 			return newSemanticError("cbor: invalid epoch-based datetime")
 		}
@@ -161,11 +417,11 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 					rv.Set(reflect.ValueOf(t))
 				}
 			} else {
-				return &UnmarshalTypeError{Value: "datetime", Type: rv.Type()}
+				return &UnmarshalTypeError{Value: "datetime", Type: rv.Type(), Offset: int64(start)}
 			}
 			return nil
 		}
-		return &UnmarshalTypeError{Value: "datetime", Type: rv.Type()}
+		return &UnmarshalTypeError{Value: "datetime", Type: rv.Type(), Offset: int64(start)}
 
 	// tag number 2: positive bignum
 	case tagNumberPositiveBignum:
@@ -180,6 +436,13 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		}
 
 		i := new(big.Int).SetBytes(b)
+		if rv.Type() == integerType {
+			if !i.IsUint64() {
+				return newSemanticError("cbor: integer overflow")
+			}
+			rv.Set(reflect.ValueOf(Integer{Value: i.Uint64()}))
+			return nil
+		}
 		switch rv.Kind() {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			if !i.IsInt64() || rv.OverflowInt(i.Int64()) {
@@ -201,10 +464,10 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			} else if reflect.PointerTo(bigIntType).Implements(rv.Type()) {
 				rv.Set(reflect.ValueOf(i))
 			} else {
-				return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+				return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
 			}
 		default:
-			return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 3: negative bignum
@@ -221,6 +484,13 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		}
 
 		i := new(big.Int).SetBytes(b)
+		if rv.Type() == integerType {
+			if !i.IsUint64() {
+				return newSemanticError("cbor: integer overflow")
+			}
+			rv.Set(reflect.ValueOf(Integer{Sign: true, Value: i.Uint64()}))
+			return nil
+		}
 		i.Sub(minusOne, i)
 		switch rv.Kind() {
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -240,10 +510,10 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			} else if reflect.PointerTo(bigIntType).Implements(rv.Type()) {
 				rv.Set(reflect.ValueOf(i))
 			} else {
-				return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+				return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
 			}
 		default:
-			return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 4: decimal fraction
@@ -275,7 +545,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		case *big.Int:
 			f.SetInt(x)
 		default:
-			return &UnmarshalTypeError{Value: "float", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "float", Type: rv.Type(), Offset: int64(start)}
 		}
 
 		f.SetMantExp(f, int(exp))
@@ -302,10 +572,39 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			} else if reflect.PointerTo(bigFloatType).Implements(rv.Type()) {
 				rv.Set(reflect.ValueOf(f))
 			} else {
-				return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+				return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
 			}
 		default:
-			return &UnmarshalTypeError{Value: "integer", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "integer", Type: rv.Type(), Offset: int64(start)}
+		}
+
+	// tag number 30: rational number
+	case tagNumberRational:
+		var a []any
+		if err := d.decode(&a); err != nil {
+			return wrapSemanticError("cbor: invalid rational number", err)
+		}
+		if len(a) != 2 {
+			return newSemanticError("cbor: invalid rational number")
+		}
+
+		num, ok := toBigInt(a[0])
+		if !ok {
+			return newSemanticError("cbor: invalid rational number")
+		}
+		denom, ok := toBigInt(a[1])
+		if !ok || denom.Sign() <= 0 {
+			return newSemanticError("cbor: invalid rational number")
+		}
+		r := new(big.Rat).SetFrac(num, denom)
+
+		switch {
+		case rv.Type() == bigRatType:
+			rv.Set(reflect.ValueOf(*r))
+		case rv.Kind() == reflect.Interface && reflect.PointerTo(bigRatType).Implements(rv.Type()):
+			rv.Set(reflect.ValueOf(r))
+		default:
+			return &UnmarshalTypeError{Value: "rational number", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 21: expected conversion to base64url
@@ -324,7 +623,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			}
 			rv.Set(reflect.ValueOf(ExpectedBase64URL{Content: a}))
 		default:
-			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type(), Offset: int64(start)}
 		}
 		return nil
 
@@ -344,7 +643,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			}
 			rv.Set(reflect.ValueOf(ExpectedBase64{Content: a}))
 		default:
-			return &UnmarshalTypeError{Value: "base64", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "base64", Type: rv.Type(), Offset: int64(start)}
 		}
 		return nil
 
@@ -364,7 +663,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			}
 			rv.Set(reflect.ValueOf(ExpectedBase16{Content: a}))
 		default:
-			return &UnmarshalTypeError{Value: "base16", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "base16", Type: rv.Type(), Offset: int64(start)}
 		}
 		return nil
 
@@ -381,15 +680,36 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			}
 		case rv.Kind() == reflect.Interface && encodedDataType.Implements(t):
 			var b []byte
-			if err := Unmarshal([]byte(tag.Content), &b); err != nil {
+			if err := (Options{}).Unmarshal([]byte(tag.Content), &b); err != nil {
 				return wrapSemanticError("cbor: invalid encoded data", err)
 			}
 			rv.Set(reflect.ValueOf(EncodedData(b)))
 		default:
-			return &UnmarshalTypeError{Value: "encoded data", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "encoded data", Type: rv.Type(), Offset: int64(start)}
 		}
 		return nil
 
+	// tag number 40: multi-dimensional array in row-major order
+	// tag number 1040: multi-dimensional array in column-major order
+	case tagNumberMultiDimArray, tagNumberMultiDimArrayColumnMajor:
+		return decodeMultiDimArray(d, start, tag.Number == tagNumberMultiDimArrayColumnMajor, rv, opts)
+
+	// tag number 41: homogeneous array
+	case tagNumberHomogeneousArray:
+		if mt != majorTypeArray {
+			return newSemanticError("cbor: invalid homogeneous array")
+		}
+		if err := checkHomogeneousArray(tag.Content); err != nil {
+			return err
+		}
+		opts.set(d)
+		if err := d.decodeReflectValue(rv); err != nil {
+			return err
+		}
+		if d.savedError != nil {
+			return d.savedError
+		}
+
 	// tag number 32: URI
 	case tagNumberURI:
 		var s string
@@ -408,7 +728,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		case rv.Kind() == reflect.Interface && reflect.PointerTo(urlType).Implements(t):
 			rv.Set(reflect.ValueOf(u))
 		default:
-			return &UnmarshalTypeError{Value: "uri", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "uri", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 33: base64url
@@ -429,7 +749,7 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		case rv.Kind() == reflect.Interface && base64URLStringType.Implements(t):
 			rv.Set(reflect.ValueOf(Base64URLString(s)))
 		default:
-			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 34: base64
@@ -450,7 +770,77 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 		case rv.Kind() == reflect.Interface && base64URLStringType.Implements(t):
 			rv.Set(reflect.ValueOf(Base64String(s)))
 		default:
-			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type()}
+			return &UnmarshalTypeError{Value: "base64url", Type: rv.Type(), Offset: int64(start)}
+		}
+
+	// tag number 52: IPv4 address or prefix
+	case tagNumberIPv4:
+		return decodeIP(d, start, mt, net.IPv4len, rv)
+
+	// tag number 54: IPv6 address or prefix
+	case tagNumberIPv6:
+		return decodeIP(d, start, mt, net.IPv6len, rv)
+
+	// tag number 100: days since 1970-01-01
+	case tagNumberEpochDays:
+		if mt != majorTypePositiveInt && mt != majorTypeNegativeInt {
+			return newSemanticError("cbor: invalid epoch days")
+		}
+		var n int64
+		if err := d.decode(&n); err != nil {
+			return wrapSemanticError("cbor: invalid epoch days", err)
+		}
+		if n <= minEpoch/secondsPerDay || n >= maxEpoch/secondsPerDay {
+			return newSemanticError("cbor: invalid range of epoch days")
+		}
+		t := time.Unix(n*secondsPerDay, 0).UTC()
+
+		rt := rv.Type()
+		switch {
+		case rt == timeType:
+			rv.Set(reflect.ValueOf(t))
+		case rt.Kind() == reflect.Int || rt.Kind() == reflect.Int8 || rt.Kind() == reflect.Int16 ||
+			rt.Kind() == reflect.Int32 || rt.Kind() == reflect.Int64:
+			if rv.OverflowInt(n) {
+				return newSemanticError("cbor: integer overflow")
+			}
+			rv.SetInt(n)
+		case rt.Kind() == reflect.Uint || rt.Kind() == reflect.Uint8 || rt.Kind() == reflect.Uint16 ||
+			rt.Kind() == reflect.Uint32 || rt.Kind() == reflect.Uint64:
+			if n < 0 || rv.OverflowUint(uint64(n)) {
+				return newSemanticError("cbor: integer overflow")
+			}
+			rv.SetUint(uint64(n))
+		case rt.Kind() == reflect.Interface && timeType.Implements(rt):
+			rv.Set(reflect.ValueOf(t))
+		default:
+			return &UnmarshalTypeError{Value: "epoch days", Type: rv.Type(), Offset: int64(start)}
+		}
+
+	// tag number 1004: full-date string
+	case tagNumberFullDate:
+		var s string
+		if err := d.decode(&s); err != nil {
+			return wrapSemanticError("cbor: invalid full-date string", err)
+		}
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return wrapSemanticError("cbor: invalid full-date string", err)
+		}
+		if t.Unix() <= minEpoch || t.Unix() >= maxEpoch {
+			return newSemanticError("cbor: invalid range of full-date")
+		}
+
+		rt := rv.Type()
+		switch {
+		case rt == timeType:
+			rv.Set(reflect.ValueOf(t))
+		case rt.Kind() == reflect.String:
+			rv.SetString(s)
+		case rt.Kind() == reflect.Interface && timeType.Implements(rt):
+			rv.Set(reflect.ValueOf(t))
+		default:
+			return &UnmarshalTypeError{Value: "full-date", Type: rv.Type(), Offset: int64(start)}
 		}
 
 	// tag number 55799 Self-Described CBOR
@@ -486,8 +876,289 @@ func (tag RawTag) decodeReflectValue(rv reflect.Value, opts Options) error {
 			rv.Set(reflect.ValueOf(v))
 			return nil
 		}
-		return &UnmarshalTypeError{Value: "tag", Type: rv.Type()}
+		return &UnmarshalTypeError{Value: "tag", Type: rv.Type(), Offset: int64(start)}
+	}
+
+	return nil
+}
+
+// toBigInt converts the integer decoded into any to *big.Int.
+func toBigInt(v any) (*big.Int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case Integer:
+		return v.BigInt(), true
+	case *big.Int:
+		return v, true
+	}
+	return nil, false
+}
+
+// parseDatetime parses the date/time string s in RFC 3339.
+// If allowLeapSecond is true, the leap second "60" is clamped to ":59.999999999".
+func parseDatetime(s string, allowLeapSecond bool) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil || !allowLeapSecond {
+		return t, err
+	}
+
+	// the seconds of "YYYY-MM-DDTHH:MM:SS".
+	const secondsOffset = len("YYYY-MM-DDTHH:MM:")
+	if len(s) < secondsOffset+2 || s[secondsOffset:secondsOffset+2] != "60" {
+		return t, err
+	}
+	t, leapErr := time.Parse(time.RFC3339Nano, s[:secondsOffset]+"59"+s[secondsOffset+2:])
+	if leapErr != nil {
+		// report the error of the original string.
+		return time.Time{}, err
+	}
+	if u := t.UTC(); u.Hour() != 23 || u.Minute() != 59 {
+		// leap seconds are inserted only at the end of a UTC day.
+		return time.Time{}, err
+	}
+	return t.Add(time.Second - 1 - time.Duration(t.Nanosecond())), nil
+}
+
+// decodeMultiDimArray decodes the content of the multi-dimensional array into rv.
+func decodeMultiDimArray(d *decodeState, start int, columnMajor bool, rv reflect.Value, opts Options) error {
+	var content []RawMessage
+	if err := d.decode(&content); err != nil {
+		return wrapSemanticError("cbor: invalid multi-dimensional array", err)
+	}
+	if len(content) != 2 {
+		return newSemanticError("cbor: invalid multi-dimensional array")
+	}
+
+	var dims []int
+	if err := (Options{}).Unmarshal(content[0], &dims); err != nil {
+		return wrapSemanticError("cbor: invalid dimensions of multi-dimensional array", err)
+	}
+	var elems []RawMessage
+	if err := (Options{}).Unmarshal(content[1], &elems); err != nil {
+		return wrapSemanticError("cbor: invalid elements of multi-dimensional array", err)
+	}
+	if err := checkDimensions(dims, len(elems)); err != nil {
+		return err
+	}
+
+	if rv.Type() == multiDimArrayType {
+		var a any
+		if err := opts.Unmarshal(content[1], &a); err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(MultiDimArray{Dimensions: dims, Elements: a, ColumnMajor: columnMajor}))
+		return nil
+	}
+
+	leaves, err := makeMultiDimArray(start, rv, dims, make([]reflect.Value, 0, len(elems)))
+	if err != nil {
+		return err
+	}
+	for i, elem := range elems {
+		leaf := leaves[i]
+		if columnMajor {
+			leaf = leaves[rowMajorIndex(i, dims)]
+		}
+		if err := opts.Unmarshal(elem, leaf.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDimensions validates that the product of dims is n.
+func checkDimensions(dims []int, n int) error {
+	if len(dims) == 0 {
+		return newSemanticError("cbor: invalid dimensions of multi-dimensional array")
+	}
+	p := 1
+	for _, dim := range dims {
+		if dim < 0 || (dim != 0 && p > n/dim) {
+			return newSemanticError("cbor: the number of elements doesn't match the dimensions of multi-dimensional array")
+		}
+		p *= dim
+	}
+	if p != n {
+		return newSemanticError("cbor: the number of elements doesn't match the dimensions of multi-dimensional array")
+	}
+	return nil
+}
+
+// makeMultiDimArray allocates the nested slices of v in the shape of dims,
+// and appends the innermost elements to leaves in row-major order.
+// Empty interfaces are filled with []any.
+func makeMultiDimArray(start int, v reflect.Value, dims []int, leaves []reflect.Value) ([]reflect.Value, error) {
+	n := dims[0]
+	switch {
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		s := reflect.MakeSlice(anySliceType, n, n)
+		v.Set(s)
+		v = s
+	case v.Kind() == reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	case v.Kind() == reflect.Array:
+		if v.Len() != n {
+			return nil, &UnmarshalTypeError{Value: "multi-dimensional array", Type: v.Type(), Offset: int64(start)}
+		}
+	default:
+		return nil, &UnmarshalTypeError{Value: "multi-dimensional array", Type: v.Type(), Offset: int64(start)}
+	}
+
+	for i := 0; i < n; i++ {
+		if len(dims) == 1 {
+			leaves = append(leaves, v.Index(i))
+			continue
+		}
+		var err error
+		leaves, err = makeMultiDimArray(start, v.Index(i), dims[1:], leaves)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return leaves, nil
+}
+
+// rowMajorIndex converts the index i of the element in column-major order
+// into the index in row-major order.
+func rowMajorIndex(i int, dims []int) int {
+	var index int
+	for j, dim := range dims {
+		stride := 1
+		for _, d := range dims[j+1:] {
+			stride *= d
+		}
+		index += (i % dim) * stride
+		i /= dim
+	}
+	return index
+}
+
+// maxEpochExponent is the maximum absolute value of the exponent
+// of the decimal fraction in epoch-based date/time.
+const maxEpochExponent = 100
+
+// decodeEpochDecimalFraction decodes the decimal fraction in epoch-based date/time exactly.
+// The digits below nanoseconds are rounded to even.
+func decodeEpochDecimalFraction(data RawMessage) (time.Time, error) {
+	var a []any
+	if err := (Options{}).Unmarshal(data, &a); err != nil {
+		return time.Time{}, wrapSemanticError("cbor: invalid decimal fraction", err)
+	}
+	if len(a) != 2 {
+		return time.Time{}, newSemanticError("cbor: invalid decimal fraction")
+	}
+	exp, ok := a[0].(int64)
+	if !ok {
+		return time.Time{}, newSemanticError("cbor: invalid decimal fraction")
+	}
+	if exp < -maxEpochExponent || exp > maxEpochExponent {
+		return time.Time{}, newSemanticError("cbor: invalid range of datetime")
+	}
+
+	// convert to nanoseconds
+	ns := new(big.Int)
+	switch x := a[1].(type) {
+	case int64:
+		ns.SetInt64(x)
+	case *big.Int:
+		ns.Set(x)
+	default:
+		return time.Time{}, newSemanticError("cbor: invalid decimal fraction")
+	}
+	if exp >= -9 {
+		ns.Mul(ns, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp+9), nil))
+	} else {
+		neg := ns.Sign() < 0
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp-9), nil)
+		var r big.Int
+		ns.QuoRem(ns, div, &r)
+
+		// round half to even
+		r.Abs(&r).Lsh(&r, 1)
+		if c := r.Cmp(div); c > 0 || (c == 0 && ns.Bit(0) == 1) {
+			if neg {
+				ns.Sub(ns, big.NewInt(1))
+			} else {
+				ns.Add(ns, big.NewInt(1))
+			}
+		}
+	}
+
+	sec, nsec := new(big.Int).DivMod(ns, big.NewInt(1e9), new(big.Int))
+	if !sec.IsInt64() || sec.Int64() <= minEpoch || sec.Int64() >= maxEpoch {
+		return time.Time{}, newSemanticError("cbor: invalid range of datetime")
 	}
+	return time.Unix(sec.Int64(), nsec.Int64()), nil
+}
+
+// decodeIP decodes the content of tag number 52 or 54 defined in RFC 9164.
+// start is the offset of the tag head, and size is the length of the address in bytes.
+func decodeIP(d *decodeState, start int, mt majorType, size int, rv reflect.Value) error {
+	t := rv.Type()
+	switch mt {
+	// address format
+	case majorTypeBytes:
+		var b []byte
+		if err := d.decode(&b); err != nil {
+			return wrapSemanticError("cbor: invalid IP address", err)
+		}
+		if len(b) != size {
+			return newSemanticError("cbor: invalid IP address")
+		}
+
+		switch {
+		case t == ipType:
+			rv.Set(reflect.ValueOf(net.IP(b)))
+		case rv.Kind() == reflect.Interface && ipType.Implements(t):
+			rv.Set(reflect.ValueOf(net.IP(b)))
+		default:
+			return &UnmarshalTypeError{Value: "ip address", Type: rv.Type(), Offset: int64(start)}
+		}
 
+	// prefix format [prefix-length, address] or interface format [address, prefix-length]
+	case majorTypeArray:
+		var a []any
+		if err := d.decode(&a); err != nil {
+			return wrapSemanticError("cbor: invalid IP prefix", err)
+		}
+		if len(a) != 2 {
+			return newSemanticError("cbor: invalid IP prefix")
+		}
+		ones, ok0 := a[0].(int64)
+		addr, ok1 := a[1].([]byte)
+		isPrefix := ok0 && ok1
+		if !isPrefix {
+			addr, ok0 = a[0].([]byte)
+			ones, ok1 = a[1].(int64)
+			if !ok0 || !ok1 || len(addr) != size {
+				return newSemanticError("cbor: invalid IP prefix")
+			}
+		}
+		if ones < 0 || ones > int64(size*8) || len(addr) > size {
+			return newSemanticError("cbor: invalid IP prefix")
+		}
+
+		ipnet := &net.IPNet{
+			IP:   make(net.IP, size),
+			Mask: net.CIDRMask(int(ones), size*8),
+		}
+		copy(ipnet.IP, addr)
+		if isPrefix {
+			ipnet.IP = ipnet.IP.Mask(ipnet.Mask)
+		}
+
+		switch {
+		case t == ipNetType:
+			rv.Set(reflect.ValueOf(*ipnet))
+		case rv.Kind() == reflect.Interface && reflect.PointerTo(ipNetType).Implements(t):
+			rv.Set(reflect.ValueOf(ipnet))
+		default:
+			return &UnmarshalTypeError{Value: "ip prefix", Type: rv.Type(), Offset: int64(start)}
+		}
+
+	default:
+		return newSemanticError("cbor: invalid IP address")
+	}
 	return nil
 }
@@ -3,6 +3,8 @@ package cbor
 import (
 	"math"
 	"math/big"
+	"net"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -44,6 +46,18 @@ func TestRawTag(t *testing.T) {
 		}
 	})
 
+	t.Run("decode with PreserveTags", func(t *testing.T) {
+		tag := RawTag{Number: 1, Content: []byte{0xc2, 0x41, 0x01}} // 1(2(h'01'))
+		var got any
+		if err := tag.Decode(&got, Options{PreserveTags: true}); err != nil {
+			t.Errorf("Decode() error: %v", err)
+		}
+		want := Tag{Number: 1, Content: Tag{Number: 2, Content: []byte{0x01}}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
 	t.Run("encode RawTag", func(t *testing.T) {
 		input := RawTag{0xffff, []byte{0x00}}
 		b, err := Marshal(input)
@@ -55,6 +69,125 @@ func TestRawTag(t *testing.T) {
 			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("encode RawTag with pre-encoded content", func(t *testing.T) {
+		// the content is written verbatim, even if it is not in the shortest form.
+		content := []byte{0x9f, 0x18, 0x01, 0x62, 0x61, 0x62, 0xff} // [_ 1, "ab"]
+		input := struct {
+			A RawTag `cbor:"a"`
+		}{
+			A: RawTag{Number: 1000, Content: content},
+		}
+		b, err := Marshal(input)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		want := append([]byte{0xa1, 0x61, 0x61, 0xd9, 0x03, 0xe8}, content...)
+		if diff := cmp.Diff(want, b); diff != "" {
+			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("encode RawTag with nil content", func(t *testing.T) {
+		b, err := Marshal(RawTag{Number: 1000})
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		want := []byte{0xd9, 0x03, 0xe8, 0xf6}
+		if diff := cmp.Diff(want, b); diff != "" {
+			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestTag_NilContent(t *testing.T) {
+	b, err := Marshal(Tag{Number: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xd9, 0x03, 0xe8, 0xf6} // 1000(null)
+	if diff := cmp.Diff(want, b); diff != "" {
+		t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	var got Tag
+	if err := Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Tag{Number: 1000}, got); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	var gotAny any
+	if err := (Options{PreserveTags: true}).Unmarshal(b, &gotAny); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Tag{Number: 1000}, gotAny); diff != "" {
+		t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("undefined", func(t *testing.T) {
+		// undefined is distinguished from null.
+		var got Tag
+		if err := Unmarshal([]byte{0xd9, 0x03, 0xe8, 0xf7}, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Content != Undefined {
+			t.Errorf("Unmarshal() got = %#v, want Undefined", got.Content)
+		}
+	})
+
+	t.Run("zero tag", func(t *testing.T) {
+		b, err := Marshal(Tag{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{0xc0, 0xf6} // 0(null)
+		if diff := cmp.Diff(want, b); diff != "" {
+			t.Errorf("Marshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestTag_Raw(t *testing.T) {
+	tag := Tag{Number: 1000, Content: []any{int64(1), "a"}}
+	raw, err := tag.Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RawTag{Number: 1000, Content: RawMessage{0x82, 0x01, 0x61, 0x61}}
+	if diff := cmp.Diff(want, raw); diff != "" {
+		t.Errorf("Raw() mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := raw.Tag(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(tag, got); diff != "" {
+		t.Errorf("Tag() mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("with options", func(t *testing.T) {
+		raw := RawTag{Number: 1000, Content: RawMessage{0x01}}
+		got, err := raw.Tag(Options{UseInteger: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Tag{Number: 1000, Content: Integer{Value: 1}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Tag() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		if _, err := (Tag{Number: 1000, Content: func() {}}).Raw(); err == nil {
+			t.Error("Raw() error = nil, want error")
+		}
+		if _, err := (RawTag{Number: 1000, Content: RawMessage{0x82, 0x01}}).Tag(Options{}); err == nil {
+			t.Error("Tag() error = nil, want error")
+		}
+	})
 }
 
 func TestUnmarshal_BigInt(t *testing.T) {
@@ -165,6 +298,96 @@ func TestUnmarshal_BigInt(t *testing.T) {
 		}
 		testUnexpectedEnd(t, input)
 	})
+
+	t.Run("integer into *big.Int", func(t *testing.T) {
+		input := []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		var got *big.Int
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := newBigInt("-18446744073709551616")
+		if got.Cmp(want) != 0 {
+			t.Errorf("Unmarshal() = %x, want %x", got, want)
+		}
+	})
+
+	t.Run("bignum into Integer", func(t *testing.T) {
+		input := []byte{0xc3, 0x48, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		var got Integer
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := Integer{Sign: true, Value: math.MaxUint64}
+		if got != want {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("bignum overflows Integer", func(t *testing.T) {
+		input := []byte{0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+		var got Integer
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("map[Integer]T", func(t *testing.T) {
+		input := []byte{
+			0xa3,             // map of length 3
+			0x01, 0x61, 0x61, //                                           1: "a"
+			0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x61, 0x62, // -18446744073709551616: "b"
+			0xc2, 0x48, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x61, 0x63, // 2(h'ffffffffffffffff'): "c"
+		}
+		var got map[Integer]string
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[Integer]string{
+			{Value: 1}:                           "a",
+			{Sign: true, Value: math.MaxUint64}:  "b",
+			{Sign: false, Value: math.MaxUint64}: "c",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+
+		// round trip
+		data, err := Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got2 map[Integer]string
+		if err := Unmarshal(data, &got2); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got2); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("map[*big.Int]T", func(t *testing.T) {
+		input := []byte{
+			0xa2,             // map of length 2
+			0x01, 0x61, 0x61, // 1: "a"
+			0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x61, 0x62, // 18446744073709551616: "b"
+		}
+		var got map[*big.Int]string
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			"1":                    "a",
+			"18446744073709551616": "b",
+		}
+		m := map[string]string{}
+		for k, v := range got {
+			m[k.String()] = v
+		}
+		if diff := cmp.Diff(want, m); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
 }
 
 func TestUnmarshal_BigFloat(t *testing.T) {
@@ -334,6 +557,453 @@ func TestUnmarshal_BigFloat(t *testing.T) {
 	})
 }
 
+func TestUnmarshal_Rational(t *testing.T) {
+	t.Run("decode into *big.Rat", func(t *testing.T) {
+		input := []byte{0xd8, 0x1e, 0x82, 0x20, 0x03}
+		var got *big.Rat
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := big.NewRat(-1, 3)
+		if got.Cmp(want) != 0 {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("decode into big.Rat", func(t *testing.T) {
+		input := []byte{
+			0xd8, 0x1e, // Tag 30
+			0x82,                                                             // Array 2
+			0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // 18446744073709551616
+			0x06, // 6
+		}
+		var got big.Rat
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := new(big.Rat).SetFrac(newBigInt("9223372036854775808"), big.NewInt(3))
+		if got.Cmp(want) != 0 {
+			t.Errorf("Unmarshal() = %v, want %v", &got, want)
+		}
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("decode into any", func(t *testing.T) {
+		input := []byte{0xd8, 0x1e, 0x82, 0x01, 0x03}
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := big.NewRat(1, 3)
+		if got, ok := got.(*big.Rat); !ok || got.Cmp(want) != 0 {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("zero denominator", func(t *testing.T) {
+		input := []byte{0xd8, 0x1e, 0x82, 0x01, 0x00}
+		var got *big.Rat
+		if err := Unmarshal(input, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+
+	t.Run("negative denominator", func(t *testing.T) {
+		input := []byte{0xd8, 0x1e, 0x82, 0x01, 0x20}
+		var got *big.Rat
+		if err := Unmarshal(input, &got); err == nil {
+			t.Error("Unmarshal() error = nil, want error")
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		input := []byte{0xd8, 0x1e, 0x82, 0x01, 0x03}
+		var got int
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want UnmarshalTypeError", err)
+		}
+	})
+}
+
+func TestUnmarshal_MultiDimArray(t *testing.T) {
+	// 40([[2, 3], [2, 4, 8, 4, 16, 256]])
+	rowMajor := []byte{
+		0xd8, 0x28, // Tag 40
+		0x82,             // Array 2
+		0x82, 0x02, 0x03, // [2, 3]
+		0x86, 0x02, 0x04, 0x08, 0x04, 0x10, 0x19, 0x01, 0x00, // [2, 4, 8, 4, 16, 256]
+	}
+	// 1040([[2, 3], [2, 4, 8, 4, 16, 256]])
+	columnMajor := []byte{
+		0xd9, 0x04, 0x10, // Tag 1040
+		0x82,             // Array 2
+		0x82, 0x02, 0x03, // [2, 3]
+		0x86, 0x02, 0x04, 0x08, 0x04, 0x10, 0x19, 0x01, 0x00, // [2, 4, 8, 4, 16, 256]
+	}
+
+	t.Run("decode into any", func(t *testing.T) {
+		var got any
+		if err := Unmarshal(rowMajor, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := []any{
+			[]any{int64(2), int64(4), int64(8)},
+			[]any{int64(4), int64(16), int64(256)},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+		testUnexpectedEnd(t, rowMajor)
+
+		// the rows are []any, not [][]any.
+		if typ := reflect.TypeOf(got); typ != reflect.TypeOf([]any{}) {
+			t.Errorf("Unmarshal() type = %v, want []interface {}", typ)
+		}
+		if typ := reflect.TypeOf(got.([]any)[0]); typ != reflect.TypeOf([]any{}) {
+			t.Errorf("Unmarshal() row type = %v, want []interface {}", typ)
+		}
+	})
+
+	t.Run("Tag.Decode into any", func(t *testing.T) {
+		tag := Tag{
+			Number:  tagNumberMultiDimArray,
+			Content: []any{[]any{int64(2), int64(2)}, []any{int64(1), int64(2), int64(3), int64(4)}},
+		}
+		var got any
+		if err := tag.Decode(&got, Options{}); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		want := []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4)}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("decode into nested slices", func(t *testing.T) {
+		var got [][]int64
+		if err := Unmarshal(rowMajor, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := [][]int64{{2, 4, 8}, {4, 16, 256}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("decode into nested arrays", func(t *testing.T) {
+		var got [2][3]int
+		if err := Unmarshal(rowMajor, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := [2][3]int{{2, 4, 8}, {4, 16, 256}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("decode column-major order", func(t *testing.T) {
+		var got [][]int
+		if err := Unmarshal(columnMajor, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := [][]int{{2, 8, 16}, {4, 4, 256}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+		testUnexpectedEnd(t, columnMajor)
+	})
+
+	t.Run("decode into MultiDimArray", func(t *testing.T) {
+		var got MultiDimArray
+		if err := Unmarshal(columnMajor, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := MultiDimArray{
+			Dimensions:  []int{2, 3},
+			Elements:    []any{int64(2), int64(4), int64(8), int64(4), int64(16), int64(256)},
+			ColumnMajor: true,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("length mismatch", func(t *testing.T) {
+		// 40([[2, 3], [1, 2, 3]])
+		input := []byte{0xd8, 0x28, 0x82, 0x82, 0x02, 0x03, 0x83, 0x01, 0x02, 0x03}
+		var got any
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("invalid dimensions", func(t *testing.T) {
+		// 40([[], []])
+		input := []byte{0xd8, 0x28, 0x82, 0x80, 0x80}
+		var got any
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+
+		// 40([[-1, -1], [1]])
+		input = []byte{0xd8, 0x28, 0x82, 0x82, 0x20, 0x20, 0x81, 0x01}
+		err = Unmarshal(input, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var got [3][2]int
+		err := Unmarshal(rowMajor, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := Marshal(MultiDimArray{Dimensions: []int{2, 2, 2}, Elements: []int{1, 2, 3, 4, 5, 6, 7, 8}, ColumnMajor: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [2][2][2]int
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		want := [2][2][2]int{{{1, 5}, {3, 7}}, {{2, 6}, {4, 8}}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestUnmarshal_HomogeneousArray(t *testing.T) {
+	t.Run("decode into []int64", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x82, 0x01, 0x02} // 41([1, 2])
+		var got []int64
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if diff := cmp.Diff([]int64{1, 2}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("decode into []string", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x9f, 0x61, 0x61, 0x61, 0x62, 0xff} // 41([_ "a", "b"])
+		var got []string
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if diff := cmp.Diff([]string{"a", "b"}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("decode into any", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x82, 0x01, 0x02} // 41([1, 2])
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if diff := cmp.Diff([]any{int64(1), int64(2)}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("not an array", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x01} // 41(1)
+		var got any
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*SemanticError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+		}
+	})
+
+	t.Run("mixed integers", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x82, 0x01, 0x20} // 41([1, -1])
+		var got []int64
+		if err := Unmarshal(input, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if diff := cmp.Diff([]int64{1, -1}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("not homogeneous", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input []byte
+		}{
+			{"integer and text string", []byte{0xd8, 0x29, 0x82, 0x01, 0x61, 0x61}},    // 41([1, "a"])
+			{"indefinite-length", []byte{0xd8, 0x29, 0x9f, 0x61, 0x61, 0x01, 0xff}},    // 41([_ "a", 1])
+			{"tagged and untagged", []byte{0xd8, 0x29, 0x82, 0xc1, 0x01, 0x01}},        // 41([1(1), 1])
+			{"different tags", []byte{0xd8, 0x29, 0x82, 0xc1, 0x01, 0xc2, 0x41, 0x01}}, // 41([1(1), 2(h'01')])
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got any
+				err := Unmarshal(tt.input, &got)
+				if _, ok := err.(*SemanticError); !ok {
+					t.Errorf("Unmarshal() error = %v, want *SemanticError", err)
+				}
+			})
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		input := []byte{0xd8, 0x29, 0x82, 0x01, 0x02} // 41([1, 2])
+		var got []string
+		err := Unmarshal(input, &got)
+		if _, ok := err.(*UnmarshalTypeError); !ok {
+			t.Errorf("Unmarshal() error = %v, want *UnmarshalTypeError", err)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := Options{HomogeneousArrayTag: true}.Marshal([]string{"a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		if err := Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if diff := cmp.Diff([]string{"a", "b"}, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestNewTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  TagNumber
+		content any
+		want    any
+	}{
+		{
+			"datetime string from time.Time",
+			0,
+			time.Date(2013, 3, 21, 20, 4, 0, 500_000_000, time.FixedZone("", 9*60*60)),
+			"2013-03-21T11:04:00.5Z",
+		},
+		{
+			"datetime string",
+			0,
+			"2013-03-21T20:04:00Z",
+			"2013-03-21T20:04:00Z",
+		},
+		{
+			"epoch from time.Time",
+			1,
+			time.Unix(1363896240, 0),
+			int64(1363896240),
+		},
+		{
+			"epoch with fraction from time.Time",
+			1,
+			time.Unix(1363896240, 500_000_000),
+			1363896240.5,
+		},
+		{
+			"positive bignum from *big.Int",
+			2,
+			newBigInt("18446744073709551616"),
+			[]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"negative bignum from *big.Int",
+			3,
+			newBigInt("-18446744073709551617"),
+			[]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			"encoded data from a value",
+			24,
+			"IETF",
+			[]byte{0x64, 0x49, 0x45, 0x54, 0x46},
+		},
+		{
+			"encoded data",
+			24,
+			EncodedData{0x01},
+			[]byte{0x01},
+		},
+		{
+			"rational number from *big.Rat",
+			30,
+			big.NewRat(-1, 3),
+			[]any{big.NewInt(-1), big.NewInt(3)},
+		},
+		{
+			"URI from *url.URL",
+			32,
+			&url.URL{Scheme: "http", Host: "www.example.com"},
+			"http://www.example.com",
+		},
+		{
+			"epoch days from time.Time",
+			100,
+			time.Date(1969, 12, 31, 12, 0, 0, 0, time.UTC),
+			int64(-1),
+		},
+		{
+			"full-date from time.Time",
+			1004,
+			time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+			"2013-03-21",
+		},
+		{
+			"unknown tag",
+			65535,
+			time.Unix(0, 0).UTC(),
+			time.Unix(0, 0).UTC(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTag(tt.number, tt.content)
+			if err != nil {
+				t.Fatalf("NewTag() error = %v", err)
+			}
+			want := Tag{Number: tt.number, Content: tt.want}
+			if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+				t.Errorf("NewTag() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name    string
+		number  TagNumber
+		content any
+	}{
+		{"invalid datetime string", 0, "2013-03-21"},
+		{"datetime out of range", 0, time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"epoch of string", 1, "1363896240"},
+		{"negative positive bignum", 2, big.NewInt(-1)},
+		{"non-negative negative bignum", 3, big.NewInt(0)},
+		{"malformed encoded data", 24, []byte{0x1c}},
+		{"zero denominator", 30, []any{1, 0}},
+		{"invalid full-date", 1004, "2013-3-21"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTag(tt.number, tt.content)
+			if _, ok := err.(*SemanticError); !ok {
+				t.Errorf("NewTag() error = %v, want *SemanticError", err)
+			}
+		})
+	}
+}
+
 func TestUnmarshal_Time(t *testing.T) {
 	t.Run("rfc3339", func(t *testing.T) {
 		input := []byte{0xc0, 0x74, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31, 0x54, 0x32, 0x30, 0x3a, 0x30, 0x34, 0x3a, 0x30, 0x30, 0x5a}
@@ -435,6 +1105,62 @@ func TestUnmarshal_Time(t *testing.T) {
 		testUnexpectedEnd(t, input)
 	})
 
+	t.Run("epoch-based decimal fraction", func(t *testing.T) {
+		// 1(4([-9, 1700000000123456789]))
+		input := []byte{0xc1, 0xc4, 0x82, 0x28, 0x1b, 0x17, 0x97, 0x9c, 0xfe, 0x3d, 0x85, 0xcd, 0x15}
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Unix(1700000000, 123456789)
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("epoch-based decimal fraction below nanoseconds", func(t *testing.T) {
+		// 1(4([-10, 17000000001234567895]))
+		input := []byte{0xc1, 0xc4, 0x82, 0x29, 0x1b, 0xeb, 0xec, 0x21, 0xee, 0x67, 0x3a, 0x02, 0xd7}
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Unix(1700000000, 123456790)
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("negative epoch-based decimal fraction", func(t *testing.T) {
+		// 1(4([-1, -15]))
+		input := []byte{0xc1, 0xc4, 0x82, 0x20, 0x2e}
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Unix(-2, 500000000)
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("epoch-based date/time in other tags", func(t *testing.T) {
+		// 1(2(h'01'))
+		input := []byte{0xc1, 0xc2, 0x41, 0x01}
+		var got time.Time
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid epoch-based datetime" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+
 	t.Run("null", func(t *testing.T) {
 		input := []byte{0xf6}
 		got := time.Now()
@@ -464,6 +1190,200 @@ func TestUnmarshal_Time(t *testing.T) {
 	})
 }
 
+func TestUnmarshal_FullDate(t *testing.T) {
+	// 1004("2013-03-21")
+	input := []byte{0xd9, 0x03, 0xec, 0x6a, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31}
+
+	t.Run("time.Time", func(t *testing.T) {
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Date(2013, 3, 21, 0, 0, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		var got string
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if got != "2013-03-21" {
+			t.Errorf("Unmarshal() = %q, want %q", got, "2013-03-21")
+		}
+	})
+
+	t.Run("FullDate", func(t *testing.T) {
+		var got FullDate
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if got != "2013-03-21" {
+			t.Errorf("Unmarshal() = %q, want %q", got, "2013-03-21")
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Date(2013, 3, 21, 0, 0, 0, 0, time.UTC)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		// 1004("2013-02-30")
+		input := []byte{0xd9, 0x03, 0xec, 0x6a, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x32, 0x2d, 0x33, 0x30}
+		var got time.Time
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid full-date string" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		// 1004("0000-01-01")
+		input := []byte{0xd9, 0x03, 0xec, 0x6a, 0x30, 0x30, 0x30, 0x30, 0x2d, 0x30, 0x31, 0x2d, 0x30, 0x31}
+		var got time.Time
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid range of full-date" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+}
+
+func TestUnmarshal_EpochDays(t *testing.T) {
+	// 100(15785)
+	input := []byte{0xd8, 0x64, 0x19, 0x3d, 0xa9}
+
+	t.Run("time.Time", func(t *testing.T) {
+		var got time.Time
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Date(2013, 3, 21, 0, 0, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Errorf("Unmarshal() = %v, want %v", got, want)
+		}
+
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("EpochDays", func(t *testing.T) {
+		var got EpochDays
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if got != 15785 {
+			t.Errorf("Unmarshal() = %d, want %d", got, 15785)
+		}
+	})
+
+	t.Run("integers", func(t *testing.T) {
+		var i16 int16
+		if err := Unmarshal(input, &i16); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if i16 != 15785 {
+			t.Errorf("Unmarshal() = %d, want %d", i16, 15785)
+		}
+
+		var u uint
+		if err := Unmarshal(input, &u); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if u != 15785 {
+			t.Errorf("Unmarshal() = %d, want %d", u, 15785)
+		}
+	})
+
+	t.Run("integer overflow", func(t *testing.T) {
+		// 100(-10676)
+		negative := []byte{0xd8, 0x64, 0x39, 0x29, 0xb3}
+		tests := []struct {
+			name  string
+			input []byte
+			v     any
+		}{
+			{"int8", input, new(int8)},
+			{"uint8", input, new(uint8)},
+			{"negative uint16", negative, new(uint16)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := Unmarshal(tt.input, tt.v)
+				se, ok := err.(*SemanticError)
+				if !ok {
+					t.Fatalf("Unmarshal() error = %v, want SemanticError", err)
+				}
+				if se.msg != "cbor: integer overflow" {
+					t.Errorf("unexpected error message: %q", se.msg)
+				}
+			})
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		// 100(-10676)
+		input := []byte{0xd8, 0x64, 0x39, 0x29, 0xb3}
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := time.Date(1940, 10, 9, 0, 0, 0, 0, time.UTC)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("not integer", func(t *testing.T) {
+		// 100("2013-03-21")
+		input := []byte{0xd8, 0x64, 0x6a, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31}
+		var got time.Time
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid epoch days" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		// 100(3000000)
+		input := []byte{0xd8, 0x64, 0x1a, 0x00, 0x2d, 0xc6, 0xc0}
+		var got time.Time
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid range of epoch days" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+}
+
 func TestUnmarshal_EncodedData(t *testing.T) {
 	t.Run("decode undefined", func(t *testing.T) {
 		input := []byte{0xd8, 0x18, 0xf7}
@@ -479,3 +1399,111 @@ func TestUnmarshal_EncodedData(t *testing.T) {
 		}
 	})
 }
+
+func TestUnmarshal_IP(t *testing.T) {
+	t.Run("IPv4 address", func(t *testing.T) {
+		// 52(h'c0000201')
+		input := []byte{0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x01}
+		var got net.IP
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if !got.Equal(net.IPv4(192, 0, 2, 1)) {
+			t.Errorf("Unmarshal() = %v, want %v", got, "192.0.2.1")
+		}
+
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("IPv6 address", func(t *testing.T) {
+		// 54(h'20010db81234deedbeefcafefacefeed')
+		input := []byte{
+			0xd8, 0x36, 0x50,
+			0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34, 0xde, 0xed,
+			0xbe, 0xef, 0xca, 0xfe, 0xfa, 0xce, 0xfe, 0xed,
+		}
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		want := net.ParseIP("2001:db8:1234:deed:beef:cafe:face:feed")
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("IPv4 prefix", func(t *testing.T) {
+		// 52([24, h'c00002'])
+		input := []byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x43, 0xc0, 0x00, 0x02}
+		var got net.IPNet
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if got.String() != "192.0.2.0/24" {
+			t.Errorf("Unmarshal() = %v, want %v", got.String(), "192.0.2.0/24")
+		}
+
+		testUnexpectedEnd(t, input)
+	})
+
+	t.Run("IPv6 prefix", func(t *testing.T) {
+		// 54([48, h'20010db81234'])
+		input := []byte{0xd8, 0x36, 0x82, 0x18, 0x30, 0x46, 0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34}
+		var got any
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		ipnet, ok := got.(*net.IPNet)
+		if !ok {
+			t.Fatalf("Unmarshal() = %T, want *net.IPNet", got)
+		}
+		if ipnet.String() != "2001:db8:1234::/48" {
+			t.Errorf("Unmarshal() = %v, want %v", ipnet.String(), "2001:db8:1234::/48")
+		}
+	})
+
+	t.Run("IPv4 interface", func(t *testing.T) {
+		// 52([h'c0000201', 24])
+		input := []byte{0xd8, 0x34, 0x82, 0x44, 0xc0, 0x00, 0x02, 0x01, 0x18, 0x18}
+		var got net.IPNet
+		if err := Unmarshal(input, &got); err != nil {
+			t.Errorf("Unmarshal() error = %v", err)
+		}
+		if !got.IP.Equal(net.IPv4(192, 0, 2, 1)) {
+			t.Errorf("Unmarshal() IP = %v, want %v", got.IP, "192.0.2.1")
+		}
+		if ones, bits := got.Mask.Size(); ones != 24 || bits != 32 {
+			t.Errorf("Unmarshal() Mask = %v, want %v", got.Mask, "/24")
+		}
+	})
+
+	t.Run("invalid length", func(t *testing.T) {
+		// 52(h'c00002')
+		input := []byte{0xd8, 0x34, 0x43, 0xc0, 0x00, 0x02}
+		var got net.IP
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid IP address" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+
+	t.Run("invalid prefix length", func(t *testing.T) {
+		// 52([33, h'c00002'])
+		input := []byte{0xd8, 0x34, 0x82, 0x18, 0x21, 0x43, 0xc0, 0x00, 0x02}
+		var got net.IPNet
+		err := Unmarshal(input, &got)
+		se, ok := err.(*SemanticError)
+		if !ok {
+			t.Errorf("Unmarshal() error = %v, want SemanticError", err)
+			return
+		}
+		if se.msg != "cbor: invalid IP prefix" {
+			t.Errorf("unexpected error message: %q", se.msg)
+		}
+	})
+}